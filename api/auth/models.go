@@ -15,7 +15,7 @@ type User struct {
 	Password  string    `json:"-" gorm:"not null"` // Password never exposed in JSON
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
-	Role      string    `json:"role" gorm:"default:'customer'"` // admin, customer, contributor
+	Role      string    `json:"role" gorm:"default:'customer'"` // admin, customer, contributor, editor
 	CreatedAt time.Time `json:"created_at" gorm:"not null"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
 }
@@ -11,14 +11,17 @@ import (
 // Post represents a blog post
 type Post struct {
 	ID           uuid.UUID   `json:"id" gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
-	Slug         string      `json:"slug" gorm:"uniqueIndex;not null"`
+	Slug         string      `json:"slug" gorm:"uniqueIndex:idx_posts_slug_locale;not null"`
 	Title        string      `json:"title" gorm:"not null"`
 	Content      string      `json:"content" gorm:"type:text;not null"`
 	Excerpt      string      `json:"excerpt" gorm:"type:text"`
 	FeaturedImage string      `json:"featured_image"`
 	AuthorID     uuid.UUID   `json:"author_id" gorm:"type:uuid;not null"`
 	Published    bool        `json:"published" gorm:"default:false"`
+	Status       string      `json:"status" gorm:"size:20;not null;default:'draft'"`
 	PublishedAt  *time.Time  `json:"published_at"`
+	Locale       string      `json:"locale" gorm:"uniqueIndex:idx_posts_slug_locale;size:10;not null;default:'en'"`
+	TranslationGroupID string `json:"translation_group_id" gorm:"index"`
 	CreatedAt    time.Time   `json:"created_at" gorm:"not null"`
 	UpdatedAt    time.Time   `json:"updated_at" gorm:"not null"`
 	Categories   []Category  `json:"categories" gorm:"many2many:post_categories;"`
@@ -28,13 +31,21 @@ type Post struct {
 
 // Category represents a blog category
 type Category struct {
-	ID          uuid.UUID `json:"id" gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
-	Name        string    `json:"name" gorm:"uniqueIndex;not null"`
-	Slug        string    `json:"slug" gorm:"uniqueIndex;not null"`
-	Description string    `json:"description" gorm:"type:text"`
-	CreatedAt   time.Time `json:"created_at" gorm:"not null"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"not null"`
-	Posts       []Post    `json:"posts" gorm:"many2many:post_categories;"`
+	ID          uuid.UUID  `json:"id" gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	Name        string     `json:"name" gorm:"uniqueIndex;not null"`
+	Slug        string     `json:"slug" gorm:"uniqueIndex;not null"`
+	Description string     `json:"description" gorm:"type:text"`
+	ParentID    *uuid.UUID `json:"parent_id" gorm:"type:uuid;index"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null"`
+	Posts       []Post     `json:"posts" gorm:"many2many:post_categories;"`
+}
+
+// CategoryCrumb is one step in a category's breadcrumb trail
+type CategoryCrumb struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
 }
 
 // Tag represents a blog tag
@@ -58,6 +69,42 @@ type Comment struct {
 	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
 }
 
+// Series represents an ordered sequence of related posts
+type Series struct {
+	ID          uuid.UUID    `json:"id" gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	Slug        string       `json:"slug" gorm:"uniqueIndex;not null"`
+	Title       string       `json:"title" gorm:"not null"`
+	Description string       `json:"description" gorm:"type:text"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time    `json:"updated_at" gorm:"not null"`
+	Posts       []SeriesPost `json:"-" gorm:"foreignKey:SeriesID"`
+}
+
+// SeriesPost is a post's membership and position within a series
+type SeriesPost struct {
+	SeriesID uuid.UUID `json:"series_id" gorm:"primaryKey;type:uuid"`
+	PostID   uuid.UUID `json:"post_id" gorm:"primaryKey;type:uuid;uniqueIndex"`
+	Position int       `json:"position" gorm:"not null"`
+}
+
+// SeriesNavItem is a neighbouring post in a series
+type SeriesNavItem struct {
+	Slug     string `json:"slug"`
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+}
+
+// SeriesNavigation describes where a post sits within its series
+type SeriesNavigation struct {
+	ID       uuid.UUID      `json:"id"`
+	Slug     string         `json:"slug"`
+	Title    string         `json:"title"`
+	Position int            `json:"position"`
+	Total    int            `json:"total"`
+	Prev     *SeriesNavItem `json:"prev"`
+	Next     *SeriesNavItem `json:"next"`
+}
+
 // PostRequest represents the form data for creating/updating a post
 type PostRequest struct {
 	Title        string    `json:"title" binding:"required"`
@@ -85,11 +132,57 @@ type Author struct {
 	LastName  string    `json:"last_name"`
 }
 
+// AuthorProfile holds the public profile of a user who writes posts
+type AuthorProfile struct {
+	ID          uuid.UUID `json:"id" gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:uuid;uniqueIndex;not null"`
+	Slug        string    `json:"slug" gorm:"uniqueIndex;not null"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio" gorm:"type:text"`
+	Avatar      string    `json:"avatar"`
+	Website     string    `json:"website"`
+	Twitter     string    `json:"twitter"`
+	Facebook    string    `json:"facebook"`
+	Instagram   string    `json:"instagram"`
+	LinkedIn    string    `json:"linkedin" gorm:"column:linkedin"`
+	GitHub      string    `json:"github" gorm:"column:github"`
+	CreatedAt   time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// AuthorSummary is an author profile with user details and post count
+type AuthorSummary struct {
+	AuthorProfile
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	PostCount int64  `json:"post_count"`
+}
+
+// AuthorProfileRequest is the request for creating/updating an author profile
+type AuthorProfileRequest struct {
+	Slug        string `json:"slug" binding:"required"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	Avatar      string `json:"avatar"`
+	Website     string `json:"website"`
+	Twitter     string `json:"twitter"`
+	Facebook    string `json:"facebook"`
+	Instagram   string `json:"instagram"`
+	LinkedIn    string `json:"linkedin"`
+	GitHub      string `json:"github"`
+}
+
 // CategoryRequest is the request for creating/updating a category
 type CategoryRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Slug        string `json:"slug" binding:"required"`
-	Description string `json:"description"`
+	Name        string  `json:"name" binding:"required"`
+	Slug        string  `json:"slug" binding:"required"`
+	Description string  `json:"description"`
+	ParentID    *string `json:"parent_id"`
+}
+
+// MergeRequest names the tag or category that another is merged into
+type MergeRequest struct {
+	Into string `json:"into" binding:"required"`
 }
 
 // TagRequest is the request for creating/updating a tag
@@ -98,6 +191,18 @@ type TagRequest struct {
 	Slug string `json:"slug" binding:"required"`
 }
 
+// SeriesRequest is the request for creating/updating a series
+type SeriesRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Slug        string `json:"slug" binding:"required"`
+	Description string `json:"description"`
+}
+
+// SeriesPostsRequest sets the ordered posts of a series
+type SeriesPostsRequest struct {
+	PostIDs []string `json:"post_ids" binding:"required"`
+}
+
 // CommentRequest is the request for creating a comment
 type CommentRequest struct {
 	Content string `json:"content" binding:"required"`
@@ -116,4 +221,13 @@ type PostQueryParams struct {
 	TagSlug      string `form:"tag" json:"tag"`
 	Search       string `form:"search" json:"search"`
 	Published    *bool  `form:"published" json:"published"`
-}
\ No newline at end of file
+}
+// SlugRedirect records a slug a post or category used to have, so old URLs redirect to the current one
+type SlugRedirect struct {
+	ID         uuid.UUID `json:"id" gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	EntityType string    `json:"entity_type" gorm:"size:32;not null;uniqueIndex:idx_slug_redirects_old_slug"`
+	EntityID   string    `json:"entity_id" gorm:"not null;index;uniqueIndex:idx_slug_redirects_old_slug"`
+	OldSlug    string    `json:"old_slug" gorm:"not null;uniqueIndex:idx_slug_redirects_old_slug"`
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"not null"`
+}
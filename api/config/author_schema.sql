@@ -0,0 +1,20 @@
+-- Author Profiles Table
+CREATE TABLE IF NOT EXISTS author_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    display_name VARCHAR(255),
+    bio TEXT,
+    avatar TEXT,
+    website TEXT,
+    twitter VARCHAR(255),
+    facebook VARCHAR(255),
+    instagram VARCHAR(255),
+    linkedin VARCHAR(255),
+    github VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create index for listing posts by author
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts(author_id);
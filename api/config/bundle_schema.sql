@@ -0,0 +1,35 @@
+-- Product Bundle Tables
+-- A bundle is a product sold at its own price that is made of other products; it has
+-- no stock of its own, only its components'
+ALTER TABLE products ADD COLUMN IF NOT EXISTS type VARCHAR(20) NOT NULL DEFAULT 'simple'; -- simple, bundle
+
+CREATE TABLE IF NOT EXISTS bundle_components (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    bundle_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    variant_id UUID REFERENCES product_variants(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_components_bundle_id ON bundle_components(bundle_id);
+CREATE INDEX IF NOT EXISTS idx_bundle_components_product_id ON bundle_components(product_id);
+
+-- What a bundle order line breaks down into for fulfilment, with the share of the
+-- line's revenue allocated to each component for reporting
+CREATE TABLE IF NOT EXISTS order_item_components (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    variant_id UUID REFERENCES product_variants(id),
+    name TEXT,
+    sku TEXT,
+    quantity INTEGER NOT NULL,
+    revenue NUMERIC NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_item_components_order_item_id ON order_item_components(order_item_id);
+CREATE INDEX IF NOT EXISTS idx_order_item_components_product_id ON order_item_components(product_id);
@@ -0,0 +1,33 @@
+-- Currency Tables
+-- Currencies customers can shop in besides the base currency (BASE_CURRENCY, UGX by default)
+CREATE TABLE IF NOT EXISTS currencies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(3) NOT NULL UNIQUE,
+    name TEXT,
+    symbol TEXT,
+    rate NUMERIC NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Prices set in a currency instead of converted from the base price
+CREATE TABLE IF NOT EXISTS product_prices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL,
+    variant_id UUID,
+    currency VARCHAR(3) NOT NULL,
+    price NUMERIC NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_prices_product_id ON product_prices(product_id);
+
+-- Orders lock their currency and exchange rate at checkout. Orders placed before this were
+-- in the base currency, so pass it in: psql -v base_currency=$BASE_CURRENCY -f currency_schema.sql
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency VARCHAR(3);
+ALTER TABLE orders ALTER COLUMN currency DROP DEFAULT;
+UPDATE orders SET currency = UPPER(:'base_currency') WHERE currency IS NULL;
+ALTER TABLE orders ALTER COLUMN currency SET NOT NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC NOT NULL DEFAULT 1;
@@ -0,0 +1,64 @@
+-- Gift Card and Store Credit Tables
+-- Balances are kept in the base currency
+CREATE TABLE IF NOT EXISTS gift_cards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(32) NOT NULL UNIQUE, -- e.g. GC-7KQ2-M9XD-4TRH
+    initial_value NUMERIC NOT NULL CHECK (initial_value > 0),
+    balance NUMERIC NOT NULL CHECK (balance >= 0),
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- pending until the order buying it is paid for, active, void
+    -- Set on cards bought in the shop
+    purchaser_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    order_id UUID,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gift_cards_order_id ON gift_cards(order_id);
+CREATE INDEX IF NOT EXISTS idx_gift_cards_purchaser_id ON gift_cards(purchaser_id);
+
+-- A customer's store credit, issued by admins for refunds or goodwill
+CREATE TABLE IF NOT EXISTS store_credits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    balance NUMERIC NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Immutable ledger of every change to a gift card or store credit balance
+CREATE TABLE IF NOT EXISTS credit_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    gift_card_id UUID REFERENCES gift_cards(id),
+    user_id UUID REFERENCES users(id),
+    type VARCHAR(20) NOT NULL, -- issue, redemption, refund, void
+    amount NUMERIC NOT NULL, -- signed change to the balance
+    balance NUMERIC NOT NULL, -- the balance after the change
+    reason TEXT,
+    actor_id UUID,
+    order_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((gift_card_id IS NULL) <> (user_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_transactions_gift_card_id ON credit_transactions(gift_card_id);
+CREATE INDEX IF NOT EXISTS idx_credit_transactions_user_id ON credit_transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_credit_transactions_order_id ON credit_transactions(order_id);
+
+-- Ledger entries are never changed or deleted
+CREATE OR REPLACE FUNCTION reject_credit_transaction_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'credit ledger entries can''t be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS credit_transactions_immutable ON credit_transactions;
+CREATE TRIGGER credit_transactions_immutable
+    BEFORE UPDATE OR DELETE ON credit_transactions
+    FOR EACH ROW EXECUTE FUNCTION reject_credit_transaction_change();
+
+-- The part of an order's total paid with gift cards and store credit; the rest is
+-- charged through a payment provider
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS credit_applied NUMERIC NOT NULL DEFAULT 0;
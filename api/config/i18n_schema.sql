@@ -0,0 +1,13 @@
+-- Post language and translation groups
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'en';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS translation_group_id TEXT;
+
+-- Existing posts each start their own translation group
+UPDATE posts SET translation_group_id = id::text WHERE translation_group_id IS NULL;
+
+-- Translations share a slug, so slugs are only unique per language
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_slug_key;
+DROP INDEX IF EXISTS idx_posts_slug;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_slug_locale ON posts(slug, locale);
+CREATE INDEX IF NOT EXISTS idx_posts_translation_group_id ON posts(translation_group_id);
+CREATE INDEX IF NOT EXISTS idx_posts_locale ON posts(locale);
@@ -0,0 +1,22 @@
+-- Newsletter Subscribers Table
+CREATE TABLE IF NOT EXISTS subscribers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email VARCHAR(255) NOT NULL UNIQUE,
+    user_id TEXT,
+    categories TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    confirm_token TEXT,
+    unsubscribe_token TEXT NOT NULL UNIQUE,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    unsubscribed_at TIMESTAMP WITH TIME ZONE,
+    last_digest_at TIMESTAMP WITH TIME ZONE,
+    -- A change to an active subscription waiting to be confirmed by email
+    pending_categories TEXT,
+    pending_user_id TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscribers_status ON subscribers(status);
+CREATE INDEX IF NOT EXISTS idx_subscribers_confirm_token ON subscribers(confirm_token);
+CREATE INDEX IF NOT EXISTS idx_subscribers_user_id ON subscribers(user_id);
@@ -0,0 +1,20 @@
+-- Series Table
+CREATE TABLE IF NOT EXISTS series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Series Posts Table (a post belongs to at most one series)
+CREATE TABLE IF NOT EXISTS series_posts (
+    series_id UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL UNIQUE REFERENCES posts(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (series_id, post_id)
+);
+
+-- Create index for ordered series listings
+CREATE INDEX IF NOT EXISTS idx_series_posts_position ON series_posts(series_id, position);
@@ -0,0 +1,18 @@
+-- Slug History Table
+-- Previous slugs of posts, products and categories, so old URLs can 301 to the current one
+CREATE TABLE IF NOT EXISTS slug_redirects (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id TEXT NOT NULL,
+    old_slug TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_slug_redirects_old_slug ON slug_redirects(entity_type, entity_id, old_slug);
+CREATE INDEX IF NOT EXISTS idx_slug_redirects_entity_id ON slug_redirects(entity_id);
+
+-- Old permalink paths kept by imports are whole paths, not slugs, so they're a kind of
+-- their own; move any recorded as post slugs before they were split out
+UPDATE slug_redirects SET entity_type = 'post_permalink'
+WHERE entity_type = 'post' AND old_slug LIKE '/%';
@@ -0,0 +1,37 @@
+-- Stock Ledger Tables
+-- Products without variants get a SKU of their own; variants already have one
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku TEXT;
+CREATE INDEX IF NOT EXISTS idx_products_sku ON products(sku);
+
+-- Every change to stock on hand, why it happened and who made it
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL,
+    variant_id UUID,
+    sku TEXT,
+    type VARCHAR(20) NOT NULL, -- sale, cancellation, adjustment, return, stock_take
+    quantity INTEGER NOT NULL, -- change to stock on hand
+    balance INTEGER NOT NULL, -- stock on hand after the change
+    reason TEXT,
+    actor_id UUID,
+    order_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_id ON stock_movements(product_id);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_variant_id ON stock_movements(variant_id);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_sku ON stock_movements(sku);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_order_id ON stock_movements(order_id);
+
+-- Ledger entries are immutable; a correction is a new entry
+CREATE OR REPLACE FUNCTION reject_stock_movement_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'stock ledger entries can''t be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS stock_movements_immutable ON stock_movements;
+CREATE TRIGGER stock_movements_immutable
+    BEFORE UPDATE OR DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION reject_stock_movement_change();
@@ -0,0 +1,40 @@
+-- Subscribe-and-save Tables
+-- A customer's recurring order of a product, renewed every 30, 60 or 90 days
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    variant_id UUID REFERENCES product_variants(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    interval_days INTEGER NOT NULL CHECK (interval_days IN (30, 60, 90)),
+    discount_percent NUMERIC NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, paused, canceled
+    street TEXT,
+    city TEXT,
+    state TEXT,
+    country TEXT,
+    postal_code TEXT,
+    payment_method_id UUID REFERENCES payment_methods(id),
+    shipping_method_id TEXT,
+    currency VARCHAR(3) NOT NULL,
+    next_renewal_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reminder_sent_at TIMESTAMP WITH TIME ZONE,
+    -- The renewal order waiting to be paid for, if any
+    renewal_order_id UUID,
+    last_order_id UUID,
+    -- Dunning: renewals in a row that weren't paid for, and when the next is tried
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    retry_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    canceled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscriptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_status ON subscriptions(status);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_next_renewal_at ON subscriptions(next_renewal_at);
+
+-- Orders placed by a subscription
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subscription_id UUID;
+CREATE INDEX IF NOT EXISTS idx_orders_subscription_id ON orders(subscription_id);
@@ -0,0 +1,22 @@
+-- Product Option Tables
+-- The axes a product varies along, like size, flavour or count, and their values
+CREATE TABLE IF NOT EXISTS product_options (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'text', -- text, number, size, color
+    values JSONB NOT NULL DEFAULT '[]',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_options_product_id ON product_options(product_id);
+
+-- Variants are combinations of their product's option values, each with its own SKU,
+-- price, stock, weight and image
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS options JSONB;
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS weight NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS image TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_options ON product_variants USING GIN (options);
@@ -0,0 +1,30 @@
+-- Editorial workflow status for posts
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'draft';
+
+-- Posts published before the workflow existed keep their published state
+UPDATE posts SET status = 'published' WHERE published = true AND status = 'draft';
+
+CREATE INDEX IF NOT EXISTS idx_posts_status ON posts(status);
+
+-- Posts synced from Hugo content carry the same status
+DO $$
+BEGIN
+    IF to_regclass('blog_posts') IS NOT NULL THEN
+        ALTER TABLE blog_posts ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'draft';
+        UPDATE blog_posts SET status = 'published' WHERE published = true AND status = 'draft';
+    END IF;
+END $$;
+
+-- Review Comments Table
+CREATE TABLE IF NOT EXISTS review_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id TEXT NOT NULL,
+    author_id TEXT NOT NULL,
+    author_name VARCHAR(255),
+    body TEXT NOT NULL,
+    status VARCHAR(20),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_comments_post_id ON review_comments(post_id);
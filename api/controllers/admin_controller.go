@@ -383,11 +383,12 @@ func (c *AdminController) GetOrderStatistics(ctx *gin.Context) {
 		startDate = now.AddDate(0, 0, -30)
 	}
 
-	// Get total sales and order count
+	// Get total sales and order count; each order's total is in the currency it was
+	// placed in, so it's converted back to the base currency before adding them up
 	var totalSales float64
 	var orderCount int
 	err := c.db.QueryRow(`
-		SELECT COALESCE(SUM(total_amount), 0), COUNT(*)
+		SELECT COALESCE(SUM(total_amount / exchange_rate), 0), COUNT(*)
 		FROM orders
 		WHERE created_at >= $1
 	`, startDate).Scan(&totalSales, &orderCount)
@@ -405,7 +406,7 @@ func (c *AdminController) GetOrderStatistics(ctx *gin.Context) {
 	}
 
 	rows, err := c.db.Query(`
-		SELECT status, COUNT(*), COALESCE(SUM(total_amount), 0)
+		SELECT status, COUNT(*), COALESCE(SUM(total_amount / exchange_rate), 0)
 		FROM orders
 		WHERE created_at >= $1
 		GROUP BY status
@@ -451,7 +452,7 @@ func (c *AdminController) GetOrderStatistics(ctx *gin.Context) {
 	}
 
 	rows, err = c.db.Query(`
-		SELECT TO_CHAR(created_at, $1) as date, COUNT(*), COALESCE(SUM(total_amount), 0)
+		SELECT TO_CHAR(created_at, $1) as date, COUNT(*), COALESCE(SUM(total_amount / exchange_rate), 0)
 		FROM orders
 		WHERE created_at >= $2
 		GROUP BY date, `+groupBy+`
@@ -488,7 +489,7 @@ func (c *AdminController) GetOrderStatistics(ctx *gin.Context) {
 	}
 
 	rows, err = c.db.Query(`
-		SELECT product_id, product_name, SUM(quantity) as total_quantity, SUM(total_price) as total_revenue
+		SELECT product_id, product_name, SUM(quantity) as total_quantity, SUM(oi.total_price / o.exchange_rate) as total_revenue
 		FROM order_items oi
 		JOIN orders o ON oi.order_id = o.id
 		WHERE o.created_at >= $1
@@ -623,7 +624,7 @@ func (c *AdminController) GetDashboardData(ctx *gin.Context) {
 	var todaySales float64
 	var todayOrders int
 	c.db.QueryRow(`
-		SELECT COALESCE(SUM(total_amount), 0), COUNT(*)
+		SELECT COALESCE(SUM(total_amount / exchange_rate), 0), COUNT(*)
 		FROM orders
 		WHERE DATE(created_at) = $1
 	`, today).Scan(&todaySales, &todayOrders)
@@ -631,7 +632,7 @@ func (c *AdminController) GetDashboardData(ctx *gin.Context) {
 	var yesterdaySales float64
 	var yesterdayOrders int
 	c.db.QueryRow(`
-		SELECT COALESCE(SUM(total_amount), 0), COUNT(*)
+		SELECT COALESCE(SUM(total_amount / exchange_rate), 0), COUNT(*)
 		FROM orders
 		WHERE DATE(created_at) = $1
 	`, yesterday).Scan(&yesterdaySales, &yesterdayOrders)
@@ -641,13 +642,13 @@ func (c *AdminController) GetDashboardData(ctx *gin.Context) {
 	var weeklyOrders, monthlyOrders int
 	
 	c.db.QueryRow(`
-		SELECT COALESCE(SUM(total_amount), 0), COUNT(*)
+		SELECT COALESCE(SUM(total_amount / exchange_rate), 0), COUNT(*)
 		FROM orders
 		WHERE created_at >= DATE_TRUNC('week', CURRENT_DATE)
 	`).Scan(&weeklySales, &weeklyOrders)
 	
 	c.db.QueryRow(`
-		SELECT COALESCE(SUM(total_amount), 0), COUNT(*)
+		SELECT COALESCE(SUM(total_amount / exchange_rate), 0), COUNT(*)
 		FROM orders
 		WHERE created_at >= DATE_TRUNC('month', CURRENT_DATE)
 	`).Scan(&monthlySales, &monthlyOrders)
@@ -834,7 +835,7 @@ func (c *AdminController) GetUser(ctx *gin.Context) {
 	var totalSpend float64
 	
 	c.db.QueryRow(`
-		SELECT COUNT(*), COALESCE(SUM(total_amount), 0)
+		SELECT COUNT(*), COALESCE(SUM(total_amount / exchange_rate), 0)
 		FROM orders
 		WHERE user_id = $1
 	`, userID).Scan(&orderCount, &totalSpend)
@@ -1094,6 +1095,7 @@ func (c *AdminController) ChangeUserRole(ctx *gin.Context) {
 		"admin":       true,
 		"customer":    true,
 		"contributor": true,
+		"editor":      true,
 	}
 	
 	if !validRoles[req.Role] {
@@ -1240,7 +1242,7 @@ func (c *AdminController) GetSiteStatistics(ctx *gin.Context) {
 	// Get order stats
 	var totalOrders, pendingOrders int
 	var totalRevenue float64
-	c.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(total_amount), 0) FROM orders`).Scan(&totalOrders, &totalRevenue)
+	c.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(total_amount / exchange_rate), 0) FROM orders`).Scan(&totalOrders, &totalRevenue)
 	c.db.QueryRow(`
 		SELECT COUNT(*) FROM orders
 		WHERE status IN ('pending', 'processing')
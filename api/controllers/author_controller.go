@@ -0,0 +1,164 @@
+// File: api/controllers/author_controller.go
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/blog"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+)
+
+// authorRoles are the user roles that can have an author profile
+var authorRoles = []string{"admin", "contributor"}
+
+// authorsQuery selects author profiles with user details and published post counts
+func (c *BlogController) authorsQuery() *gorm.DB {
+	return c.DB.Table("author_profiles").
+		Select(`author_profiles.*, users.first_name, users.last_name,
+			(SELECT COUNT(*) FROM posts WHERE posts.author_id = author_profiles.user_id AND posts.published = true) AS post_count`).
+		Joins("JOIN users ON users.id = author_profiles.user_id").
+		Where("users.role IN ?", authorRoles)
+}
+
+// GetAuthors lists author profiles with their published post counts
+func (c *BlogController) GetAuthors(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var total int64
+	c.DB.Table("author_profiles").
+		Joins("JOIN users ON users.id = author_profiles.user_id").
+		Where("users.role IN ?", authorRoles).
+		Count(&total)
+
+	var authors []blog.AuthorSummary
+	if err := c.authorsQuery().
+		Order("post_count DESC, author_profiles.slug ASC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Scan(&authors).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"authors":     authors,
+		"page":        page,
+		"page_size":   pageSize,
+		"total":       total,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
+
+// GetAuthor retrieves an author profile by slug along with their published posts
+func (c *BlogController) GetAuthor(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	var author blog.AuthorSummary
+	result := c.authorsQuery().Where("author_profiles.slug = ?", slug).Limit(1).Scan(&author)
+	if result.Error != nil || result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	var params blog.PaginationParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 10
+	}
+
+	var posts []blog.Post
+	c.DB.Preload("Categories").Preload("Tags").
+		Where("author_id = ? AND published = ?", author.UserID, true).
+		Order("published_at DESC NULLS LAST, created_at DESC").
+		Limit(params.PageSize).
+		Offset((params.Page - 1) * params.PageSize).
+		Find(&posts)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"author":      author,
+		"posts":       posts,
+		"page":        params.Page,
+		"page_size":   params.PageSize,
+		"total":       author.PostCount,
+		"total_pages": (author.PostCount + int64(params.PageSize) - 1) / int64(params.PageSize),
+	})
+}
+
+// UpdateMyAuthorProfile creates or updates the current user's author profile
+func (c *BlogController) UpdateMyAuthorProfile(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	authorID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req blog.AuthorProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The slug names the author's Hugo data file, so it has to be a plain slug
+	if req.Slug == "" || util.GenerateSlug(req.Slug) != req.Slug {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Slug may only contain lowercase letters, numbers and hyphens"})
+		return
+	}
+
+	// Check if slug is unique
+	var existing blog.AuthorProfile
+	if c.DB.Where("slug = ? AND user_id <> ?", req.Slug, authorID).First(&existing).Error == nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "An author with this slug already exists"})
+		return
+	}
+
+	now := time.Now()
+	var profile blog.AuthorProfile
+	if err := c.DB.Where("user_id = ?", authorID).First(&profile).Error; err != nil {
+		profile = blog.AuthorProfile{UserID: authorID, CreatedAt: now}
+	}
+
+	profile.Slug = req.Slug
+	profile.DisplayName = req.DisplayName
+	profile.Bio = req.Bio
+	profile.Avatar = req.Avatar
+	profile.Website = req.Website
+	profile.Twitter = req.Twitter
+	profile.Facebook = req.Facebook
+	profile.Instagram = req.Instagram
+	profile.LinkedIn = req.LinkedIn
+	profile.GitHub = req.GitHub
+	profile.UpdatedAt = now
+
+	if err := c.DB.Save(&profile).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save author profile"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
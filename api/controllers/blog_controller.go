@@ -9,8 +9,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/adrianmcmains/blog-ecommerce/api/blog"
+	"github.com/adrianmcmains/blog-ecommerce/api/utils"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 )
 
 // BlogController handles blog-related routes
@@ -48,8 +51,9 @@ func (c *BlogController) GetPosts(ctx *gin.Context) {
 	if params.CategorySlug != "" {
 		var category blog.Category
 		if err := c.DB.Where("slug = ?", params.CategorySlug).First(&category).Error; err == nil {
-			query = query.Joins("JOIN post_categories ON post_categories.post_id = posts.id").
-				Where("post_categories.category_id = ?", category.ID)
+			// Include posts filed under any of the category's subcategories
+			query = query.Where("posts.id IN (SELECT post_id FROM post_categories WHERE category_id IN (?))",
+				categoryDescendants(c.DB, category.ID))
 		}
 	}
 
@@ -61,9 +65,11 @@ func (c *BlogController) GetPosts(ctx *gin.Context) {
 		}
 	}
 
-	if params.Search != "" {
-		searchTerm := "%" + params.Search + "%"
-		query = query.Where("title ILIKE ? OR content ILIKE ?", searchTerm, searchTerm)
+	var orderBy interface{} = "created_at DESC"
+	if tsQuery := utils.BuildSearchQuery(params.Search); tsQuery != "" {
+		query = query.Where("posts.search_vector @@ to_tsquery('english', ?)", tsQuery)
+		// Rank by relevance when searching
+		orderBy = gorm.Expr("ts_rank_cd(posts.search_vector, to_tsquery('english', ?)) DESC, posts.created_at DESC", tsQuery)
 	}
 
 	if params.Published != nil {
@@ -77,7 +83,7 @@ func (c *BlogController) GetPosts(ctx *gin.Context) {
 	// Get the posts for the current page
 	var posts []blog.Post
 	query.Preload("Categories").Preload("Tags").
-		Order("created_at DESC").
+		Order(orderBy).
 		Limit(params.PageSize).
 		Offset(offset).
 		Find(&posts)
@@ -95,8 +101,25 @@ func (c *BlogController) GetPosts(ctx *gin.Context) {
 func (c *BlogController) GetPost(ctx *gin.Context) {
 	slug := ctx.Param("slug")
 
+	// Translations share a slug, so prefer the requested language, then the default
+	lang := util.ResolveLanguage(ctx.Query("lang"), ctx.GetHeader("Accept-Language"))
+
 	var post blog.Post
-	if err := c.DB.Preload("Categories").Preload("Tags").Where("slug = ?", slug).First(&post).Error; err != nil {
+	if err := c.DB.Preload("Categories").Preload("Tags").Where("slug = ?", slug).
+		Order(localePreference("locale", lang)).
+		First(&post).Error; err != nil {
+		// The post may have been renamed; send old links on to its current slug
+		var current string
+		c.DB.Model(&blog.Post{}).Select("posts.slug").
+			Joins("JOIN slug_redirects r ON r.entity_id = posts.id::text").
+			Where("r.entity_type = ? AND r.old_slug = ?", slugEntityPost, slug).
+			Order(localePreference("posts.locale", lang)).
+			Order("r.created_at DESC").Limit(1).Scan(&current)
+		if current != "" && current != slug {
+			redirectToSlug(ctx, slug, current)
+			return
+		}
+
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
@@ -120,10 +143,23 @@ func (c *BlogController) GetPost(ctx *gin.Context) {
 	var author blog.Author
 	c.DB.Table("users").Select("id, first_name, last_name").Where("id = ?", post.AuthorID).Scan(&author)
 
-	ctx.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"post":   post,
 		"author": author,
-	})
+		"series": c.seriesNavigation(post.ID),
+	}
+
+	// Optionally include sanitized HTML rendered from the Markdown content
+	if ctx.Query("render") == "html" {
+		rendered, err := util.RenderMarkdown(post.Content)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render post content"})
+			return
+		}
+		response["rendered"] = rendered
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
 // CreatePost creates a new blog post
@@ -255,7 +291,8 @@ func (c *BlogController) UpdatePost(ctx *gin.Context) {
 	publishingNow := !post.Published && req.Published
 	now := time.Now()
 
-	// Update post fields
+	// Update post fields, keeping the old slug so existing links redirect
+	oldSlug := post.Slug
 	post.Title = req.Title
 	post.Slug = req.Slug
 	post.Content = req.Content
@@ -275,6 +312,10 @@ func (c *BlogController) UpdatePost(ctx *gin.Context) {
 			return err
 		}
 
+		if err := recordSlugChange(tx, slugEntityPost, post.ID.String(), oldSlug, post.Slug); err != nil {
+			return err
+		}
+
 		// Update categories
 		if err := tx.Model(&post).Association("Categories").Clear(); err != nil {
 			return err
@@ -393,11 +434,18 @@ func (c *BlogController) CreateCategory(ctx *gin.Context) {
 		return
 	}
 
+	parentID, err := c.resolveCategoryParent(uuid.Nil, req.ParentID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	category := blog.Category{
 		ID:          uuid.New(),
 		Name:        req.Name,
 		Slug:        req.Slug,
 		Description: req.Description,
+		ParentID:    parentID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -435,13 +483,27 @@ func (c *BlogController) UpdateCategory(ctx *gin.Context) {
 		}
 	}
 
-	// Update category fields
+	parentID, err := c.resolveCategoryParent(category.ID, req.ParentID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update category fields, keeping the old slug so existing links redirect
+	oldSlug := category.Slug
 	category.Name = req.Name
 	category.Slug = req.Slug
 	category.Description = req.Description
+	category.ParentID = parentID
 	category.UpdatedAt = time.Now()
 
-	if err := c.DB.Save(&category).Error; err != nil {
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&category).Error; err != nil {
+			return err
+		}
+		return recordSlugChange(tx, slugEntityCategory, category.ID.String(), oldSlug, category.Slug)
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
 		return
 	}
@@ -467,7 +529,15 @@ func (c *BlogController) DeleteCategory(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.DB.Delete(&category).Error; err != nil {
+	// Subcategories move up to the deleted category's parent
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&blog.Category{}).Where("parent_id = ?", category.ID).
+			Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
 		return
 	}
@@ -707,4 +777,12 @@ func (c *BlogController) DeleteComment(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
-}
\ No newline at end of file
+}
+
+// localePreference orders posts in lang first, then the default language, then the rest
+func localePreference(column, lang string) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:  "CASE WHEN " + column + " = ? THEN 0 WHEN " + column + " = ? THEN 1 ELSE 2 END",
+		Vars: []interface{}{lang, util.DefaultLanguage()},
+	}}
+}
@@ -0,0 +1,446 @@
+// File: api/controllers/category_tree.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/blog"
+)
+
+// maxCategoryDepth bounds the breadcrumb walk in case the tree was corrupted into a cycle
+const maxCategoryDepth = 32
+
+// categoryDescendantsSQL selects a category and every category nested under it
+const categoryDescendantsSQL = `
+	WITH RECURSIVE tree AS (
+		SELECT id FROM categories WHERE id = ?
+		UNION
+		SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+	)
+	SELECT id FROM tree`
+
+// categoryAncestorsSQL selects a category and its parents, root first
+const categoryAncestorsSQL = `
+	WITH RECURSIVE chain AS (
+		SELECT id, name, slug, parent_id, 0 AS depth FROM categories WHERE id = ?
+		UNION ALL
+		SELECT c.id, c.name, c.slug, c.parent_id, chain.depth + 1
+		FROM categories c JOIN chain ON c.id = chain.parent_id
+		WHERE chain.depth < ?
+	)
+	SELECT id, name, slug FROM chain ORDER BY depth DESC`
+
+// productCategoryTreeSQL selects the product category whose column matches parameter
+// $arg and every category nested under it
+func productCategoryTreeSQL(column string, arg int) string {
+	return `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM product_categories WHERE ` + column + ` = $` + strconv.Itoa(arg) + `
+			UNION
+			SELECT c.id FROM product_categories c JOIN tree ON c.parent_id = tree.id
+		)
+		SELECT id FROM tree`
+}
+
+// productCategoryAncestorsSQL selects a product category and its parents, root first
+const productCategoryAncestorsSQL = `
+	WITH RECURSIVE chain AS (
+		SELECT id, name, slug, parent_id, 0 AS depth FROM product_categories WHERE id = $1
+		UNION ALL
+		SELECT c.id, c.name, c.slug, c.parent_id, chain.depth + 1
+		FROM product_categories c JOIN chain ON c.id = chain.parent_id
+		WHERE chain.depth < $2
+	)
+	SELECT id, name, slug FROM chain ORDER BY depth DESC`
+
+// errCategoryCycle is returned when a category would become its own ancestor
+var errCategoryCycle = errors.New("a category cannot be nested under itself or its descendants")
+
+// categoryDescendants returns a subquery selecting the category and all its descendants
+func categoryDescendants(db *gorm.DB, categoryID uuid.UUID) *gorm.DB {
+	return db.Raw(categoryDescendantsSQL, categoryID)
+}
+
+// categoryBreadcrumbs returns the trail from the root category down to the given one
+func categoryBreadcrumbs(db *gorm.DB, categoryID uuid.UUID) []blog.CategoryCrumb {
+	var crumbs []blog.CategoryCrumb
+	db.Raw(categoryAncestorsSQL, categoryID, maxCategoryDepth).Scan(&crumbs)
+	return crumbs
+}
+
+// resolveCategoryParent parses a requested parent ID, checking it exists and that
+// nesting the category under it wouldn't create a cycle
+func (c *BlogController) resolveCategoryParent(categoryID uuid.UUID, parentID *string) (*uuid.UUID, error) {
+	if parentID == nil || *parentID == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(*parentID)
+	if err != nil {
+		return nil, errors.New("invalid parent category ID")
+	}
+
+	var parent blog.Category
+	if err := c.DB.Where("id = ?", id).First(&parent).Error; err != nil {
+		return nil, errors.New("parent category not found")
+	}
+
+	if categoryID != uuid.Nil {
+		var count int64
+		c.DB.Raw("SELECT COUNT(*) FROM ("+categoryDescendantsSQL+") d WHERE d.id = ?", categoryID, id).Scan(&count)
+		if count > 0 {
+			return nil, errCategoryCycle
+		}
+	}
+
+	return &id, nil
+}
+
+// findCategory looks a category up by ID or slug
+func (c *BlogController) findCategory(ref string) (*blog.Category, error) {
+	var category blog.Category
+	if err := c.DB.Where("id::text = ? OR slug = ?", ref, ref).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// findTag looks a tag up by ID or slug
+func (c *BlogController) findTag(ref string) (*blog.Tag, error) {
+	var tag blog.Tag
+	if err := c.DB.Where("id::text = ? OR slug = ?", ref, ref).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetCategory retrieves a category with its breadcrumbs and direct children
+func (c *BlogController) GetCategory(ctx *gin.Context) {
+	category, err := c.findCategory(ctx.Param("slug"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var children []blog.Category
+	c.DB.Where("parent_id = ?", category.ID).Order("name ASC").Find(&children)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"category":    category,
+		"breadcrumbs": categoryBreadcrumbs(c.DB, category.ID),
+		"children":    children,
+	})
+}
+
+// MergeCategory moves every post from one blog category into another,
+// re-parents its children and deletes it
+func (c *BlogController) MergeCategory(ctx *gin.Context) {
+	var req blog.MergeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, err := c.findCategory(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	target, err := c.findCategory(req.Into)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Target category not found"})
+		return
+	}
+	if source.ID == target.ID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a category into itself"})
+		return
+	}
+
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repointJoinRows(tx, "post_categories", "category_id", "post_id", source.ID, target.ID); err != nil {
+			return err
+		}
+
+		// The target may itself sit anywhere under the source, so lift it out to the
+		// source's place before adopting the children, or the tree would loop
+		var nested int64
+		if err := tx.Raw("SELECT COUNT(*) FROM ("+categoryDescendantsSQL+") tree WHERE id = ?",
+			source.ID, target.ID).Scan(&nested).Error; err != nil {
+			return err
+		}
+		if nested > 0 {
+			if err := tx.Model(&blog.Category{}).Where("id = ?", target.ID).
+				Updates(map[string]interface{}{"parent_id": source.ParentID, "updated_at": time.Now()}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&blog.Category{}).Where("parent_id = ? AND id <> ?", source.ID, target.ID).
+			Updates(map[string]interface{}{"parent_id": target.ID, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&blog.Category{}, "id = ?", source.ID).Error; err != nil {
+			return err
+		}
+
+		// Links to the merged category, including its own old slugs, now lead to the one
+		// it was merged into
+		if err := repointSlugRedirects(tx, slugEntityCategory, source.ID.String(), target.ID.String(), target.Slug); err != nil {
+			return err
+		}
+		return recordSlugChange(tx, slugEntityCategory, target.ID.String(), source.Slug, target.Slug)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge categories"})
+		return
+	}
+
+	c.DB.First(target, "id = ?", target.ID)
+	ctx.JSON(http.StatusOK, gin.H{"category": target})
+}
+
+// MergeTag moves every post from one tag to another and deletes it
+func (c *BlogController) MergeTag(ctx *gin.Context) {
+	var req blog.MergeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, err := c.findTag(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	target, err := c.findTag(req.Into)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Target tag not found"})
+		return
+	}
+	if source.ID == target.ID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a tag into itself"})
+		return
+	}
+
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repointJoinRows(tx, "post_tags", "tag_id", "post_id", source.ID, target.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&blog.Tag{}, "id = ?", source.ID).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge tags"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tag": target})
+}
+
+// repointJoinRows moves the rows of a many-to-many join table from one tag or category
+// to another, skipping rows the target already has
+func repointJoinRows(tx *gorm.DB, table, column, ownerColumn string, fromID, toID uuid.UUID) error {
+	if err := tx.Exec(
+		"INSERT INTO "+table+" ("+ownerColumn+", "+column+") "+
+			"SELECT s."+ownerColumn+", ? FROM "+table+" s "+
+			"WHERE s."+column+" = ? AND NOT EXISTS ("+
+			"SELECT 1 FROM "+table+" t WHERE t."+ownerColumn+" = s."+ownerColumn+" AND t."+column+" = ?)",
+		toID, fromID, toID,
+	).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec("DELETE FROM "+table+" WHERE "+column+" = ?", fromID).Error
+}
+
+// productCategory is a row of the product_categories table
+type productCategory struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Slug        string  `json:"slug"`
+	Description string  `json:"description"`
+	ParentID    *string `json:"parent_id"`
+}
+
+// findProductCategory looks a product category up by ID or slug
+func (c *ShopController) findProductCategory(ref string) (*productCategory, error) {
+	var category productCategory
+	err := c.DB.QueryRow(`
+		SELECT id, name, slug, COALESCE(description, ''), parent_id
+		FROM product_categories
+		WHERE id::text = $1 OR slug = $1
+	`, ref).Scan(&category.ID, &category.Name, &category.Slug, &category.Description, &category.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetCategory retrieves a product category with its breadcrumbs and direct children
+func (c *ShopController) GetCategory(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+	category, err := c.findProductCategory(slug)
+	if err != nil {
+		// The category may have been renamed or merged; send old links on to its current slug
+		var current string
+		lookupErr := c.DB.QueryRow(`
+			SELECT pc.slug
+			FROM slug_redirects r
+			JOIN product_categories pc ON pc.id::text = r.entity_id
+			WHERE r.entity_type = $1 AND r.old_slug = $2
+			ORDER BY r.created_at DESC
+			LIMIT 1
+		`, slugEntityProductCategory, slug).Scan(&current)
+		if lookupErr == nil && current != slug {
+			redirectToSlug(ctx, slug, current)
+			return
+		}
+
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	breadcrumbs := []blog.CategoryCrumb{}
+	rows, err := c.DB.Query(productCategoryAncestorsSQL, category.ID, maxCategoryDepth)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var crumb blog.CategoryCrumb
+		if err := rows.Scan(&crumb.ID, &crumb.Name, &crumb.Slug); err != nil {
+			continue
+		}
+		breadcrumbs = append(breadcrumbs, crumb)
+	}
+
+	children := []productCategory{}
+	childRows, err := c.DB.Query(`
+		SELECT id, name, slug, COALESCE(description, ''), parent_id
+		FROM product_categories
+		WHERE parent_id = $1
+		ORDER BY name
+	`, category.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
+	defer childRows.Close()
+	for childRows.Next() {
+		var child productCategory
+		if err := childRows.Scan(&child.ID, &child.Name, &child.Slug, &child.Description, &child.ParentID); err != nil {
+			continue
+		}
+		children = append(children, child)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"category":    category,
+		"breadcrumbs": breadcrumbs,
+		"children":    children,
+	})
+}
+
+// MergeCategory moves every product from one product category into another,
+// re-parents its children and deletes it
+func (c *ShopController) MergeCategory(ctx *gin.Context) {
+	var req blog.MergeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, err := c.findProductCategory(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	target, err := c.findProductCategory(req.Into)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Target category not found"})
+		return
+	}
+	if source.ID == target.ID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a category into itself"})
+		return
+	}
+
+	if err := c.mergeProductCategory(source, target); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge categories"})
+		return
+	}
+
+	target, _ = c.findProductCategory(target.ID)
+	ctx.JSON(http.StatusOK, gin.H{"category": target})
+}
+
+// mergeProductCategory folds source into target in one transaction
+func (c *ShopController) mergeProductCategory(source, target *productCategory) error {
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO product_categories_mapping (product_id, category_id)
+		SELECT s.product_id, $1 FROM product_categories_mapping s
+		WHERE s.category_id = $2 AND NOT EXISTS (
+			SELECT 1 FROM product_categories_mapping t
+			WHERE t.product_id = s.product_id AND t.category_id = $1
+		)
+	`, target.ID, source.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM product_categories_mapping WHERE category_id = $1`, source.ID); err != nil {
+		return err
+	}
+
+	// The target may itself sit anywhere under the source, so lift it out to the
+	// source's place before adopting the children, or the tree would loop
+	var nested int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM (`+productCategoryTreeSQL("id", 1)+`) tree WHERE id = $2`,
+		source.ID, target.ID).Scan(&nested); err != nil {
+		return err
+	}
+	if nested > 0 {
+		if _, err := tx.Exec(`UPDATE product_categories SET parent_id = $1, updated_at = NOW() WHERE id = $2`,
+			source.ParentID, target.ID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE product_categories SET parent_id = $1, updated_at = NOW() WHERE parent_id = $2 AND id <> $1`,
+		target.ID, source.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM product_categories WHERE id = $1`, source.ID); err != nil {
+		return err
+	}
+
+	// Links to the merged category, including its own old slugs, now lead to the one
+	// it was merged into
+	if _, err := tx.Exec(`
+		UPDATE slug_redirects SET entity_id = $1, updated_at = NOW()
+		WHERE entity_type = $2 AND entity_id = $3 AND old_slug <> $4
+			AND old_slug NOT IN (SELECT old_slug FROM slug_redirects WHERE entity_type = $2 AND entity_id = $1)
+	`, target.ID, slugEntityProductCategory, source.ID, target.Slug); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM slug_redirects WHERE entity_type = $1 AND entity_id = $2`,
+		slugEntityProductCategory, source.ID); err != nil {
+		return err
+	}
+	if err := recordSlugChangeSQL(tx, slugEntityProductCategory, target.ID, source.Slug, target.Slug); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,259 @@
+// File: api/controllers/feed_controller.go
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/blog"
+	"github.com/adrianmcmains/blog-ecommerce/api/utils"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+)
+
+// feedItemLimit is the number of entries included in each feed
+const feedItemLimit = 20
+
+// FeedController serves RSS, Atom and JSON feeds for posts and products
+type FeedController struct {
+	DB       *gorm.DB
+	Shop     *ShopController
+	SiteURL  string
+	Title    string
+	Language string
+}
+
+// NewFeedController creates a new feed controller
+func NewFeedController(db *gorm.DB, shop *ShopController) *FeedController {
+	siteURL := os.Getenv("SITE_URL")
+	if siteURL == "" {
+		siteURL = "http://localhost:1313"
+	}
+
+	title := os.Getenv("SITE_TITLE")
+	if title == "" {
+		title = "Blog & Shop"
+	}
+
+	language := os.Getenv("SITE_LANGUAGE")
+	if language == "" {
+		language = "en-us"
+	}
+
+	return &FeedController{
+		DB:       db,
+		Shop:     shop,
+		SiteURL:  strings.TrimRight(siteURL, "/"),
+		Title:    title,
+		Language: language,
+	}
+}
+
+// GetPostsFeed returns a feed of the latest published posts
+func (c *FeedController) GetPostsFeed(ctx *gin.Context) {
+	feed := &utils.Feed{
+		Title:       c.Title + " - Blog",
+		Description: "Latest articles and updates",
+		Link:        c.SiteURL + "/blog/",
+	}
+
+	c.servePostsFeed(ctx, feed, c.DB.Model(&blog.Post{}))
+}
+
+// GetCategoryFeed returns a feed of the latest published posts in a category
+func (c *FeedController) GetCategoryFeed(ctx *gin.Context) {
+	var category blog.Category
+	if err := c.DB.Where("slug = ?", ctx.Param("slug")).First(&category).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	feed := &utils.Feed{
+		Title:       fmt.Sprintf("%s - %s", c.Title, category.Name),
+		Description: category.Description,
+		Link:        c.SiteURL + "/categories/" + category.Slug + "/",
+	}
+
+	query := c.DB.Model(&blog.Post{}).
+		Joins("JOIN post_categories ON post_categories.post_id = posts.id").
+		Where("post_categories.category_id = ?", category.ID)
+
+	c.servePostsFeed(ctx, feed, query)
+}
+
+// GetTagFeed returns a feed of the latest published posts with a tag
+func (c *FeedController) GetTagFeed(ctx *gin.Context) {
+	var tag blog.Tag
+	if err := c.DB.Where("slug = ?", ctx.Param("slug")).First(&tag).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	feed := &utils.Feed{
+		Title:       fmt.Sprintf("%s - #%s", c.Title, tag.Name),
+		Description: fmt.Sprintf("Posts tagged %s", tag.Name),
+		Link:        c.SiteURL + "/tags/" + tag.Slug + "/",
+	}
+
+	query := c.DB.Model(&blog.Post{}).
+		Joins("JOIN post_tags ON post_tags.post_id = posts.id").
+		Where("post_tags.tag_id = ?", tag.ID)
+
+	c.servePostsFeed(ctx, feed, query)
+}
+
+// GetProductsFeed returns a feed of new arrivals in the shop
+func (c *FeedController) GetProductsFeed(ctx *gin.Context) {
+	format, ok := feedFormat(ctx)
+	if !ok {
+		return
+	}
+
+	products, err := c.Shop.fetchNewArrivals(feedItemLimit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch new arrivals"})
+		return
+	}
+
+	feed := &utils.Feed{
+		Title:       c.Title + " - New Arrivals",
+		Description: "The latest products in our shop",
+		Link:        c.SiteURL + "/shop/",
+		FeedURL:     c.SiteURL + ctx.Request.URL.Path,
+		Language:    c.Language,
+	}
+
+	for _, product := range products {
+		feed.Items = append(feed.Items, utils.FeedItem{
+			ID:        "urn:uuid:" + product.ID,
+			Title:     product.Name,
+			Link:      c.SiteURL + "/shop/" + product.Slug + "/",
+			Summary:   product.Description,
+			Image:     product.FeaturedImage,
+			Published: product.CreatedAt,
+			Updated:   product.UpdatedAt,
+		})
+	}
+
+	writeFeed(ctx, feed, format)
+}
+
+// servePostsFeed loads the latest published posts from query into feed and writes it
+func (c *FeedController) servePostsFeed(ctx *gin.Context, feed *utils.Feed, query *gorm.DB) {
+	format, ok := feedFormat(ctx)
+	if !ok {
+		return
+	}
+
+	var posts []blog.Post
+	err := query.Preload("Tags").
+		Where("posts.published = ?", true).
+		Order("posts.published_at DESC NULLS LAST, posts.created_at DESC").
+		Limit(feedItemLimit).
+		Find(&posts).Error
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	// Look up author names in one query
+	authorIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		authorIDs = append(authorIDs, post.AuthorID)
+	}
+	var authors []blog.Author
+	if len(authorIDs) > 0 {
+		c.DB.Table("users").Select("id, first_name, last_name").Where("id IN ?", authorIDs).Scan(&authors)
+	}
+	authorNames := make(map[uuid.UUID]string, len(authors))
+	for _, author := range authors {
+		authorNames[author.ID] = strings.TrimSpace(author.FirstName + " " + author.LastName)
+	}
+
+	feed.FeedURL = c.SiteURL + ctx.Request.URL.Path
+	feed.Language = c.Language
+
+	for _, post := range posts {
+		published := post.CreatedAt
+		if post.PublishedAt != nil {
+			published = *post.PublishedAt
+		}
+
+		var tags []string
+		for _, tag := range post.Tags {
+			tags = append(tags, tag.Name)
+		}
+
+		contentHTML := ""
+		if rendered, err := util.RenderMarkdown(post.Content); err == nil {
+			contentHTML = rendered.HTML
+		}
+
+		feed.Items = append(feed.Items, utils.FeedItem{
+			ID:          "urn:uuid:" + post.ID.String(),
+			Title:       post.Title,
+			Link:        c.SiteURL + "/blog/" + post.Slug + "/",
+			Summary:     post.Excerpt,
+			ContentHTML: contentHTML,
+			Author:      authorNames[post.AuthorID],
+			Image:       post.FeaturedImage,
+			Tags:        tags,
+			Published:   published,
+			Updated:     post.UpdatedAt,
+		})
+	}
+
+	writeFeed(ctx, feed, format)
+}
+
+// feedFormat validates the :format route parameter
+func feedFormat(ctx *gin.Context) (string, bool) {
+	format := ctx.Param("format")
+	if _, ok := utils.FeedContentTypes[format]; !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feed format. Use rss, atom or json"})
+		return "", false
+	}
+	return format, true
+}
+
+// writeFeed renders feed, honouring If-None-Match and If-Modified-Since
+func writeFeed(ctx *gin.Context, feed *utils.Feed, format string) {
+	etag := feed.ETag(format)
+	lastModified := feed.LastModified()
+
+	ctx.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		ctx.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+	ctx.Header("Cache-Control", "public, max-age=300")
+
+	// If-None-Match takes precedence over If-Modified-Since
+	if match := ctx.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == etag || candidate == "*" || candidate == "W/"+etag {
+				ctx.Status(http.StatusNotModified)
+				return
+			}
+		}
+	} else if since := ctx.GetHeader("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		if t, err := time.Parse(http.TimeFormat, since); err == nil && !lastModified.After(t) {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	body, err := feed.Render(format)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render feed"})
+		return
+	}
+
+	ctx.Data(http.StatusOK, utils.FeedContentTypes[format], body)
+}
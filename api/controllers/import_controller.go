@@ -0,0 +1,59 @@
+// File: api/controllers/import_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/wxr"
+)
+
+// ImportWordPress imports a WordPress WXR export uploaded as the "file" form field.
+// With ?dry_run=true nothing is saved and the report shows what would be imported;
+// with ?skip_media=true image URLs are rewritten but the files aren't downloaded.
+func (c *BlogController) ImportWordPress(ctx *gin.Context) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A WXR export file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	export, err := wxr.Parse(file)
+	if err != nil {
+		if errors.Is(err, wxr.ErrNotWXR) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid WXR file: " + err.Error()})
+		return
+	}
+
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "static/images"
+	}
+	if ctx.Query("skip_media") == "true" {
+		mediaDir = ""
+	}
+
+	report, err := wxr.NewImporter(c.DB, wxr.Options{
+		DryRun:   ctx.Query("dry_run") == "true",
+		MediaDir: mediaDir,
+	}).Import(export)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"report": report})
+}
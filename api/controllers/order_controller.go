@@ -3,6 +3,7 @@ package controllers
 
 import (
 	"github.com/adrianmcmains/blog-ecommerce/api/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 	"database/sql"
 	"net/http"
 	"strconv"
@@ -98,11 +99,11 @@ func (c *ShopController) CreateOrder(ctx *gin.Context) {
 	var orderID int
 	err = tx.QueryRow(`
 		INSERT INTO orders (
-			user_id, total_amount, status, shipping_address, billing_address,
+			user_id, total_amount, currency, status, shipping_address, billing_address,
 			payment_method, notes, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
 		RETURNING id
-	`, userID, totalAmount, models.OrderStatusPending, req.ShippingAddress,
+	`, userID, totalAmount, util.BaseCurrency(), models.OrderStatusPending, req.ShippingAddress,
 		req.BillingAddress, req.PaymentMethod, req.Notes).Scan(&orderID)
 
 	if err != nil {
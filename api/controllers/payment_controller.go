@@ -14,6 +14,8 @@ import (
 	"strings"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 )
 
 // PaymentController handles payment-related routes
@@ -89,7 +91,7 @@ type PaymentMethodInfo struct {
 type InitiatePaymentRequest struct {
 	OrderID       string `json:"order_id" binding:"required"`
 	PaymentMethod string `json:"payment_method" binding:"required"`
-	Currency      string `json:"currency" binding:"required"`
+	Currency      string `json:"currency"` // Must match the order's currency if given
 	ReturnURL     string `json:"return_url"`
 	CancelURL     string `json:"cancel_url"`
 }
@@ -141,12 +143,14 @@ func (c *PaymentController) InitiatePayment(ctx *gin.Context) {
 		return
 	}
 
-	// Get order details
+	// Get order details; the order is charged in the currency its total was locked in at
+	// checkout, less what gift cards and store credit already paid
 	var orderTotal float64
 	var orderStatus string
+	var orderCurrency string
 	err := c.DB.QueryRow(`
-		SELECT total, status FROM orders WHERE id = $1
-	`, req.OrderID).Scan(&orderTotal, &orderStatus)
+		SELECT total_amount - COALESCE(credit_applied, 0), status, COALESCE(currency, '') FROM orders WHERE id = $1
+	`, req.OrderID).Scan(&orderTotal, &orderStatus, &orderCurrency)
 
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
@@ -158,6 +162,16 @@ func (c *PaymentController) InitiatePayment(ctx *gin.Context) {
 		return
 	}
 
+	if orderCurrency == "" {
+		orderCurrency = util.BaseCurrency()
+	}
+	if req.Currency != "" && util.NormalizeCurrency(req.Currency) != orderCurrency {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Order is priced in %s", orderCurrency)})
+		return
+	}
+	req.Currency = orderCurrency
+	orderTotal = util.RoundCurrency(orderTotal, orderCurrency)
+
 	// Create payment record
 	paymentID := uuid.New().String()
 	_, err = c.DB.Exec(`
@@ -471,11 +485,9 @@ func (c *PaymentController) GetPaymentStatus(ctx *gin.Context) {
 				
 				// If payment is completed, update order status
 				if newStatus == "completed" {
-					c.DB.Exec(`
-						UPDATE orders
-						SET status = 'processing', updated_at = $1
-						WHERE id = $2 AND status = 'pending'
-					`, time.Now(), payment.OrderID)
+					if err := c.completeOrderPayment(paymentID, payment.OrderID); err != nil {
+						fmt.Printf("Failed to complete order payment: %v\n", err)
+					}
 				}
 			}
 		}
@@ -883,15 +895,9 @@ func (c *PaymentController) handleEversendWebhook(ctx *gin.Context, body []byte,
 	
 	// If payment is completed, update order status
 	if status == "completed" {
-		_, err = c.DB.Exec(`
-			UPDATE orders
-			SET status = 'processing', updated_at = $1
-			WHERE id = $2
-		`, time.Now(), orderID)
-		
-		if err != nil {
+		if err := c.completeOrderPayment(paymentID, orderID); err != nil {
 			// Non-critical error, just log it
-			fmt.Printf("Failed to update order status: %v\n", err)
+			fmt.Printf("Failed to complete order payment: %v\n", err)
 		}
 	}
 	
@@ -1001,17 +1007,49 @@ func (c *PaymentController) handlePayPalWebhook(ctx *gin.Context, body []byte, s
 	
 	// If payment is completed, update order status
 	if status == "completed" {
-		_, err = c.DB.Exec(`
-			UPDATE orders
-			SET status = 'processing', updated_at = $1
-			WHERE id = $2
-		`, time.Now(), orderID)
-		
-		if err != nil {
+		if err := c.completeOrderPayment(paymentID, orderID); err != nil {
 			// Non-critical error, just log it
-			fmt.Printf("Failed to update order status: %v\n", err)
+			fmt.Printf("Failed to complete order payment: %v\n", err)
 		}
 	}
 	
 	ctx.JSON(http.StatusOK, gin.H{"status": "processed"})
-}
\ No newline at end of file
+}
+
+// completeOrderPayment moves an order awaiting payment on to processing once its
+// payment completes, taking the stock reserved at checkout off stock on hand and making
+// the gift cards bought in it usable. The order only moves on from pending, so a payment
+// landing after the order was canceled, e.g. because its stock reservation expired,
+// doesn't bring it back without stock; the payment is flagged for a refund instead.
+func (c *PaymentController) completeOrderPayment(paymentID, orderID string) error {
+	result, err := c.DB.Exec(`
+		UPDATE orders
+		SET status = 'processing', updated_at = $1
+		WHERE id = $2 AND status = 'pending'
+	`, time.Now(), orderID)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		var status string
+		if err := c.DB.QueryRow(`SELECT status FROM orders WHERE id = $1`, orderID).Scan(&status); err != nil {
+			return err
+		}
+		if status != "canceled" {
+			// Already paid for, e.g. a repeated webhook
+			return nil
+		}
+		_, err := c.DB.Exec(`
+			UPDATE payments
+			SET status = 'refund_required', error_message = $1, updated_at = $2
+			WHERE id = $3
+		`, "The order was canceled before the payment arrived", time.Now(), paymentID)
+		return err
+	}
+
+	if err := commitReservedStock(c.DB, orderID); err != nil {
+		return err
+	}
+	return activatePurchasedGiftCards(c.DB, orderID)
+}
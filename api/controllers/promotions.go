@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"database/sql"
+	"time"
+)
+
+// releaseOrderPromotions gives back the uses a canceled order took from the promotions
+// and coupons applied to it
+func releaseOrderPromotions(tx *sql.Tx, orderID string) error {
+	_, err := tx.Exec(`
+		UPDATE promotions p
+		SET usage_count = p.usage_count - 1, updated_at = $2
+		FROM promotion_redemptions r
+		WHERE r.order_id = $1 AND r.promotion_id = p.id AND p.usage_count > 0
+	`, orderID, time.Now())
+	return err
+}
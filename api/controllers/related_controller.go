@@ -0,0 +1,204 @@
+// File: api/controllers/related_controller.go
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/blog"
+	"github.com/adrianmcmains/blog-ecommerce/api/utils"
+)
+
+// relatedCache holds recently computed recommendations
+var relatedCache = utils.NewTTLCache(10*time.Minute, 1000)
+
+// relatedLimit parses the limit query parameter for related content
+func relatedLimit(ctx *gin.Context) int {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "4"))
+	if limit < 1 || limit > 20 {
+		limit = 4
+	}
+	return limit
+}
+
+// GetRelatedPosts returns published posts related to the post with the given slug
+func (c *BlogController) GetRelatedPosts(ctx *gin.Context) {
+	slug := ctx.Param("id")
+	limit := relatedLimit(ctx)
+
+	cacheKey := fmt.Sprintf("posts:%s:%d", slug, limit)
+	if cached, ok := relatedCache.Get(cacheKey); ok {
+		ctx.JSON(http.StatusOK, gin.H{"posts": cached})
+		return
+	}
+
+	var post blog.Post
+	if err := c.DB.Where("slug = ? AND published = ?", slug, true).First(&post).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	// Count shared tags and categories for every other published post that has any overlap
+	var candidates []utils.RelatedCandidate
+	err := c.DB.Raw(`
+		SELECT p.id::text AS id,
+			COALESCE(p.published_at, p.created_at) AS date,
+			(SELECT COUNT(*) FROM post_tags a JOIN post_tags b ON a.tag_id = b.tag_id
+				WHERE a.post_id = @source AND b.post_id = p.id) AS shared_tags,
+			(SELECT COUNT(*) FROM post_categories a JOIN post_categories b ON a.category_id = b.category_id
+				WHERE a.post_id = @source AND b.post_id = p.id) AS shared_categories
+		FROM posts p
+		WHERE p.published = true AND p.id <> @source
+			AND (
+				EXISTS (SELECT 1 FROM post_tags a JOIN post_tags b ON a.tag_id = b.tag_id
+					WHERE a.post_id = @source AND b.post_id = p.id)
+				OR EXISTS (SELECT 1 FROM post_categories a JOIN post_categories b ON a.category_id = b.category_id
+					WHERE a.post_id = @source AND b.post_id = p.id)
+			)
+	`, map[string]interface{}{"source": post.ID}).Scan(&candidates).Error
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related posts"})
+		return
+	}
+
+	scored := utils.ScoreRelated(candidates, utils.DefaultRelatedWeights, limit)
+
+	ids := make([]string, 0, len(scored))
+	for _, candidate := range scored {
+		ids = append(ids, candidate.ID)
+	}
+
+	// Load the posts and return them in score order
+	related := []blog.Post{}
+	if len(ids) > 0 {
+		var posts []blog.Post
+		c.DB.Preload("Categories").Preload("Tags").Where("id IN ?", ids).Find(&posts)
+
+		byID := make(map[string]blog.Post, len(posts))
+		for _, p := range posts {
+			byID[p.ID.String()] = p
+		}
+		for _, id := range ids {
+			if p, ok := byID[id]; ok {
+				related = append(related, p)
+			}
+		}
+	}
+
+	// Only cache hits, so posts tagged later show up without waiting for the cache
+	if len(related) > 0 {
+		relatedCache.Set(cacheKey, related)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"posts": related})
+}
+
+// GetRelatedProducts returns visible products that share categories with the given product
+func (c *ShopController) GetRelatedProducts(ctx *gin.Context) {
+	productID := ctx.Param("id")
+	limit := relatedLimit(ctx)
+
+	if _, err := uuid.Parse(productID); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	cacheKey := fmt.Sprintf("products:%s:%d", productID, limit)
+	if cached, ok := relatedCache.Get(cacheKey); ok {
+		ctx.JSON(http.StatusOK, gin.H{"products": cached})
+		return
+	}
+
+	var exists bool
+	if err := c.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM products p WHERE p.id = $1 AND `+visibleProductCondition+`)`,
+		productID).Scan(&exists); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related products"})
+		return
+	}
+	if !exists {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	rows, err := c.DB.Query(`
+		SELECT p.id, p.created_at, COUNT(*) AS shared_categories
+		FROM products p
+		JOIN product_categories_mapping b ON b.product_id = p.id
+		JOIN product_categories_mapping a ON a.category_id = b.category_id
+		WHERE a.product_id = $1 AND p.id <> $1 AND `+visibleProductCondition+`
+		GROUP BY p.id, p.created_at
+	`, productID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related products"})
+		return
+	}
+	defer rows.Close()
+
+	var candidates []utils.RelatedCandidate
+	for rows.Next() {
+		var candidate utils.RelatedCandidate
+		if err := rows.Scan(&candidate.ID, &candidate.Date, &candidate.SharedCategories); err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	scored := utils.ScoreRelated(candidates, utils.DefaultRelatedWeights, limit)
+
+	ids := make([]string, 0, len(scored))
+	for _, candidate := range scored {
+		ids = append(ids, candidate.ID)
+	}
+
+	// Load the products in one query and return them in score order
+	products := []map[string]interface{}{}
+	if len(ids) > 0 {
+		detailRows, err := c.DB.Query(`
+			SELECT id, name, description, price, slug, featured_image
+			FROM products
+			WHERE id = ANY($1::uuid[])
+		`, ids)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related products"})
+			return
+		}
+		defer detailRows.Close()
+
+		byID := make(map[string]map[string]interface{}, len(ids))
+		for detailRows.Next() {
+			var product struct {
+				ID            string
+				Name          string
+				Description   string
+				Price         float64
+				Slug          string
+				FeaturedImage string
+			}
+			if err := detailRows.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.Slug, &product.FeaturedImage); err != nil {
+				continue
+			}
+			byID[product.ID] = map[string]interface{}{
+				"id":             product.ID,
+				"name":           product.Name,
+				"description":    product.Description,
+				"price":          product.Price,
+				"slug":           product.Slug,
+				"featured_image": product.FeaturedImage,
+			}
+		}
+		for _, id := range ids {
+			if product, ok := byID[id]; ok {
+				products = append(products, product)
+			}
+		}
+	}
+
+	if len(products) > 0 {
+		relatedCache.Set(cacheKey, products)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"products": products})
+}
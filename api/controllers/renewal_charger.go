@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+)
+
+// ChargeRenewal charges a subscription's renewal order, less what gift cards and store
+// credit already paid, to a saved payment method without the customer being there.
+// It records the payment and returns an error unless the provider took the money;
+// only a service.ErrRenewalDeclined error means it certainly didn't.
+func (c *PaymentController) ChargeRenewal(ctx context.Context, order *models.Order, method *models.PaymentMethod) error {
+	currency := order.Currency
+	if currency == "" {
+		currency = util.BaseCurrency()
+	}
+	amount := util.RoundCurrency(order.TotalAmount-order.CreditApplied, currency)
+
+	paymentID := uuid.New().String()
+	orderID := order.ID.String()
+	if _, err := c.DB.ExecContext(ctx, `
+		INSERT INTO payments (
+			id, order_id, amount, currency, payment_method, status, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+	`, paymentID, orderID, amount, currency, method.Provider, "initiated", time.Now()); err != nil {
+		return err
+	}
+
+	var providerRef string
+	var err error
+	switch PaymentProvider(method.Provider) {
+	case PaymentProviderEversend:
+		providerRef, err = c.chargeEversendToken(ctx, paymentID, orderID, amount, currency, method)
+	case PaymentProviderPayPal:
+		providerRef, err = c.chargePayPalVault(ctx, paymentID, orderID, amount, currency, method)
+	default:
+		err = fmt.Errorf("%w: unsupported payment provider %q", service.ErrRenewalDeclined, method.Provider)
+	}
+
+	if err != nil {
+		// A request that failed some other way may still have been charged, so the
+		// payment is left for an admin to check with the provider
+		status := "review"
+		if errors.Is(err, service.ErrRenewalDeclined) {
+			status = "failed"
+		}
+		c.DB.ExecContext(ctx, `
+			UPDATE payments
+			SET status = $1, provider_reference = $2, error_message = $3, updated_at = $4
+			WHERE id = $5
+		`, status, providerRef, err.Error(), time.Now(), paymentID)
+		return err
+	}
+
+	_, err = c.DB.ExecContext(ctx, `
+		UPDATE payments
+		SET status = 'completed', provider_reference = $1, updated_at = $2
+		WHERE id = $3
+	`, providerRef, time.Now(), paymentID)
+	return err
+}
+
+// chargeEversendToken charges a card or mobile money account saved with Eversend
+func (c *PaymentController) chargeEversendToken(ctx context.Context, paymentID, orderID string, amount float64, currency string, method *models.PaymentMethod) (string, error) {
+	if c.EversendAPIKey == "" {
+		return "", fmt.Errorf("%w: Eversend isn't configured", service.ErrRenewalDeclined)
+	}
+
+	paymentType := "card"
+	if method.Type == "mobile_money" {
+		paymentType = "mobile_money"
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"amount":        amount,
+		"currency":      currency,
+		"description":   fmt.Sprintf("Subscription renewal for order %s", orderID),
+		"payment_type":  paymentType,
+		"payment_token": method.TokenID,
+		"metadata": map[string]string{
+			"payment_id": paymentID,
+			"order_id":   orderID,
+		},
+		"callback_url": c.CallbackURL,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/payments", c.EversendBaseURL), strings.NewReader(string(jsonData)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.EversendAPIKey))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("%w: eversend API error: %s", service.ErrRenewalDeclined, string(body))
+	}
+
+	var response struct {
+		Success bool `json:"success"`
+		Data    struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+	if !response.Success || response.Data.Status != "successful" {
+		return response.Data.ID, fmt.Errorf("%w: eversend payment status %q", service.ErrRenewalDeclined, response.Data.Status)
+	}
+	return response.Data.ID, nil
+}
+
+// chargePayPalVault charges a PayPal account or card saved in the PayPal vault, which
+// captures straight away without sending the customer to approve it
+func (c *PaymentController) chargePayPalVault(ctx context.Context, paymentID, orderID string, amount float64, currency string, method *models.PaymentMethod) (string, error) {
+	if c.PayPalClientID == "" || c.PayPalSecret == "" {
+		return "", fmt.Errorf("%w: PayPal isn't configured", service.ErrRenewalDeclined)
+	}
+
+	accessToken, err := c.payPalAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	source := "paypal"
+	if method.Type == "credit_card" {
+		source = "card"
+	}
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"intent": "CAPTURE",
+		"purchase_units": []map[string]interface{}{
+			{
+				"reference_id": orderID,
+				"amount": map[string]interface{}{
+					"currency_code": currency,
+					"value":         strconv.FormatFloat(amount, 'f', 2, 64),
+				},
+				"description": fmt.Sprintf("Subscription renewal for order %s", orderID),
+				"custom_id":   paymentID,
+			},
+		},
+		"payment_source": map[string]interface{}{
+			source: map[string]interface{}{
+				"vault_id": method.TokenID,
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/v2/checkout/orders", c.PayPalBaseURL), strings.NewReader(string(jsonData)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	// Each renewal is a new order, so keying the request on the order makes PayPal
+	// answer a repeated charge of it with the first result instead of charging again
+	req.Header.Set("PayPal-Request-Id", "renewal-"+orderID)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: PayPal API error: %s", service.ErrRenewalDeclined, string(body))
+	}
+
+	var orderResponse struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &orderResponse); err != nil {
+		return "", err
+	}
+	if orderResponse.Status != "COMPLETED" {
+		return orderResponse.ID, fmt.Errorf("%w: PayPal order status %q", service.ErrRenewalDeclined, orderResponse.Status)
+	}
+	return orderResponse.ID, nil
+}
+
+// payPalAccessToken gets an OAuth access token for the PayPal API
+func (c *PaymentController) payPalAccessToken(ctx context.Context) (string, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/v1/oauth2/token", c.PayPalBaseURL), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.PayPalClientID, c.PayPalSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+	if tokenResponse.AccessToken == "" {
+		return "", errors.New("failed to get PayPal access token")
+	}
+	return tokenResponse.AccessToken, nil
+}
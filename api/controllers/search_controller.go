@@ -0,0 +1,134 @@
+// File: api/controllers/search_controller.go
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchController handles site-wide search
+type SearchController struct {
+	DB *sql.DB
+}
+
+// NewSearchController creates a new search controller
+func NewSearchController(db *sql.DB) *SearchController {
+	return &SearchController{DB: db}
+}
+
+// Search returns ranked posts and products matching the query
+func (c *SearchController) Search(ctx *gin.Context) {
+	query := ctx.Query("q")
+	searchType := ctx.DefaultQuery("type", "all")
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	// Validate page and pageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	tsQuery := utils.BuildSearchQuery(query)
+	if tsQuery == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	// Build one SELECT per searchable type; snippets are highlighted
+	// after pagination so only the returned rows pay for ts_headline
+	var parts, counts []string
+	if searchType == "all" || searchType == "posts" {
+		from := ` FROM posts p, q WHERE ` + publishedPostCondition + ` AND p.search_vector @@ q.query`
+		parts = append(parts, `
+			SELECT 'post' AS type, p.id::text AS id, p.title AS title, p.slug AS slug,
+				coalesce(p.excerpt, '') || ' ' || coalesce(p.content, '') AS body,
+				ts_rank_cd(p.search_vector, q.query) AS rank,
+				NULL::numeric AS price, coalesce(p.featured_image, '') AS image,
+				coalesce(p.published_at, p.created_at) AS date`+from)
+		counts = append(counts, `(SELECT COUNT(*)`+from+`)`)
+	}
+	if searchType == "all" || searchType == "products" {
+		from := ` FROM products p, q WHERE ` + visibleProductCondition + ` AND p.search_vector @@ q.query`
+		parts = append(parts, `
+			SELECT 'product' AS type, p.id::text AS id, p.name AS title, p.slug AS slug,
+				coalesce(p.description, '') AS body,
+				ts_rank_cd(p.search_vector, q.query) AS rank,
+				p.price AS price, coalesce(p.featured_image, '') AS image,
+				p.created_at AS date`+from)
+		counts = append(counts, `(SELECT COUNT(*)`+from+`)`)
+	}
+	if len(parts) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search type"})
+		return
+	}
+
+	withQuery := `WITH q AS (SELECT to_tsquery('english', $1) AS query) `
+
+	rows, err := c.DB.Query(`
+		`+withQuery+`
+		SELECT type, id, title, slug,
+			ts_headline('english', body, (SELECT query FROM q), $2) AS snippet,
+			rank, price, image, date
+		FROM (`+strings.Join(parts, " UNION ALL ")+`) results
+		ORDER BY rank DESC, date DESC
+		LIMIT $3 OFFSET $4
+	`, tsQuery, utils.SearchHeadlineOptions, pageSize, (page-1)*pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+	defer rows.Close()
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			resultType, id, title, slug, snippet, image string
+			rank                                        float64
+			price                                       sql.NullFloat64
+			date                                        time.Time
+		)
+		if err := rows.Scan(&resultType, &id, &title, &slug, &snippet, &rank, &price, &image, &date); err != nil {
+			continue
+		}
+
+		result := map[string]interface{}{
+			"type":    resultType,
+			"id":      id,
+			"title":   title,
+			"slug":    slug,
+			"snippet": utils.SafeSnippet(snippet),
+			"rank":    rank,
+			"image":   image,
+			"date":    date,
+		}
+		if price.Valid {
+			result["price"] = price.Float64
+		}
+		results = append(results, result)
+	}
+
+	// Get total count for pagination
+	var total int
+	err = c.DB.QueryRow(withQuery+`SELECT `+strings.Join(counts, " + "), tsQuery).Scan(&total)
+	if err != nil {
+		total = len(results)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"query":       query,
+		"results":     results,
+		"page":        page,
+		"page_size":   pageSize,
+		"total":       total,
+		"total_pages": (total + pageSize - 1) / pageSize,
+	})
+}
@@ -0,0 +1,246 @@
+// File: api/controllers/series_controller.go
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/blog"
+)
+
+// GetSeriesList retrieves all series
+func (c *BlogController) GetSeriesList(ctx *gin.Context) {
+	var series []blog.Series
+	c.DB.Order("title ASC").Find(&series)
+	ctx.JSON(http.StatusOK, gin.H{"series": series})
+}
+
+// GetSeries retrieves a series by slug with its published posts in order
+func (c *BlogController) GetSeries(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	var series blog.Series
+	if err := c.DB.Where("slug = ?", slug).First(&series).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	var posts []blog.Post
+	c.DB.Preload("Categories").Preload("Tags").
+		Joins("JOIN series_posts ON series_posts.post_id = posts.id").
+		Where("series_posts.series_id = ? AND posts.published = ?", series.ID, true).
+		Order("series_posts.position ASC").
+		Find(&posts)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"series": series,
+		"posts":  posts,
+	})
+}
+
+// CreateSeries creates a new series
+func (c *BlogController) CreateSeries(ctx *gin.Context) {
+	var req blog.SeriesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if slug is unique
+	var existingSeries blog.Series
+	if c.DB.Where("slug = ?", req.Slug).First(&existingSeries).Error == nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A series with this slug already exists"})
+		return
+	}
+
+	series := blog.Series{
+		ID:          uuid.New(),
+		Title:       req.Title,
+		Slug:        req.Slug,
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := c.DB.Create(&series).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"series": series})
+}
+
+// UpdateSeries updates an existing series
+func (c *BlogController) UpdateSeries(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	var series blog.Series
+	if err := c.DB.Where("slug = ?", slug).First(&series).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	var req blog.SeriesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if new slug is different and unique
+	if req.Slug != slug {
+		var existingSeries blog.Series
+		if c.DB.Where("slug = ? AND id != ?", req.Slug, series.ID).First(&existingSeries).Error == nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "A series with this slug already exists"})
+			return
+		}
+	}
+
+	series.Title = req.Title
+	series.Slug = req.Slug
+	series.Description = req.Description
+	series.UpdatedAt = time.Now()
+
+	if err := c.DB.Save(&series).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"series": series})
+}
+
+// DeleteSeries deletes a series; its posts are kept
+func (c *BlogController) DeleteSeries(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	var series blog.Series
+	if err := c.DB.Where("slug = ?", slug).First(&series).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ?", series.ID).Delete(&blog.SeriesPost{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&series).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete series"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Series deleted successfully"})
+}
+
+// SetSeriesPosts replaces the ordered list of posts in a series
+func (c *BlogController) SetSeriesPosts(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	var series blog.Series
+	if err := c.DB.Where("slug = ?", slug).First(&series).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	var req blog.SeriesPostsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate post IDs, rejecting duplicates
+	memberships := make([]blog.SeriesPost, 0, len(req.PostIDs))
+	seen := make(map[uuid.UUID]bool, len(req.PostIDs))
+	for i, id := range req.PostIDs {
+		postID, err := uuid.Parse(id)
+		if err != nil || seen[postID] {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or duplicate post ID: " + id})
+			return
+		}
+		seen[postID] = true
+		memberships = append(memberships, blog.SeriesPost{SeriesID: series.ID, PostID: postID, Position: i + 1})
+	}
+
+	var count int64
+	if len(memberships) > 0 {
+		c.DB.Model(&blog.Post{}).Where("id IN ?", req.PostIDs).Count(&count)
+		if int(count) != len(memberships) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "One or more posts do not exist"})
+			return
+		}
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ?", series.ID).Delete(&blog.SeriesPost{}).Error; err != nil {
+			return err
+		}
+		if len(memberships) == 0 {
+			return nil
+		}
+		// A post can only belong to one series, so move it out of any other
+		if err := tx.Where("post_id IN ?", req.PostIDs).Delete(&blog.SeriesPost{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&memberships).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series posts"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"series": series, "posts": memberships})
+}
+
+// seriesNavigation returns the series a post belongs to with its published neighbours,
+// or nil if the post is not in a series
+func (c *BlogController) seriesNavigation(postID uuid.UUID) *blog.SeriesNavigation {
+	var membership blog.SeriesPost
+	if err := c.DB.Where("post_id = ?", postID).First(&membership).Error; err != nil {
+		return nil
+	}
+
+	var series blog.Series
+	if err := c.DB.Where("id = ?", membership.SeriesID).First(&series).Error; err != nil {
+		return nil
+	}
+
+	// Published posts in the series, in order, plus the current post even if it is a draft
+	var items []struct {
+		ID    uuid.UUID
+		Slug  string
+		Title string
+	}
+	c.DB.Table("series_posts").
+		Select("posts.id, posts.slug, posts.title").
+		Joins("JOIN posts ON posts.id = series_posts.post_id").
+		Where("series_posts.series_id = ? AND (posts.published = ? OR posts.id = ?)", series.ID, true, postID).
+		Order("series_posts.position ASC").
+		Scan(&items)
+
+	nav := &blog.SeriesNavigation{
+		ID:    series.ID,
+		Slug:  series.Slug,
+		Title: series.Title,
+		Total: len(items),
+	}
+
+	for i, item := range items {
+		if item.ID != postID {
+			continue
+		}
+		nav.Position = i + 1
+		if i > 0 {
+			nav.Prev = &blog.SeriesNavItem{Slug: items[i-1].Slug, Title: items[i-1].Title, Position: i}
+		}
+		if i < len(items)-1 {
+			nav.Next = &blog.SeriesNavItem{Slug: items[i+1].Slug, Title: items[i+1].Title, Position: i + 2}
+		}
+		break
+	}
+
+	return nav
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 	"fmt"
 
+	"github.com/adrianmcmains/blog-ecommerce/api/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -71,6 +72,21 @@ func (c *ShopController) GetProductBySlug(ctx *gin.Context) {
 	`, slug).Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.Slug, &product.Active)
 	
 	if err != nil {
+		// The product may have been renamed; send old links on to its current slug
+		var current string
+		lookupErr := c.DB.QueryRow(`
+			SELECT p.slug
+			FROM slug_redirects r
+			JOIN products p ON p.id::text = r.entity_id
+			WHERE r.entity_type = $1 AND r.old_slug = $2 AND p.active = true
+			ORDER BY r.created_at DESC
+			LIMIT 1
+		`, slugEntityProduct, slug).Scan(&current)
+		if lookupErr == nil && current != slug {
+			redirectToSlug(ctx, slug, current)
+			return
+		}
+		
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
@@ -162,34 +178,14 @@ func (c *ShopController) GetFeaturedProducts(ctx *gin.Context) {
 
 // GetNewArrivals gets the newest products
 func (c *ShopController) GetNewArrivals(ctx *gin.Context) {
-	rows, err := c.DB.Query(`
-		SELECT id, name, description, price, slug, featured_image
-		FROM products
-		WHERE active = true
-		ORDER BY created_at DESC
-		LIMIT 8
-	`)
-	
+	arrivals, err := c.fetchNewArrivals(8)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch new arrivals"})
 		return
 	}
-	defer rows.Close()
 	
 	var products []map[string]interface{}
-	for rows.Next() {
-		var product struct {
-			ID            string  `json:"id"`
-			Name          string  `json:"name"`
-			Description   string  `json:"description"`
-			Price         float64 `json:"price"`
-			Slug          string  `json:"slug"`
-			FeaturedImage string  `json:"featured_image"`
-		}
-		err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.Slug, &product.FeaturedImage)
-		if err != nil {
-			continue
-		}
+	for _, product := range arrivals {
 		products = append(products, map[string]interface{}{
 			"id":             product.ID,
 			"name":           product.Name,
@@ -203,6 +199,46 @@ func (c *ShopController) GetNewArrivals(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"products": products})
 }
 
+// newArrival is a row returned by the new arrivals query
+type newArrival struct {
+	ID            string
+	Name          string
+	Description   string
+	Price         float64
+	Slug          string
+	FeaturedImage string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// fetchNewArrivals returns the most recently added active products
+func (c *ShopController) fetchNewArrivals(limit int) ([]newArrival, error) {
+	rows, err := c.DB.Query(`
+		SELECT id, name, description, price, slug, featured_image, created_at, updated_at
+		FROM products
+		WHERE active = true
+		ORDER BY created_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var products []newArrival
+	for rows.Next() {
+		var product newArrival
+		err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.Slug,
+			&product.FeaturedImage, &product.CreatedAt, &product.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		products = append(products, product)
+	}
+	
+	return products, rows.Err()
+}
+
 // GetBestSellers gets the best-selling products
 func (c *ShopController) GetBestSellers(ctx *gin.Context) {
 	rows, err := c.DB.Query(`
@@ -279,29 +315,27 @@ func (c *ShopController) SearchProducts(ctx *gin.Context) {
 		FROM products p
 	`
 	
-	// Add category join if needed
-	if category != "" {
-		sqlQuery += `
-			JOIN product_categories pc ON p.id = pc.product_id
-			JOIN categories c ON pc.category_id = c.id
-		`
-	}
-	
 	sqlQuery += ` WHERE p.active = true `
 	
 	args := []interface{}{}
 	argIndex := 1
 	
-	// Add search condition
-	if query != "" {
-		sqlQuery += ` AND (p.name ILIKE $` + strconv.Itoa(argIndex) + ` OR p.description ILIKE $` + strconv.Itoa(argIndex) + `) `
-		args = append(args, "%"+query+"%")
+	// Add full-text search condition
+	tsQuery := utils.BuildSearchQuery(query)
+	searchArgIndex := 0
+	if tsQuery != "" {
+		searchArgIndex = argIndex
+		sqlQuery += ` AND p.search_vector @@ to_tsquery('english', $` + strconv.Itoa(argIndex) + `) `
+		args = append(args, tsQuery)
 		argIndex++
 	}
 	
-	// Add category condition
+	// Add category condition, taking in the categories nested under it
 	if category != "" {
-		sqlQuery += ` AND c.slug = $` + strconv.Itoa(argIndex) + ` `
+		sqlQuery += ` AND p.id IN (
+			SELECT m.product_id FROM product_categories_mapping m
+			WHERE m.category_id IN (` + productCategoryTreeSQL("slug", argIndex) + `)
+		) `
 		args = append(args, category)
 		argIndex++
 	}
@@ -333,8 +367,15 @@ func (c *ShopController) SearchProducts(ctx *gin.Context) {
 		sqlQuery += ` ORDER BY p.price DESC `
 	case "newest":
 		sqlQuery += ` ORDER BY p.created_at DESC `
-	default:
+	case "name":
 		sqlQuery += ` ORDER BY p.name ASC `
+	default:
+		// Rank by relevance when searching
+		if searchArgIndex > 0 {
+			sqlQuery += ` ORDER BY ts_rank_cd(p.search_vector, to_tsquery('english', $` + strconv.Itoa(searchArgIndex) + `)) DESC, p.name ASC `
+		} else {
+			sqlQuery += ` ORDER BY p.name ASC `
+		}
 	}
 	
 	// Add pagination
@@ -375,7 +416,7 @@ func (c *ShopController) SearchProducts(ctx *gin.Context) {
 	
 	// Get total count for pagination
 	countQuery := strings.Replace(sqlQuery, "SELECT p.id, p.name, p.description, p.price, p.slug, p.featured_image", "SELECT COUNT(*)", 1)
-	countQuery = strings.Split(countQuery, " LIMIT ")[0]
+	countQuery = strings.Split(countQuery, " ORDER BY ")[0]
 	
 	var total int
 	err = c.DB.QueryRow(countQuery, args[:len(args)-2]...).Scan(&total)
@@ -422,7 +463,7 @@ func (c *ShopController) SyncContentFromTina(ctx *gin.Context) {
 // GetInventoryReport generates an inventory report
 func (c *ShopController) GetInventoryReport(ctx *gin.Context) {
 	rows, err := c.DB.Query(`
-		SELECT p.id, p.name, p.sku, p.stock, p.price
+		SELECT p.id, p.name, COALESCE(p.sku, ''), p.stock_quantity, p.price
 		FROM products p
 		ORDER BY p.name
 	`)
@@ -458,19 +499,23 @@ func (c *ShopController) GetInventoryReport(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"inventory": inventory})
 }
 
-// UpdateStockLevels updates stock levels for products
+// UpdateStockLevels records stock-takes: each update sets a product's or variant's
+// stock to a counted level, and the difference goes in the stock ledger
 func (c *ShopController) UpdateStockLevels(ctx *gin.Context) {
 	var req struct {
 		Updates []struct {
 			ProductID string `json:"product_id" binding:"required"`
-			Stock     int    `json:"stock" binding:"required"`
-		} `json:"updates" binding:"required"`
+			VariantID string `json:"variant_id"`
+			Stock     *int   `json:"stock" binding:"required,gte=0"`
+			Reason    string `json:"reason"`
+		} `json:"updates" binding:"required,dive"`
 	}
 	
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	userID, _ := ctx.Get("userID")
 	
 	tx, err := c.DB.Begin()
 	if err != nil {
@@ -479,11 +524,22 @@ func (c *ShopController) UpdateStockLevels(ctx *gin.Context) {
 	}
 	
 	for _, update := range req.Updates {
-		_, err := tx.Exec(`
-			UPDATE products
-			SET stock = $1, updated_at = $2
-			WHERE id = $3
-		`, update.Stock, time.Now(), update.ProductID)
+		variantID := sql.NullString{String: update.VariantID, Valid: update.VariantID != ""}
+		table, id := "products", update.ProductID
+		if variantID.Valid {
+			table, id = "product_variants", update.VariantID
+		}
+		
+		// Lock the row so sales made during the count land before or after it
+		var onHand int
+		err := tx.QueryRow(`SELECT stock_quantity FROM `+table+` WHERE id = $1 FOR UPDATE`, id).Scan(&onHand)
+		if err == nil {
+			reason := update.Reason
+			if reason == "" {
+				reason = "Stock-take"
+			}
+			err = recordStockMovement(tx, update.ProductID, variantID, "stock_take", *update.Stock-onHand, reason, userID, nil)
+		}
 		
 		if err != nil {
 			tx.Rollback()
@@ -604,20 +660,27 @@ func (c *ShopController) CancelOrder(ctx *gin.Context) {
 		return
 	}
 	
-	// Return items to inventory if needed
-	_, err = tx.Exec(`
-		UPDATE products p
-		SET stock = p.stock + oi.quantity
-		FROM order_items oi
-		WHERE oi.order_id = $1 AND oi.product_id = p.id
-	`, orderID)
-	
-	if err != nil {
+	// Release the stock reserved at checkout, returning it to inventory if it was paid for
+	if err := releaseReservedStock(tx, orderID, userID); err != nil {
 		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update inventory"})
 		return
 	}
 	
+	// Give back the uses the order took from its promotions and coupons
+	if err := releaseOrderPromotions(tx, orderID); err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release promotions"})
+		return
+	}
+	
+	// Give back the gift card and store credit the order used, and void the gift cards bought in it
+	if err := releaseOrderCredit(tx, orderID, userID); err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund credit"})
+		return
+	}
+	
 	if err := tx.Commit(); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
@@ -730,8 +793,15 @@ func (c *ShopController) UpdateCategory(ctx *gin.Context) {
 		return
 	}
 	
+	tx, err := c.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+	defer tx.Rollback()
+	
 	// Update category
-	_, err = c.DB.Exec(`
+	_, err = tx.Exec(`
 		UPDATE product_categories
 		SET name = $1, slug = $2, description = $3, parent_id = $4, image_url = $5, updated_at = $6
 		WHERE id = $7
@@ -742,6 +812,17 @@ func (c *ShopController) UpdateCategory(ctx *gin.Context) {
 		return
 	}
 	
+	// Keep the old slug so existing links redirect
+	if err := recordSlugChangeSQL(tx, slugEntityProductCategory, categoryID, currentSlug, req.Slug); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+	
+	if err := tx.Commit(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+	
 	ctx.JSON(http.StatusOK, gin.H{"message": "Category updated successfully"})
 }
 
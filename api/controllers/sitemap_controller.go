@@ -0,0 +1,250 @@
+// File: api/controllers/sitemap_controller.go
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Visibility rules shared by the sitemap, search and related-content queries so
+// drafts and hidden products never leak into public indexes
+const (
+	publishedPostCondition  = "p.published = true"
+	visibleProductCondition = "p.active = true"
+)
+
+// SitemapController serves sitemap.xml and robots.txt
+type SitemapController struct {
+	DB      *sql.DB
+	SiteURL string
+}
+
+// NewSitemapController creates a new sitemap controller
+func NewSitemapController(db *sql.DB) *SitemapController {
+	siteURL := os.Getenv("SITE_URL")
+	if siteURL == "" {
+		siteURL = "http://localhost:1313"
+	}
+
+	return &SitemapController{
+		DB:      db,
+		SiteURL: strings.TrimRight(siteURL, "/"),
+	}
+}
+
+// sitemapLink is a URL to be listed in the sitemap
+type sitemapLink struct {
+	Path       string
+	LastMod    time.Time
+	ChangeFreq string
+	Priority   string
+}
+
+// GetSitemap returns the sitemap, or a sitemap index when there are too many URLs for one file
+func (c *SitemapController) GetSitemap(ctx *gin.Context) {
+	links, err := c.collectLinks()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+
+	if len(links) <= utils.SitemapMaxURLs {
+		c.writeURLSet(ctx, links)
+		return
+	}
+
+	// Split into numbered sitemap files
+	var entries []utils.SitemapEntry
+	for i := 0; i*utils.SitemapMaxURLs < len(links); i++ {
+		chunk := sitemapChunk(links, i+1)
+
+		var lastMod time.Time
+		for _, link := range chunk {
+			if link.LastMod.After(lastMod) {
+				lastMod = link.LastMod
+			}
+		}
+
+		entries = append(entries, utils.SitemapEntry{
+			Loc:     fmt.Sprintf("%s/sitemaps/sitemap-%d.xml", requestBaseURL(ctx), i+1),
+			LastMod: utils.SitemapDate(lastMod),
+		})
+	}
+
+	body, err := utils.RenderSitemapIndex(entries)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render sitemap index"})
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+// GetSitemapPage returns one file of a split sitemap
+func (c *SitemapController) GetSitemapPage(ctx *gin.Context) {
+	var page int
+	if _, err := fmt.Sscanf(ctx.Param("file"), "sitemap-%d.xml", &page); err != nil || page < 1 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Sitemap not found"})
+		return
+	}
+
+	links, err := c.collectLinks()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+
+	chunk := sitemapChunk(links, page)
+	if len(chunk) == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Sitemap not found"})
+		return
+	}
+
+	c.writeURLSet(ctx, chunk)
+}
+
+// GetRobots returns robots.txt, using the robots_txt system setting when present
+func (c *SitemapController) GetRobots(ctx *gin.Context) {
+	var robots string
+	err := c.DB.QueryRow(`
+		SELECT setting_value FROM system_settings
+		WHERE setting_key = 'robots_txt'
+	`).Scan(&robots)
+	if err != nil || strings.TrimSpace(robots) == "" {
+		robots = os.Getenv("ROBOTS_TXT")
+	}
+	if strings.TrimSpace(robots) == "" {
+		robots = "User-agent: *\nDisallow: /api/\nAllow: /api/feeds/\n"
+	}
+
+	// Always advertise the sitemap
+	if !strings.Contains(strings.ToLower(robots), "sitemap:") {
+		robots = strings.TrimRight(robots, "\n") + "\n\nSitemap: " + requestBaseURL(ctx) + "/sitemap.xml\n"
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(robots))
+}
+
+// writeURLSet renders links as a <urlset> document
+func (c *SitemapController) writeURLSet(ctx *gin.Context, links []sitemapLink) {
+	urls := make([]utils.SitemapURL, 0, len(links))
+	for _, link := range links {
+		urls = append(urls, utils.SitemapURL{
+			Loc:        c.SiteURL + link.Path,
+			LastMod:    utils.SitemapDate(link.LastMod),
+			ChangeFreq: link.ChangeFreq,
+			Priority:   link.Priority,
+		})
+	}
+
+	body, err := utils.RenderSitemap(urls)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render sitemap"})
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+// collectLinks gathers every public URL on the site
+func (c *SitemapController) collectLinks() ([]sitemapLink, error) {
+	links := []sitemapLink{
+		{Path: "/", ChangeFreq: "daily", Priority: "1.0"},
+		{Path: "/blog/", ChangeFreq: "daily", Priority: "0.9"},
+		{Path: "/shop/", ChangeFreq: "daily", Priority: "0.9"},
+	}
+
+	queries := []struct {
+		sql        string
+		prefix     string
+		changeFreq string
+		priority   string
+	}{
+		// Published posts
+		{`SELECT p.slug, p.updated_at FROM posts p WHERE ` + publishedPostCondition + ` ORDER BY p.updated_at DESC`,
+			"/blog/", "weekly", "0.8"},
+		// Blog categories with at least one published post
+		{`SELECT c.slug, MAX(GREATEST(c.updated_at, p.updated_at)) FROM categories c
+			JOIN post_categories pc ON pc.category_id = c.id
+			JOIN posts p ON p.id = pc.post_id
+			WHERE ` + publishedPostCondition + ` GROUP BY c.slug ORDER BY c.slug`,
+			"/categories/", "weekly", "0.5"},
+		// Tags with at least one published post
+		{`SELECT t.slug, MAX(GREATEST(t.updated_at, p.updated_at)) FROM tags t
+			JOIN post_tags pt ON pt.tag_id = t.id
+			JOIN posts p ON p.id = pt.post_id
+			WHERE ` + publishedPostCondition + ` GROUP BY t.slug ORDER BY t.slug`,
+			"/tags/", "weekly", "0.4"},
+		// Visible products
+		{`SELECT p.slug, p.updated_at FROM products p WHERE ` + visibleProductCondition + ` ORDER BY p.updated_at DESC`,
+			"/shop/", "weekly", "0.8"},
+		// Product categories with at least one visible product
+		{`SELECT pc.slug, MAX(GREATEST(pc.updated_at, p.updated_at)) FROM product_categories pc
+			JOIN product_categories_mapping m ON m.category_id = pc.id
+			JOIN products p ON p.id = m.product_id
+			WHERE ` + visibleProductCondition + ` GROUP BY pc.slug ORDER BY pc.slug`,
+			"/shop/categories/", "weekly", "0.5"},
+	}
+
+	for _, q := range queries {
+		rows, err := c.DB.Query(q.sql)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var slug string
+			var lastMod sql.NullTime
+			if err := rows.Scan(&slug, &lastMod); err != nil {
+				continue
+			}
+			links = append(links, sitemapLink{
+				Path:       q.prefix + slug + "/",
+				LastMod:    lastMod.Time,
+				ChangeFreq: q.changeFreq,
+				Priority:   q.priority,
+			})
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return links, nil
+}
+
+// sitemapChunk returns the links belonging to the 1-based sitemap file page
+func sitemapChunk(links []sitemapLink, page int) []sitemapLink {
+	start := (page - 1) * utils.SitemapMaxURLs
+	if start >= len(links) {
+		return nil
+	}
+	end := start + utils.SitemapMaxURLs
+	if end > len(links) {
+		end = len(links)
+	}
+	return links[start:end]
+}
+
+// requestBaseURL returns the scheme and host the request was made to
+func requestBaseURL(ctx *gin.Context) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + ctx.Request.Host
+}
@@ -0,0 +1,89 @@
+// File: api/controllers/slug_redirects.go
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/blog"
+)
+
+// Entity types recorded in slug_redirects
+const (
+	slugEntityPost            = "post"
+	slugEntityProduct         = "product"
+	slugEntityCategory        = "category"
+	slugEntityProductCategory = "product_category"
+)
+
+// recordSlugChange keeps oldSlug as a redirect to the entity when its slug changes
+func recordSlugChange(tx *gorm.DB, entityType, entityID, oldSlug, newSlug string) error {
+	if oldSlug == "" || oldSlug == newSlug {
+		return nil
+	}
+
+	// A live slug always wins, so drop any redirect still pointing the new slug elsewhere
+	if err := tx.Where("entity_type = ? AND old_slug = ?", entityType, newSlug).
+		Delete(&blog.SlugRedirect{}).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&blog.SlugRedirect{
+		EntityType: entityType,
+		EntityID:   entityID,
+		OldSlug:    oldSlug,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}).Error
+}
+
+// repointSlugRedirects moves the old slugs of a merged entity over to the one it was
+// merged into, dropping those the target already has or now uses
+func repointSlugRedirects(tx *gorm.DB, entityType, fromID, toID, toSlug string) error {
+	if err := tx.Model(&blog.SlugRedirect{}).
+		Where("entity_type = ? AND entity_id = ? AND old_slug <> ?", entityType, fromID, toSlug).
+		Where("old_slug NOT IN (?)", tx.Model(&blog.SlugRedirect{}).Select("old_slug").
+			Where("entity_type = ? AND entity_id = ?", entityType, toID)).
+		Updates(map[string]interface{}{"entity_id": toID, "updated_at": time.Now()}).Error; err != nil {
+		return err
+	}
+	return tx.Where("entity_type = ? AND entity_id = ?", entityType, fromID).
+		Delete(&blog.SlugRedirect{}).Error
+}
+
+// recordSlugChangeSQL is recordSlugChange for the database/sql controllers
+func recordSlugChangeSQL(tx *sql.Tx, entityType, entityID, oldSlug, newSlug string) error {
+	if oldSlug == "" || oldSlug == newSlug {
+		return nil
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM slug_redirects
+		WHERE entity_type = $1 AND old_slug = $2
+	`, entityType, newSlug); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO slug_redirects (entity_type, entity_id, old_slug, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT DO NOTHING
+	`, entityType, entityID, oldSlug)
+	return err
+}
+
+// redirectToSlug permanently redirects a request for oldSlug to the same URL with newSlug,
+// keeping the query string
+func redirectToSlug(ctx *gin.Context, oldSlug, newSlug string) {
+	target := *ctx.Request.URL
+	target.Path = strings.TrimSuffix(target.Path, oldSlug) + newSlug
+	target.RawPath = ""
+	ctx.Redirect(http.StatusMovedPermanently, target.RequestURI())
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// errStockShort is returned when a stock change would take stock on hand below zero
+var errStockShort = errors.New("not enough stock")
+
+// reservedLine is the product or variant, customer and quantity of a stock reservation
+type reservedLine struct {
+	ProductID string
+	VariantID sql.NullString
+	UserID    string
+	Quantity  int
+	Status    string
+}
+
+// commitReservedStock takes the stock reserved for an order at checkout off stock on
+// hand once the order is paid for, recording each line as a sale in the stock ledger
+func commitReservedStock(db *sql.DB, orderID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Claiming the reservations in the same statement keeps two webhooks for one
+	// payment from taking the stock twice
+	lines, err := claimReservations(tx, `
+		UPDATE stock_reservations r
+		SET status = 'converted', updated_at = $2
+		FROM stock_reservations held
+		WHERE held.id = r.id AND r.order_id = $1 AND r.status = 'active'
+		RETURNING r.product_id, r.variant_id, r.user_id, r.quantity, held.status
+	`, orderID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, line := range lines {
+		if err := recordStockMovement(tx, line.ProductID, line.VariantID, "sale", -line.Quantity, "Order paid", line.UserID, orderID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// releaseReservedStock gives back the stock held for a canceled order: active
+// reservations stop counting against available stock, and stock already taken for a
+// paid order is put back on hand as a cancellation in the stock ledger
+func releaseReservedStock(tx *sql.Tx, orderID string, actorID interface{}) error {
+	lines, err := claimReservations(tx, `
+		UPDATE stock_reservations r
+		SET status = 'released', updated_at = $2
+		FROM stock_reservations held
+		WHERE held.id = r.id AND r.order_id = $1 AND r.status IN ('active', 'converted')
+		RETURNING r.product_id, r.variant_id, r.user_id, r.quantity, held.status
+	`, orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if line.Status != "converted" {
+			continue
+		}
+		if err := recordStockMovement(tx, line.ProductID, line.VariantID, "cancellation", line.Quantity, "Order canceled", actorID, orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimReservations runs an update of an order's reservations and returns the lines
+// it changed, with the status they had before
+func claimReservations(tx *sql.Tx, query, orderID string) ([]reservedLine, error) {
+	rows, err := tx.Query(query, orderID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []reservedLine
+	for rows.Next() {
+		var line reservedLine
+		if err := rows.Scan(&line.ProductID, &line.VariantID, &line.UserID, &line.Quantity, &line.Status); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// recordStockMovement changes a product's or variant's stock on hand and records the
+// change in the stock ledger. The change is conditional, so it fails with
+// errStockShort rather than take stock below zero.
+func recordStockMovement(tx *sql.Tx, productID string, variantID sql.NullString, movementType string, quantity int, reason string, actorID interface{}, orderID interface{}) error {
+	table, id := "products", productID
+	if variantID.Valid {
+		table, id = "product_variants", variantID.String
+	}
+
+	var balance int
+	var sku string
+	err := tx.QueryRow(`
+		UPDATE `+table+`
+		SET stock_quantity = stock_quantity + $1, updated_at = $2
+		WHERE id = $3 AND stock_quantity + $1 >= 0
+		RETURNING stock_quantity, COALESCE(sku, '')
+	`, quantity, time.Now(), id).Scan(&balance, &sku)
+	if err == sql.ErrNoRows {
+		return errStockShort
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO stock_movements (id, product_id, variant_id, sku, type, quantity, balance, reason, actor_id, order_id, created_at, updated_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+	`, productID, variantID, sku, movementType, quantity, balance, reason, actorID, orderID, time.Now())
+	return err
+}
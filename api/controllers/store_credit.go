@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"database/sql"
+	"time"
+)
+
+// creditTaken is what an order took from a gift card or store credit balance
+type creditTaken struct {
+	GiftCardID sql.NullString
+	UserID     sql.NullString
+	Amount     float64
+}
+
+// activatePurchasedGiftCards makes the gift cards bought in an order usable once the
+// order is paid for
+func activatePurchasedGiftCards(db *sql.DB, orderID string) error {
+	_, err := db.Exec(`
+		UPDATE gift_cards
+		SET status = 'active', updated_at = $2
+		WHERE order_id = $1 AND status = 'pending'
+	`, orderID, time.Now())
+	return err
+}
+
+// releaseOrderCredit gives back the gift card and store credit a canceled order was
+// paid with, and voids the gift cards bought in it with whatever is left on them,
+// recording each change in the credit ledger
+func releaseOrderCredit(tx *sql.Tx, orderID string, actorID interface{}) error {
+	// Net out earlier refunds, so an order canceled twice doesn't give credit back twice
+	rows, err := tx.Query(`
+		SELECT gift_card_id, user_id, SUM(amount)
+		FROM credit_transactions
+		WHERE order_id = $1 AND type IN ('redemption', 'refund')
+		GROUP BY gift_card_id, user_id
+		HAVING SUM(amount) < 0
+	`, orderID)
+	if err != nil {
+		return err
+	}
+	var taken []creditTaken
+	for rows.Next() {
+		var t creditTaken
+		if err := rows.Scan(&t.GiftCardID, &t.UserID, &t.Amount); err != nil {
+			rows.Close()
+			return err
+		}
+		taken = append(taken, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, t := range taken {
+		var balance float64
+		if t.GiftCardID.Valid {
+			err = tx.QueryRow(`
+				UPDATE gift_cards SET balance = balance + $1, updated_at = $2
+				WHERE id = $3
+				RETURNING balance
+			`, -t.Amount, now, t.GiftCardID.String).Scan(&balance)
+		} else {
+			err = tx.QueryRow(`
+				UPDATE store_credits SET balance = balance + $1, updated_at = $2
+				WHERE user_id = $3
+				RETURNING balance
+			`, -t.Amount, now, t.UserID.String).Scan(&balance)
+		}
+		if err != nil {
+			return err
+		}
+		if err := recordCreditTransaction(tx, t.GiftCardID, t.UserID, "refund", -t.Amount, balance, actorID, orderID); err != nil {
+			return err
+		}
+	}
+
+	// Void the gift cards bought in the order
+	voided, err := tx.Query(`
+		UPDATE gift_cards g
+		SET balance = 0, status = 'void', updated_at = $2
+		FROM gift_cards old
+		WHERE old.id = g.id AND g.order_id = $1 AND g.status <> 'void'
+		RETURNING g.id, old.balance
+	`, orderID, now)
+	if err != nil {
+		return err
+	}
+	var cards []creditTaken
+	for voided.Next() {
+		var card creditTaken
+		if err := voided.Scan(&card.GiftCardID, &card.Amount); err != nil {
+			voided.Close()
+			return err
+		}
+		cards = append(cards, card)
+	}
+	voided.Close()
+	if err := voided.Err(); err != nil {
+		return err
+	}
+
+	for _, card := range cards {
+		if err := recordCreditTransaction(tx, card.GiftCardID, card.UserID, "void", -card.Amount, 0, actorID, orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordCreditTransaction adds an entry to the credit ledger of a gift card or a
+// customer's store credit
+func recordCreditTransaction(tx *sql.Tx, giftCardID, userID sql.NullString, transactionType string, amount, balance float64, actorID interface{}, orderID string) error {
+	_, err := tx.Exec(`
+		INSERT INTO credit_transactions (id, gift_card_id, user_id, type, amount, balance, reason, actor_id, order_id, created_at, updated_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, 'Order canceled', $6, $7, $8, $8)
+	`, giftCardID, userID, transactionType, amount, balance, actorID, orderID, time.Now())
+	return err
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// visitorCookie identifies a browser across visits so clicks can be matched to later orders
+const visitorCookie = "visitor_id"
+
+// TrackProductClick handles recording a click-through from a post to an embedded product
+func (h *BlogHandler) TrackProductClick(c *gin.Context) {
+	var input models.TrackClickInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ""
+	if id, ok := c.Get("userID"); ok {
+		userID, _ = id.(string)
+	}
+
+	err := h.services.Attribution.TrackClick(c.Request.Context(), c.Param("id"), input.ProductID, ensureVisitorID(c), userID)
+	if err != nil {
+		switch err {
+		case service.ErrPostNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		case service.ErrProductNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetAttribution handles retrieving the posts that drove the most clicks and orders
+func (h *BlogHandler) GetAttribution(c *gin.Context) {
+	days := attributionDays(c)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	stats, err := h.services.Attribution.TopPosts(c.Request.Context(), days, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"posts": stats, "days": days})
+}
+
+// GetPostAttribution handles retrieving a post's clicks and orders by product
+func (h *BlogHandler) GetPostAttribution(c *gin.Context) {
+	days := attributionDays(c)
+
+	stats, err := h.services.Attribution.PostProducts(c.Request.Context(), c.Param("id"), days)
+	if err != nil {
+		if err == service.ErrPostNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"products": stats, "days": days})
+}
+
+// attributionDays parses the reporting period in days
+func attributionDays(c *gin.Context) int {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		days = 30
+	}
+	return days
+}
+
+// ensureVisitorID returns the visitor cookie, setting a new one if the browser has none
+func ensureVisitorID(c *gin.Context) string {
+	if id, err := c.Cookie(visitorCookie); err == nil && id != "" {
+		return id
+	}
+	id := uuid.New().String()
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(visitorCookie, id, 365*24*60*60, "/", "", false, true)
+	return id
+}
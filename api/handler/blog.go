@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/adrianmcmains/blog-ecommerce/internal/models"
 	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -45,8 +47,10 @@ func (h *BlogHandler) CreatePost(c *gin.Context) {
 	serviceInput := service.PostInput{
 		Title:      input.Title,
 		Content:    input.Content,
-		Categories: input.Categories,
-		Tags:       input.Tags,
+		Categories:    input.Categories,
+		Tags:          input.Tags,
+		Locale:        input.Locale,
+		TranslationOf: input.TranslationOf,
 	}
 
 	// Set optional fields if they exist in your input model
@@ -58,7 +62,19 @@ func (h *BlogHandler) CreatePost(c *gin.Context) {
 	// Create post
 	post, err := h.services.Blog.CreatePost(c.Request.Context(), serviceInput, userIDStr)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if writeUnknownProducts(c, err) {
+			return
+		}
+		switch err {
+		case service.ErrPostNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source post for translation not found"})
+		case service.ErrUnsupportedLanguage:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		case service.ErrTranslationExists:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -69,12 +85,53 @@ func (h *BlogHandler) CreatePost(c *gin.Context) {
 func (h *BlogHandler) GetPost(c *gin.Context) {
 	id := c.Param("id")
 	post, err := h.services.Blog.GetPost(c.Request.Context(), id)
-	if err != nil {
+	if err != nil || (post.Status != models.PostStatusPublished && !canSeeUnpublished(c)) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	// Expand embedded products into cards with live price and stock
+	products, err := h.services.Blog.GetProductCards(c.Request.Context(), post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load embedded products"})
+		return
+	}
+
+	// Link to the posts before and after this one when it is part of a series
+	series, err := h.services.Blog.GetSeriesNavigation(c.Request.Context(), post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load series navigation"})
+		return
+	}
+
+	response := struct {
+		*models.Post
+		Products []models.ProductCard     `json:"products"`
+		Series   *models.SeriesNavigation `json:"series"`
+		Rendered *util.RenderedMarkdown   `json:"rendered,omitempty"`
+	}{Post: post, Products: products, Series: series}
+
+	// Optionally include sanitized HTML rendered from the Markdown content
+	if c.Query("render") == "html" {
+		response.Rendered, err = util.RenderMarkdown(post.Content)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render post content"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// canSeeUnpublished reports whether the caller takes part in the editorial workflow
+// and so may see posts that aren't published
+func canSeeUnpublished(c *gin.Context) bool {
+	role, _ := c.Get("userRole")
+	switch role {
+	case models.RoleAdmin, models.RoleEditor, models.RoleContributor:
+		return true
+	}
+	return false
 }
 
 // ListPosts handles retrieving a list of blog posts with pagination and filtering
@@ -93,9 +150,28 @@ func (h *BlogHandler) ListPosts(c *gin.Context) {
 	// Get filter parameters
 	category := c.Query("category")
 	authorID := c.Query("author")
+	lang := util.ResolveLanguage(c.Query("lang"), c.GetHeader("Accept-Language"))
+
+	query := models.PostQuery{
+		Category: category,
+		Tag:      c.Query("tag"),
+		AuthorID: authorID,
+		Search:   c.Query("q"),
+		Page:     page,
+		Limit:    limit,
+	}
+	if published, err := strconv.ParseBool(c.Query("published")); err == nil {
+		query.Published = &published
+	}
+	// Only the newsroom sees posts that are still going through the workflow
+	if canSeeUnpublished(c) {
+		query.Status = c.Query("status")
+	} else {
+		query.Status = models.PostStatusPublished
+	}
 
 	// Call service with correct parameters
-	posts, total, err := h.services.Blog.ListPosts(c.Request.Context(), page, limit, category, authorID)
+	posts, total, err := h.services.Blog.ListPostsInLanguage(c.Request.Context(), query, lang)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -110,9 +186,25 @@ func (h *BlogHandler) ListPosts(c *gin.Context) {
 		"page":       page,
 		"limit":      limit,
 		"totalPages": totalPages,
+		"lang":       lang,
 	})
 }
 
+// GetTranslations handles retrieving every language version of a blog post
+func (h *BlogHandler) GetTranslations(c *gin.Context) {
+	translations, err := h.services.Blog.GetTranslations(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err == service.ErrPostNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"translations": translations})
+}
+
 // UpdatePost handles updating an existing blog post
 func (h *BlogHandler) UpdatePost(c *gin.Context) {
     id := c.Param("id")
@@ -157,15 +249,26 @@ func (h *BlogHandler) UpdatePost(c *gin.Context) {
     if input.Tags != nil {
         serviceInput.Tags = *input.Tags
     }
+
+    if input.Locale != nil {
+        serviceInput.Locale = *input.Locale
+    }
     
     // Update post
     post, err := h.services.Blog.UpdatePost(c.Request.Context(), id, serviceInput, userIDStr)
     if err != nil {
+        if writeUnknownProducts(c, err) {
+            return
+        }
         switch err {
         case service.ErrPostNotFound:
             c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
         case service.ErrNotAuthorized:
             c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this post"})
+        case service.ErrUnsupportedLanguage:
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+        case service.ErrTranslationExists:
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
         default:
             c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         }
@@ -205,4 +308,133 @@ func (h *BlogHandler) DeletePost(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
-}
\ No newline at end of file
+}
+// TransitionPost handles moving a post through the editorial workflow
+func (h *BlogHandler) TransitionPost(c *gin.Context) {
+	var input models.PostTransitionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	post, err := h.services.Blog.TransitionPost(c.Request.Context(), c.Param("id"), input.Status, input.Comment, userID)
+	if err != nil {
+		writeWorkflowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// ListReviewComments handles retrieving the review comments on a post
+func (h *BlogHandler) ListReviewComments(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	comments, err := h.services.Blog.ListReviewComments(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		writeWorkflowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": comments})
+}
+
+// AddReviewComment handles attaching a review comment to a post
+func (h *BlogHandler) AddReviewComment(c *gin.Context) {
+	var input models.ReviewCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	comment, err := h.services.Blog.AddReviewComment(c.Request.Context(), c.Param("id"), input.Body, userID)
+	if err != nil {
+		writeWorkflowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// ListSubmissions handles retrieving the review queue, filtered by workflow status
+func (h *BlogHandler) ListSubmissions(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	posts, total, err := h.services.Blog.ListSubmissions(c.Request.Context(), c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":      posts,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// currentUserID returns the authenticated user's ID, writing an error response if there is none
+func currentUserID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return "", false
+	}
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return "", false
+	}
+	return userIDStr, true
+}
+
+// writeUnknownProducts responds with the unknown product slugs if err is an
+// UnknownProductsError, reporting whether it did
+func writeUnknownProducts(c *gin.Context, err error) bool {
+	var unknown *service.UnknownProductsError
+	if !errors.As(err, &unknown) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":            "Content embeds products that don't exist",
+		"unknown_products": unknown.Slugs,
+	})
+	return true
+}
+
+// writeWorkflowError maps editorial workflow errors to responses
+func writeWorkflowError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrPostNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+	case service.ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to perform this action"})
+	case service.ErrInvalidTransition:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
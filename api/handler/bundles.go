@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductRevenue reports what each product and variant sold for between two dates,
+// including its share of bundle sales, in the base currency
+func (h *ShopHandler) GetProductRevenue(c *gin.Context) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if value := c.Query("from"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date like 2006-01-02"})
+			return
+		}
+		from = date
+	}
+	if value := c.Query("to"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date like 2006-01-02"})
+			return
+		}
+		to = date
+	}
+
+	rows, err := h.services.Shop.ProductRevenue(c.Request.Context(), models.ProductRevenueFilter{
+		From: from,
+		To:   to.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var revenue, bundleRevenue float64
+	for _, row := range rows {
+		revenue += row.Revenue
+		bundleRevenue += row.BundleRevenue
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rows":           rows,
+		"from":           from.Format("2006-01-02"),
+		"to":             to.Format("2006-01-02"),
+		"currency":       util.BaseCurrency(),
+		"revenue":        util.RoundCurrency(revenue, util.BaseCurrency()),
+		"bundle_revenue": util.RoundCurrency(bundleRevenue, util.BaseCurrency()),
+	})
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetGiftCardBalance looks up what's left on a gift card by its code
+func (h *ShopHandler) GetGiftCardBalance(c *gin.Context) {
+	balance, err := h.services.Shop.GiftCardBalance(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+// GetStoreCredit returns the current user's store credit balance and its ledger
+func (h *ShopHandler) GetStoreCredit(c *gin.Context) {
+	page, limit := creditLedgerPage(c)
+	userID, _ := c.Get("userID")
+
+	credit, entries, total, err := h.services.Shop.StoreCredit(c.Request.Context(), userID.(string), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance":      credit.Balance,
+		"transactions": entries,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+		"totalPages":   (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// ApplyOrderCredit pays part or all of one of the current user's pending orders with
+// gift cards and store credit
+func (h *ShopHandler) ApplyOrderCredit(c *gin.Context) {
+	var input models.ApplyCreditInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	input.UserID = userID.(string)
+	input.OrderID = c.Param("id")
+
+	order, err := h.services.Shop.ApplyCredit(c.Request.Context(), input)
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// IssueStoreCredit adds store credit to a customer's balance
+func (h *ShopHandler) IssueStoreCredit(c *gin.Context) {
+	var input models.StoreCreditInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	input.ActorID, _ = userID.(string)
+
+	entry, err := h.services.Shop.IssueStoreCredit(c.Request.Context(), input)
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// IssueGiftCard creates a gift card from the admin
+func (h *ShopHandler) IssueGiftCard(c *gin.Context) {
+	var input models.GiftCardInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	input.ActorID, _ = userID.(string)
+
+	card, err := h.services.Shop.IssueGiftCard(c.Request.Context(), input)
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, card)
+}
+
+// GetGiftCardLedger returns a gift card and its ledger entries, newest first
+func (h *ShopHandler) GetGiftCardLedger(c *gin.Context) {
+	page, limit := creditLedgerPage(c)
+
+	card, entries, total, err := h.services.Shop.GiftCardLedger(c.Request.Context(), c.Param("code"), page, limit)
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"gift_card":    card,
+		"transactions": entries,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+		"totalPages":   (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// creditLedgerPage reads the page of a credit ledger asked for
+func creditLedgerPage(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+	return page, limit
+}
+
+// writeCreditError responds with the status for an error from issuing or applying credit
+func writeCreditError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrGiftCardNotFound), errors.Is(err, service.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrGiftCardUnusable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCredit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		writeCheckoutError(c, err)
+	}
+}
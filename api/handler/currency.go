@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// currencyCookie remembers the display currency a visitor picked
+const currencyCookie = "currency"
+
+// ListCurrencies lists the base currency and the other currencies customers can shop in
+func (h *ShopHandler) ListCurrencies(c *gin.Context) {
+	currencies, err := h.services.Shop.ListCurrencies(c.Request.Context(), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base":       util.BaseCurrency(),
+		"selected":   displayCurrency(c),
+		"currencies": currencies,
+	})
+}
+
+// SelectCurrency remembers the currency a visitor wants prices shown in
+func (h *ShopHandler) SelectCurrency(c *gin.Context) {
+	var input struct {
+		Currency string `json:"currency" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency, err := h.services.Shop.CheckCurrency(c.Request.Context(), input.Currency)
+	if err != nil {
+		writeCurrencyError(c, err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(currencyCookie, currency, 365*24*60*60, "/", "", false, false)
+	c.JSON(http.StatusOK, gin.H{"currency": currency})
+}
+
+// ListAllCurrencies lists every currency, including inactive ones
+func (h *ShopHandler) ListAllCurrencies(c *gin.Context) {
+	currencies, err := h.services.Shop.ListCurrencies(c.Request.Context(), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"base": util.BaseCurrency(), "currencies": currencies})
+}
+
+// CreateCurrency adds a currency and its exchange rate
+func (h *ShopHandler) CreateCurrency(c *gin.Context) {
+	var input models.CurrencyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency, err := h.services.Shop.CreateCurrency(c.Request.Context(), input)
+	if err != nil {
+		writeCurrencyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, currency)
+}
+
+// UpdateCurrency changes a currency's exchange rate or details
+func (h *ShopHandler) UpdateCurrency(c *gin.Context) {
+	var input models.CurrencyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency, err := h.services.Shop.UpdateCurrency(c.Request.Context(), c.Param("code"), input)
+	if err != nil {
+		writeCurrencyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, currency)
+}
+
+// DeleteCurrency removes a currency and its price overrides
+func (h *ShopHandler) DeleteCurrency(c *gin.Context) {
+	if err := h.services.Shop.DeleteCurrency(c.Request.Context(), c.Param("code")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Currency deleted successfully"})
+}
+
+// SetProductPrice sets a product's or variant's price in a currency
+func (h *ShopHandler) SetProductPrice(c *gin.Context) {
+	var input models.ProductPriceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	price, err := h.services.Shop.SetProductPrice(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		writeCurrencyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, price)
+}
+
+// DeleteProductPrice removes a price override; ?variant_id picks a variant's override
+func (h *ShopHandler) DeleteProductPrice(c *gin.Context) {
+	err := h.services.Shop.DeleteProductPrice(c.Request.Context(), c.Param("id"), c.Query("variant_id"), c.Param("currency"))
+	if err != nil {
+		writeCurrencyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Price override deleted successfully"})
+}
+
+// displayCurrency returns the currency to show prices in: an explicit currency
+// parameter wins, then the one the visitor picked, then the base currency
+func displayCurrency(c *gin.Context) string {
+	if currency := c.Query("currency"); currency != "" {
+		return util.NormalizeCurrency(currency)
+	}
+	if currency, err := c.Cookie(currencyCookie); err == nil && currency != "" {
+		return util.NormalizeCurrency(currency)
+	}
+	return util.BaseCurrency()
+}
+
+// writeCurrencyError responds with the status matching a currency error
+func writeCurrencyError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrUnsupportedCurrency) || errors.Is(err, service.ErrInvalidCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
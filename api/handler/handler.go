@@ -5,15 +5,17 @@ import (
 )
 
 type Handler struct {
-    Auth *AuthHandler
-    Blog *BlogHandler
-    Shop *ShopHandler
+    Auth       *AuthHandler
+    Blog       *BlogHandler
+    Shop       *ShopHandler
+    Newsletter *NewsletterHandler
 }
 
 func NewHandler(services *service.Service) *Handler {
     return &Handler{
-        Auth: NewAuthHandler(services),
-        Blog: NewBlogHandler(services),
-        Shop: NewShopHandler(services),
+        Auth:       NewAuthHandler(services),
+        Blog:       NewBlogHandler(services),
+        Shop:       NewShopHandler(services),
+        Newsletter: NewNewsletterHandler(services),
     }
 }
\ No newline at end of file
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewsletterHandler handles newsletter subscriptions
+type NewsletterHandler struct {
+	services *service.Service
+}
+
+// NewNewsletterHandler creates a new newsletter handler
+func NewNewsletterHandler(services *service.Service) *NewsletterHandler {
+	return &NewsletterHandler{
+		services: services,
+	}
+}
+
+// Subscribe handles a newsletter sign-up; logged-in users are subscribed with their
+// account email unless they give another
+func (h *NewsletterHandler) Subscribe(c *gin.Context) {
+	var input models.SubscribeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := input.Email
+	if email == "" {
+		if accountEmail, ok := c.Get("userEmail"); ok {
+			email, _ = accountEmail.(string)
+		}
+	}
+	userID := ""
+	if id, ok := c.Get("userId"); ok {
+		userID = fmt.Sprint(id)
+	}
+
+	subscriber, err := h.services.Newsletter.Subscribe(c.Request.Context(), email, userID, input.Categories)
+	if err != nil {
+		if err == service.ErrEmailRequired {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+		return
+	}
+
+	message := "Please check your email to confirm your subscription"
+	if subscriber.ChangePending() {
+		message = "Please check your email to confirm the change to your subscription"
+	} else if subscriber.Status == models.SubscriberActive {
+		message = "Your subscription has been updated"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "status": subscriber.Status})
+}
+
+// Confirm handles the double opt-in link from the confirmation email
+func (h *NewsletterHandler) Confirm(c *gin.Context) {
+	if _, err := h.services.Newsletter.Confirm(c.Request.Context(), c.Query("token")); err != nil {
+		writeSubscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Your subscription is confirmed"})
+}
+
+// Unsubscribe handles the unsubscribe link included in every newsletter email
+func (h *NewsletterHandler) Unsubscribe(c *gin.Context) {
+	if _, err := h.services.Newsletter.Unsubscribe(c.Request.Context(), c.Query("token")); err != nil {
+		writeSubscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You have been unsubscribed"})
+}
+
+// GetStats handles retrieving subscriber counts by status
+func (h *NewsletterHandler) GetStats(c *gin.Context) {
+	stats, err := h.services.Newsletter.Stats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// ExportSubscribers handles downloading every subscriber as CSV
+func (h *NewsletterHandler) ExportSubscribers(c *gin.Context) {
+	subscribers, err := h.services.Newsletter.ListSubscribers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("subscribers-%s.csv", time.Now().Format("2006-01-02"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"email", "status", "categories", "user_id", "subscribed_at", "confirmed_at", "unsubscribed_at"})
+	for _, subscriber := range subscribers {
+		w.Write([]string{
+			subscriber.Email,
+			subscriber.Status,
+			strings.Join(subscriber.Categories, ";"),
+			subscriber.UserID,
+			subscriber.CreatedAt.Format(time.RFC3339),
+			formatOptionalTime(subscriber.ConfirmedAt),
+			formatOptionalTime(subscriber.UnsubscribedAt),
+		})
+	}
+	w.Flush()
+}
+
+// SendDigests handles sending the digest now to every subscriber who is due one
+func (h *NewsletterHandler) SendDigests(c *gin.Context) {
+	sent, err := h.services.Newsletter.SendDueDigests(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sent": sent})
+}
+
+// writeSubscriptionError responds with the status matching a newsletter error
+func writeSubscriptionError(c *gin.Context, err error) {
+	if err == service.ErrInvalidSubscriptionToken {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// formatOptionalTime formats t as RFC 3339, or returns an empty string if it is nil
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
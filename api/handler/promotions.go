@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePromotion creates a coupon or automatic promotion
+func (h *ShopHandler) CreatePromotion(c *gin.Context) {
+	var input models.PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion, err := h.services.Shop.CreatePromotion(c.Request.Context(), input)
+	if err != nil {
+		writePromotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// ListPromotions lists every promotion with its usage so far
+func (h *ShopHandler) ListPromotions(c *gin.Context) {
+	promotions, err := h.services.Shop.ListPromotions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promotions": promotions})
+}
+
+// GetPromotion returns a single promotion
+func (h *ShopHandler) GetPromotion(c *gin.Context) {
+	promotion, err := h.services.Shop.GetPromotion(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// UpdatePromotion replaces a promotion's settings
+func (h *ShopHandler) UpdatePromotion(c *gin.Context) {
+	var input models.PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion, err := h.services.Shop.UpdatePromotion(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		writePromotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// DeletePromotion deletes a promotion
+func (h *ShopHandler) DeletePromotion(c *gin.Context) {
+	if err := h.services.Shop.DeletePromotion(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted successfully"})
+}
+
+// QuoteCart prices the given cart items with the promotions and coupons that apply
+func (h *ShopHandler) QuoteCart(c *gin.Context) {
+	var input models.QuoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	input.UserID = userID.(string)
+	if input.Currency == "" {
+		input.Currency = displayCurrency(c)
+	}
+
+	quote, err := h.services.Shop.QuoteCart(c.Request.Context(), input)
+	if err != nil {
+		writeCheckoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// writePromotionError responds with the status matching a promotion or checkout error
+func writePromotionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPromotion), errors.Is(err, service.ErrInvalidCoupon):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPromotionUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateShippingZone creates a shipping zone
+func (h *ShopHandler) CreateShippingZone(c *gin.Context) {
+	var input models.ShippingZoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := h.services.Shop.CreateShippingZone(c.Request.Context(), input)
+	if err != nil {
+		writeCheckoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+// ListShippingZones lists every shipping zone with its methods
+func (h *ShopHandler) ListShippingZones(c *gin.Context) {
+	zones, err := h.services.Shop.ListShippingZones(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"zones": zones})
+}
+
+// GetShippingZone returns a single shipping zone with its methods
+func (h *ShopHandler) GetShippingZone(c *gin.Context) {
+	zone, err := h.services.Shop.GetShippingZone(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping zone not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// UpdateShippingZone changes where a shipping zone covers
+func (h *ShopHandler) UpdateShippingZone(c *gin.Context) {
+	var input models.ShippingZoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := h.services.Shop.UpdateShippingZone(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		writeCheckoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// DeleteShippingZone deletes a shipping zone and its methods
+func (h *ShopHandler) DeleteShippingZone(c *gin.Context) {
+	if err := h.services.Shop.DeleteShippingZone(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shipping zone deleted successfully"})
+}
+
+// CreateShippingMethod adds a shipping method to a zone
+func (h *ShopHandler) CreateShippingMethod(c *gin.Context) {
+	var input models.ShippingMethodInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method, err := h.services.Shop.CreateShippingMethod(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		writeCheckoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, method)
+}
+
+// UpdateShippingMethod changes a shipping method
+func (h *ShopHandler) UpdateShippingMethod(c *gin.Context) {
+	var input models.ShippingMethodInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method, err := h.services.Shop.UpdateShippingMethod(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		writeCheckoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, method)
+}
+
+// DeleteShippingMethod deletes a shipping method
+func (h *ShopHandler) DeleteShippingMethod(c *gin.Context) {
+	if err := h.services.Shop.DeleteShippingMethod(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shipping method deleted successfully"})
+}
+
+// QuoteShipping returns the shipping methods available for cart items and an address
+func (h *ShopHandler) QuoteShipping(c *gin.Context) {
+	var input models.ShippingQuoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	input.UserID = userID.(string)
+	if input.Currency == "" {
+		input.Currency = displayCurrency(c)
+	}
+
+	options, err := h.services.Shop.QuoteShipping(c.Request.Context(), input)
+	if err != nil {
+		writeCheckoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"methods": options})
+}
+
+// writeCheckoutError responds with the status matching an error from pricing a cart
+func writeCheckoutError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidShipping),
+		errors.Is(err, service.ErrShippingMethodRequired),
+		errors.Is(err, service.ErrShippingMethodUnavailable),
+		errors.Is(err, service.ErrUnsupportedCurrency),
+		errors.Is(err, service.ErrGiftCardQuantity):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		writePromotionError(c, err)
+	}
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+    "errors"
+    "log"
     "net/http"
     "github.com/adrianmcmains/blog-ecommerce/internal/models"
     "github.com/adrianmcmains/blog-ecommerce/internal/service"
@@ -26,6 +28,10 @@ func (h *ShopHandler) CreateProduct(c *gin.Context) {
     }
 
     product, err := h.services.Shop.CreateProduct(c.Request.Context(), input)
+    if errors.Is(err, service.ErrInvalidVariant) || errors.Is(err, service.ErrInvalidBundle) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -42,7 +48,13 @@ func (h *ShopHandler) GetProduct(c *gin.Context) {
         return
     }
 
-    c.JSON(http.StatusOK, product)
+    products := []models.Product{*product}
+    if err := h.services.Shop.LocalizeProducts(c.Request.Context(), products, displayCurrency(c)); err != nil {
+        writeCurrencyError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, products[0])
 }
 
 func (h *ShopHandler) ListProducts(c *gin.Context) {
@@ -52,6 +64,7 @@ func (h *ShopHandler) ListProducts(c *gin.Context) {
         Category: c.Query("category"),
         MinPrice: c.Query("minPrice"),
         MaxPrice: c.Query("maxPrice"),
+        Options:  c.QueryArray("option"),
     }
 
     products, total, err := h.services.Shop.ListProducts(c.Request.Context(), filter)
@@ -60,6 +73,11 @@ func (h *ShopHandler) ListProducts(c *gin.Context) {
         return
     }
 
+    if err := h.services.Shop.LocalizeProducts(c.Request.Context(), products, displayCurrency(c)); err != nil {
+        writeCurrencyError(c, err)
+        return
+    }
+
     c.JSON(http.StatusOK, gin.H{
         "products": products,
         "total":    total,
@@ -77,6 +95,10 @@ func (h *ShopHandler) UpdateProduct(c *gin.Context) {
     }
 
     product, err := h.services.Shop.UpdateProduct(c.Request.Context(), id, input)
+    if errors.Is(err, service.ErrInvalidBundle) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -109,6 +131,15 @@ func (h *ShopHandler) AddToCart(c *gin.Context) {
     input.UserID = userID.(string)
 
     cartItem, err := h.services.Shop.AddToCart(c.Request.Context(), input)
+    if errors.Is(err, service.ErrInsufficientStock) {
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+        return
+    }
+    if errors.Is(err, service.ErrVariantRequired) || errors.Is(err, service.ErrInvalidVariant) ||
+        errors.Is(err, service.ErrGiftCardQuantity) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -125,6 +156,11 @@ func (h *ShopHandler) GetCart(c *gin.Context) {
         return
     }
 
+    if err := h.services.Shop.LocalizeCart(c.Request.Context(), cart, displayCurrency(c)); err != nil {
+        writeCurrencyError(c, err)
+        return
+    }
+
     c.JSON(http.StatusOK, cart)
 }
 
@@ -141,6 +177,10 @@ func (h *ShopHandler) UpdateCartItem(c *gin.Context) {
     }
 
     cartItem, err := h.services.Shop.UpdateCartItem(c.Request.Context(), id, input.Quantity)
+    if errors.Is(err, service.ErrGiftCardQuantity) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -171,13 +211,22 @@ func (h *ShopHandler) CreateOrder(c *gin.Context) {
 
     userID, _ := c.Get("userID")
     input.UserID = userID.(string)
+    if input.Currency == "" {
+        input.Currency = displayCurrency(c)
+    }
 
     order, err := h.services.Shop.CreateOrder(c.Request.Context(), input)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        writeCreditError(c, err)
         return
     }
 
+    // Credit the order to any posts the buyer clicked through from; this must not fail the order
+    visitorID, _ := c.Cookie(visitorCookie)
+    if err := h.services.Attribution.AttributeOrder(c.Request.Context(), order, visitorID); err != nil {
+        log.Printf("Failed to attribute order %s to posts: %v", order.ID, err)
+    }
+
     c.JSON(http.StatusCreated, order)
 }
 
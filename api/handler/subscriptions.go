@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSubscription subscribes the current user to a product and places the first order
+func (h *ShopHandler) CreateSubscription(c *gin.Context) {
+	var input models.SubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	input.UserID = userID.(string)
+
+	subscription, err := h.services.Shop.CreateSubscription(c.Request.Context(), input)
+	if err != nil {
+		writeSubscribeAndSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// ListSubscriptions lists the current user's subscriptions
+func (h *ShopHandler) ListSubscriptions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	subscriptions, err := h.services.Shop.ListSubscriptions(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
+
+// GetSubscription returns one of the current user's subscriptions
+func (h *ShopHandler) GetSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.services.Shop.GetSubscription)
+}
+
+// SkipSubscription skips the next renewal of one of the current user's subscriptions
+func (h *ShopHandler) SkipSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.services.Shop.SkipSubscription)
+}
+
+// PauseSubscription pauses one of the current user's subscriptions
+func (h *ShopHandler) PauseSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.services.Shop.PauseSubscription)
+}
+
+// ResumeSubscription resumes one of the current user's paused subscriptions
+func (h *ShopHandler) ResumeSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.services.Shop.ResumeSubscription)
+}
+
+// CancelSubscription cancels one of the current user's subscriptions
+func (h *ShopHandler) CancelSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.services.Shop.CancelSubscription)
+}
+
+// changeSubscription runs a service call on the subscription in the URL for the
+// current user and responds with the result
+func (h *ShopHandler) changeSubscription(c *gin.Context, change func(ctx context.Context, id, userID string) (*models.Subscription, error)) {
+	userID, _ := c.Get("userID")
+
+	subscription, err := change(c.Request.Context(), c.Param("id"), userID.(string))
+	if err != nil {
+		writeSubscribeAndSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// writeSubscribeAndSaveError responds with the status matching an error from a product subscription
+func writeSubscribeAndSaveError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSubscriptionState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidSubscription),
+		errors.Is(err, service.ErrVariantRequired),
+		errors.Is(err, service.ErrInvalidVariant):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		writeCheckoutError(c, err)
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTaxZone creates a tax zone with its rates
+func (h *ShopHandler) CreateTaxZone(c *gin.Context) {
+	var input models.TaxZoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := h.services.Shop.CreateTaxZone(c.Request.Context(), input)
+	if err != nil {
+		writeTaxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+// ListTaxZones lists every tax zone with its rates
+func (h *ShopHandler) ListTaxZones(c *gin.Context) {
+	zones, err := h.services.Shop.ListTaxZones(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"zones": zones})
+}
+
+// GetTaxZone returns a single tax zone
+func (h *ShopHandler) GetTaxZone(c *gin.Context) {
+	zone, err := h.services.Shop.GetTaxZone(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tax zone not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// UpdateTaxZone replaces a tax zone's settings and rates
+func (h *ShopHandler) UpdateTaxZone(c *gin.Context) {
+	var input models.TaxZoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := h.services.Shop.UpdateTaxZone(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		writeTaxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// DeleteTaxZone deletes a tax zone
+func (h *ShopHandler) DeleteTaxZone(c *gin.Context) {
+	if err := h.services.Shop.DeleteTaxZone(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tax zone deleted successfully"})
+}
+
+// GetTaxReport totals the tax charged by zone and period. The dates are inclusive and
+// default to the start of the year through today; ?period defaults to month.
+func (h *ShopHandler) GetTaxReport(c *gin.Context) {
+	now := time.Now()
+	from := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if value := c.Query("from"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date like 2006-01-02"})
+			return
+		}
+		from = date
+	}
+	if value := c.Query("to"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date like 2006-01-02"})
+			return
+		}
+		to = date
+	}
+
+	filter := models.TaxReportFilter{
+		From:   from,
+		To:     to.AddDate(0, 0, 1),
+		Period: c.DefaultQuery("period", "month"),
+	}
+	rows, err := h.services.Shop.TaxReport(c.Request.Context(), filter)
+	if err != nil {
+		writeTaxError(c, err)
+		return
+	}
+
+	var taxable, tax float64
+	for _, row := range rows {
+		taxable += row.TaxableAmount
+		tax += row.TaxAmount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rows":           rows,
+		"from":           from.Format("2006-01-02"),
+		"to":             to.Format("2006-01-02"),
+		"period":         filter.Period,
+		"currency":       util.BaseCurrency(),
+		"taxable_amount": util.RoundCurrency(taxable, util.BaseCurrency()),
+		"tax_amount":     util.RoundCurrency(tax, util.BaseCurrency()),
+	})
+}
+
+// writeTaxError responds with the status matching a tax error
+func writeTaxError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidTaxZone) || errors.Is(err, service.ErrInvalidTaxPeriod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
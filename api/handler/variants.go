@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SetProductOptions replaces a product's option axes and regenerates its variants
+func (h *ShopHandler) SetProductOptions(c *gin.Context) {
+	var input models.ProductOptionsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.services.Shop.SetProductOptions(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		writeVariantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// UpdateVariant changes a variant's SKU, price, weight or image
+func (h *ShopHandler) UpdateVariant(c *gin.Context) {
+	var input models.VariantUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant, err := h.services.Shop.UpdateVariant(c.Request.Context(), c.Param("id"), c.Param("variantId"), input)
+	if err != nil {
+		writeVariantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// writeVariantError responds with the status matching an error from changing variants
+func writeVariantError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidVariant):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrVariantOrdered):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product or variant not found"})
+	}
+}
@@ -17,6 +17,7 @@ const (
 	RoleAdmin       = "admin"
 	RoleCustomer    = "customer"
 	RoleContributor = "contributor"
+	RoleEditor      = "editor"
 )
 
 // JWTClaims defines the claims in JWT token
@@ -55,6 +56,22 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware sets the user information like AuthMiddleware when a valid
+// token is sent, but lets anonymous requests through
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, err := extractToken(c.Request); err == nil {
+			if claims, err := validateToken(token); err == nil {
+				c.Set("userId", claims.UserID)
+				c.Set("userEmail", claims.Email)
+				c.Set("userRole", claims.Role)
+			}
+		}
+
+		c.Next()
+	}
+}
+
 // RoleMiddleware returns middleware for checking user roles
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -25,4 +25,7 @@ const (
 	
 	// RoleContributor represents a blog contributor
 	RoleContributor UserRole = "contributor"
+	
+	// RoleEditor represents an editor who reviews and publishes contributor posts
+	RoleEditor UserRole = "editor"
 )
\ No newline at end of file
@@ -3,6 +3,7 @@ package routes
 import (
 	"database/sql"
 	"log"
+	"time"
 
 	"github.com/adrianmcmains/blog-ecommerce/api/controllers"
 	"github.com/adrianmcmains/blog-ecommerce/api/handler"
@@ -27,6 +28,9 @@ func SetupRouter(r *gin.Engine, handler *handler.Handler, db *sql.DB) {
     blogController := controllers.NewBlogController(gormDB) // Use gormDB here
     shopController := controllers.NewShopController(db)
     adminController := controllers.NewAdminController(db)
+    searchController := controllers.NewSearchController(db)
+    feedController := controllers.NewFeedController(gormDB, shopController)
+    sitemapController := controllers.NewSitemapController(db)
     
     // Initialize payment controller
     paymentController, err := controllers.NewPaymentController(db)
@@ -40,9 +44,26 @@ func SetupRouter(r *gin.Engine, handler *handler.Handler, db *sql.DB) {
     r.Use(middleware.CORSMiddleware())
     r.Use(middleware.SecurityHeaders())
 
+    // Sitemap and robots.txt
+    r.GET("/sitemap.xml", sitemapController.GetSitemap)
+    r.GET("/sitemaps/:file", sitemapController.GetSitemapPage)
+    r.GET("/robots.txt", sitemapController.GetRobots)
+
     // API routes
     api := r.Group("/api")
     {
+        // Site-wide search across posts and products
+        api.GET("/search", searchController.Search)
+
+        // Syndication feeds (format is rss, atom or json)
+        feeds := api.Group("/feeds")
+        {
+            feeds.GET("/posts/:format", feedController.GetPostsFeed)
+            feeds.GET("/categories/:slug/:format", feedController.GetCategoryFeed)
+            feeds.GET("/tags/:slug/:format", feedController.GetTagFeed)
+            feeds.GET("/products/:format", feedController.GetProductsFeed)
+        }
+
         // Auth routes
         auth := api.Group("/auth")
         {
@@ -65,30 +86,50 @@ func SetupRouter(r *gin.Engine, handler *handler.Handler, db *sql.DB) {
         blog := api.Group("/blog")
         {
             // Public routes
-            blog.GET("/posts", handler.Blog.ListPosts)
-            blog.GET("/posts/:id", handler.Blog.GetPost)
+            blog.GET("/posts", middleware.OptionalAuthMiddleware(), handler.Blog.ListPosts)
+            blog.GET("/posts/:id", middleware.OptionalAuthMiddleware(), handler.Blog.GetPost)
+            blog.GET("/posts/slug/:slug", blogController.GetPost)
+            blog.GET("/posts/:id/related", blogController.GetRelatedPosts) // :id is the post slug
+            blog.GET("/posts/:id/translations", handler.Blog.GetTranslations)
+            blog.POST("/posts/:id/clicks", handler.Blog.TrackProductClick)
             blog.GET("/categories", blogController.GetCategories)
+            blog.GET("/categories/:slug", blogController.GetCategory)
             blog.GET("/tags", blogController.GetTags)
             
-            // Comment this out until implemented
-            // blog.GET("/authors", blogController.GetAuthors)
+            blog.GET("/authors", blogController.GetAuthors)
+            blog.GET("/authors/:slug", blogController.GetAuthor)
+            blog.GET("/series", blogController.GetSeriesList)
+            blog.GET("/series/:slug", blogController.GetSeries)
             
             // Protected routes - require authentication and proper role
             blogAdmin := blog.Group("/")
             blogAdmin.Use(middleware.AuthMiddleware())
             {
-                // Admin/contributor routes
+                // Admin/editor/contributor routes
                 adminContributor := blogAdmin.Group("/")
-                adminContributor.Use(middleware.RequireRole("admin", "contributor"))
+                adminContributor.Use(middleware.RequireRole("admin", "editor", "contributor"))
                 {
                     adminContributor.POST("/posts", handler.Blog.CreatePost)
                     adminContributor.PUT("/posts/:id", handler.Blog.UpdatePost)
+                    adminContributor.POST("/posts/:id/status", handler.Blog.TransitionPost)
+                    adminContributor.GET("/posts/:id/reviews", handler.Blog.ListReviewComments)
+                    adminContributor.POST("/posts/:id/reviews", handler.Blog.AddReviewComment)
+                    adminContributor.PUT("/authors/me", blogController.UpdateMyAuthorProfile)
                     
                     // Comment these out until implemented
                     // adminContributor.POST("/draft", blogController.SaveDraft)
                     // adminContributor.PUT("/draft/:id", blogController.UpdateDraft)
                 }
                 
+                // Admin/editor routes
+                adminEditor := blogAdmin.Group("/")
+                adminEditor.Use(middleware.RequireRole("admin", "editor"))
+                {
+                    adminEditor.GET("/submissions", handler.Blog.ListSubmissions)
+                    adminEditor.GET("/attribution", handler.Blog.GetAttribution)
+                    adminEditor.GET("/posts/:id/attribution", handler.Blog.GetPostAttribution)
+                }
+                
                 // Admin-only routes
                 adminOnly := blogAdmin.Group("/")
                 adminOnly.Use(middleware.RequireRole("admin"))
@@ -97,9 +138,16 @@ func SetupRouter(r *gin.Engine, handler *handler.Handler, db *sql.DB) {
                     adminOnly.POST("/categories", blogController.CreateCategory)
                     adminOnly.PUT("/categories/:id", blogController.UpdateCategory)
                     adminOnly.DELETE("/categories/:id", blogController.DeleteCategory)
+                    adminOnly.POST("/categories/:id/merge", blogController.MergeCategory)
                     adminOnly.POST("/tags", blogController.CreateTag)
                     adminOnly.PUT("/tags/:id", blogController.UpdateTag)
                     adminOnly.DELETE("/tags/:id", blogController.DeleteTag)
+                    adminOnly.POST("/tags/:id/merge", blogController.MergeTag)
+                    adminOnly.POST("/series", blogController.CreateSeries)
+                    adminOnly.PUT("/series/:slug", blogController.UpdateSeries)
+                    adminOnly.DELETE("/series/:slug", blogController.DeleteSeries)
+                    adminOnly.PUT("/series/:slug/posts", blogController.SetSeriesPosts)
+                    adminOnly.POST("/import/wordpress", blogController.ImportWordPress)
                     
                     // Comment this out until implemented
                     // adminOnly.POST("/sync", blogController.SyncContent)
@@ -117,19 +165,46 @@ func SetupRouter(r *gin.Engine, handler *handler.Handler, db *sql.DB) {
             }
         }
 
+        // Newsletter routes
+        newsletter := api.Group("/newsletter")
+        {
+            // Each sign-up sends an email, so limit how often one IP can ask for them
+            newsletter.POST("/subscribe", middleware.RateLimiter(5, time.Minute), middleware.OptionalAuthMiddleware(), handler.Newsletter.Subscribe)
+            newsletter.GET("/confirm", handler.Newsletter.Confirm)
+            newsletter.GET("/unsubscribe", handler.Newsletter.Unsubscribe)
+            newsletter.POST("/unsubscribe", handler.Newsletter.Unsubscribe)
+
+            newsletterAdmin := newsletter.Group("/admin")
+            newsletterAdmin.Use(middleware.AuthMiddleware())
+            newsletterAdmin.Use(middleware.RequireRole("admin"))
+            {
+                newsletterAdmin.GET("/stats", handler.Newsletter.GetStats)
+                newsletterAdmin.GET("/subscribers.csv", handler.Newsletter.ExportSubscribers)
+                newsletterAdmin.POST("/digest", handler.Newsletter.SendDigests)
+            }
+        }
+
         // Shop routes
         shop := api.Group("/shop")
         {
             // Public product routes
             shop.GET("/products", handler.Shop.ListProducts)
             shop.GET("/products/:id", handler.Shop.GetProduct)
+            shop.GET("/products/:id/related", shopController.GetRelatedProducts)
             shop.GET("/products/slug/:slug", shopController.GetProductBySlug)
             shop.GET("/categories", shopController.GetCategories)
+            shop.GET("/categories/:slug", shopController.GetCategory)
             shop.GET("/featured", shopController.GetFeaturedProducts)
             shop.GET("/new-arrivals", shopController.GetNewArrivals)
             shop.GET("/best-sellers", shopController.GetBestSellers)
             shop.GET("/search", shopController.SearchProducts)
+            shop.GET("/currencies", handler.Shop.ListCurrencies)
+            shop.POST("/currency", handler.Shop.SelectCurrency)
             shop.GET("/debug-products", shopController.DebugProducts) // Debug endpoint
+            
+            // Gift card codes can be tried here and at checkout, so each of those routes is
+            // rate limited per IP to keep codes from being guessed
+            shop.GET("/gift-cards/:code/balance", middleware.RateLimiter(10, time.Minute), handler.Shop.GetGiftCardBalance)
 
             // Admin product routes
             productAdmin := shop.Group("/admin")
@@ -139,12 +214,46 @@ func SetupRouter(r *gin.Engine, handler *handler.Handler, db *sql.DB) {
                 productAdmin.POST("/products", handler.Shop.CreateProduct)
                 productAdmin.PUT("/products/:id", handler.Shop.UpdateProduct)
                 productAdmin.DELETE("/products/:id", handler.Shop.DeleteProduct)
+                productAdmin.PUT("/products/:id/options", handler.Shop.SetProductOptions)
+                productAdmin.PUT("/products/:id/variants/:variantId", handler.Shop.UpdateVariant)
                 productAdmin.POST("/categories", shopController.CreateCategory)
                 productAdmin.PUT("/categories/:id", shopController.UpdateCategory)
                 productAdmin.DELETE("/categories/:id", shopController.DeleteCategory)
+                productAdmin.POST("/categories/:id/merge", shopController.MergeCategory)
                 productAdmin.POST("/sync-products", shopController.SyncProducts)
                 productAdmin.GET("/inventory", shopController.GetInventoryReport)
                 productAdmin.PUT("/inventory/update-stock", shopController.UpdateStockLevels)
+                productAdmin.POST("/inventory/adjustments", handler.Shop.AdjustStock)
+                productAdmin.GET("/inventory/:sku/ledger", handler.Shop.GetStockLedger)
+                productAdmin.GET("/promotions", handler.Shop.ListPromotions)
+                productAdmin.POST("/promotions", handler.Shop.CreatePromotion)
+                productAdmin.GET("/promotions/:id", handler.Shop.GetPromotion)
+                productAdmin.PUT("/promotions/:id", handler.Shop.UpdatePromotion)
+                productAdmin.DELETE("/promotions/:id", handler.Shop.DeletePromotion)
+                productAdmin.GET("/tax-zones", handler.Shop.ListTaxZones)
+                productAdmin.POST("/tax-zones", handler.Shop.CreateTaxZone)
+                productAdmin.GET("/tax-zones/:id", handler.Shop.GetTaxZone)
+                productAdmin.PUT("/tax-zones/:id", handler.Shop.UpdateTaxZone)
+                productAdmin.DELETE("/tax-zones/:id", handler.Shop.DeleteTaxZone)
+                productAdmin.GET("/tax/report", handler.Shop.GetTaxReport)
+                productAdmin.GET("/reports/product-revenue", handler.Shop.GetProductRevenue)
+                productAdmin.POST("/store-credit", handler.Shop.IssueStoreCredit)
+                productAdmin.POST("/gift-cards", handler.Shop.IssueGiftCard)
+                productAdmin.GET("/gift-cards/:code", handler.Shop.GetGiftCardLedger)
+                productAdmin.GET("/shipping-zones", handler.Shop.ListShippingZones)
+                productAdmin.POST("/shipping-zones", handler.Shop.CreateShippingZone)
+                productAdmin.GET("/shipping-zones/:id", handler.Shop.GetShippingZone)
+                productAdmin.PUT("/shipping-zones/:id", handler.Shop.UpdateShippingZone)
+                productAdmin.DELETE("/shipping-zones/:id", handler.Shop.DeleteShippingZone)
+                productAdmin.POST("/shipping-zones/:id/methods", handler.Shop.CreateShippingMethod)
+                productAdmin.PUT("/shipping-methods/:id", handler.Shop.UpdateShippingMethod)
+                productAdmin.DELETE("/shipping-methods/:id", handler.Shop.DeleteShippingMethod)
+                productAdmin.GET("/currencies", handler.Shop.ListAllCurrencies)
+                productAdmin.POST("/currencies", handler.Shop.CreateCurrency)
+                productAdmin.PUT("/currencies/:code", handler.Shop.UpdateCurrency)
+                productAdmin.DELETE("/currencies/:code", handler.Shop.DeleteCurrency)
+                productAdmin.PUT("/products/:id/prices", handler.Shop.SetProductPrice)
+                productAdmin.DELETE("/products/:id/prices/:currency", handler.Shop.DeleteProductPrice)
                 
                 // Order management
                 productAdmin.GET("/orders", adminController.GetAllOrders)
@@ -166,17 +275,42 @@ func SetupRouter(r *gin.Engine, handler *handler.Handler, db *sql.DB) {
                 cart.DELETE("/items/:id", handler.Shop.RemoveFromCart)
                 cart.DELETE("", shopController.ClearCart)
                 cart.GET("/count", shopController.GetCartItemCount)
+                cart.POST("/quote", handler.Shop.QuoteCart)
+            }
+
+            // Shipping quotes (require authentication, as they price the user's cart)
+            shipping := shop.Group("/shipping")
+            shipping.Use(middleware.AuthMiddleware())
+            {
+                shipping.POST("/quote", handler.Shop.QuoteShipping)
             }
 
             // Order routes (require authentication)
             orders := shop.Group("/orders")
             orders.Use(middleware.AuthMiddleware())
             {
-                orders.POST("", handler.Shop.CreateOrder)
+                orders.POST("", middleware.RateLimiter(20, time.Minute), handler.Shop.CreateOrder)
                 orders.GET("", handler.Shop.ListOrders)
                 orders.GET("/:id", handler.Shop.GetOrder)
                 orders.GET("/:id/tracking", shopController.GetOrderTracking)
                 orders.POST("/:id/cancel", shopController.CancelOrder)
+                orders.POST("/:id/apply-credit", middleware.RateLimiter(10, time.Minute), handler.Shop.ApplyOrderCredit)
+            }
+            
+            // Store credit (requires authentication)
+            shop.GET("/store-credit", middleware.AuthMiddleware(), handler.Shop.GetStoreCredit)
+
+            // Subscribe-and-save routes (require authentication)
+            subscriptions := shop.Group("/subscriptions")
+            subscriptions.Use(middleware.AuthMiddleware())
+            {
+                subscriptions.POST("", handler.Shop.CreateSubscription)
+                subscriptions.GET("", handler.Shop.ListSubscriptions)
+                subscriptions.GET("/:id", handler.Shop.GetSubscription)
+                subscriptions.POST("/:id/skip", handler.Shop.SkipSubscription)
+                subscriptions.POST("/:id/pause", handler.Shop.PauseSubscription)
+                subscriptions.POST("/:id/resume", handler.Shop.ResumeSubscription)
+                subscriptions.POST("/:id/cancel", handler.Shop.CancelSubscription)
             }
             
             // Wishlist routes (require authentication)
@@ -7,8 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 	"gopkg.in/gomail.v2"
 )
 
@@ -23,12 +25,9 @@ type EmailService struct {
 	Debug    bool
 }
 
-// EmailTemplate represents an email template
-type EmailTemplate struct {
-	Subject string
-	HTML    string
-	Text    string
-}
+// EmailTemplate represents an email template; the templates themselves live in pkg/util
+// so the services that send them don't depend on the api packages
+type EmailTemplate = util.EmailTemplate
 
 // NewEmailService creates a new email service with configuration from environment variables
 func NewEmailService() (*EmailService, error) {
@@ -438,4 +437,75 @@ Subject: ` + subject + `
 Message: ` + message
 	
 	return s.SendEmail(adminEmail, emailSubject, htmlBody, textBody)
-}
\ No newline at end of file
+}
+// postStatusLabels are the human-readable names of post workflow statuses
+var postStatusLabels = map[string]string{
+	"draft":             "Draft",
+	"in_review":         "In review",
+	"changes_requested": "Changes requested",
+	"approved":          "Approved",
+	"published":         "Published",
+}
+
+// SendPostStatusEmail notifies a user that a post has moved to a new workflow status
+func (s *EmailService) SendPostStatusEmail(to, name, postTitle, status, comment string) error {
+	label := postStatusLabels[status]
+	if label == "" {
+		label = status
+	}
+
+	subject := "\"" + postTitle + "\" is now " + strings.ToLower(label)
+
+	commentHTML := ""
+	commentText := ""
+	if comment != "" {
+		commentHTML = `<p><strong>Reviewer comment:</strong></p>
+				<blockquote>` + template.HTMLEscapeString(comment) + `</blockquote>`
+		commentText = "\nReviewer comment:\n" + comment + "\n"
+	}
+
+	htmlBody := `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="utf-8">
+		<title>Post Status Update</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.header { text-align: center; padding: 20px 0; }
+			.content { padding: 20px; background-color: #f9f9f9; border-radius: 5px; }
+			blockquote { margin: 10px 0; padding: 10px 15px; border-left: 3px solid #0066cc; background-color: #fff; }
+			.footer { text-align: center; margin-top: 20px; font-size: 12px; color: #999; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>Post Status Update</h1>
+			</div>
+			<div class="content">
+				<p>Hello ` + template.HTMLEscapeString(name) + `,</p>
+				<p>The post <strong>` + template.HTMLEscapeString(postTitle) + `</strong> has moved to <strong>` + label + `</strong>.</p>
+				` + commentHTML + `
+			</div>
+			<div class="footer">
+				<p>&copy; ` + strconv.Itoa(time.Now().Year()) + ` BlogCommerce. All rights reserved.</p>
+				<p>This email was sent to ` + to + `</p>
+			</div>
+		</div>
+	</body>
+	</html>
+	`
+
+	textBody := `Post Status Update
+
+Hello ` + name + `,
+
+The post "` + postTitle + `" has moved to ` + label + `.
+` + commentText + `
+© ` + strconv.Itoa(time.Now().Year()) + ` BlogCommerce. All rights reserved.
+This email was sent to ` + to
+
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
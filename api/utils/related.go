@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"container/list"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RelatedWeights controls how overlap and recency contribute to a related-content score
+type RelatedWeights struct {
+	Tag      float64       // Points per shared tag
+	Category float64       // Points per shared category
+	Recency  float64       // Maximum points for brand new content
+	HalfLife time.Duration // Age at which the recency bonus is halved
+}
+
+// DefaultRelatedWeights favours shared tags over shared categories, with a small recency bonus
+var DefaultRelatedWeights = RelatedWeights{
+	Tag:      3,
+	Category: 2,
+	Recency:  1,
+	HalfLife: 90 * 24 * time.Hour,
+}
+
+// RelatedCandidate is an item that shares tags or categories with the source item
+type RelatedCandidate struct {
+	ID               string    `gorm:"column:id"`
+	SharedTags       int       `gorm:"column:shared_tags"`
+	SharedCategories int       `gorm:"column:shared_categories"`
+	Date             time.Time `gorm:"column:date"`
+	Score            float64   `gorm:"-"`
+}
+
+// ScoreRelated scores candidates and returns the best limit of them, highest score first
+func ScoreRelated(candidates []RelatedCandidate, weights RelatedWeights, limit int) []RelatedCandidate {
+	now := time.Now()
+	for i := range candidates {
+		c := &candidates[i]
+		c.Score = float64(c.SharedTags)*weights.Tag + float64(c.SharedCategories)*weights.Category
+
+		if weights.HalfLife > 0 && !c.Date.IsZero() {
+			age := now.Sub(c.Date)
+			if age < 0 {
+				age = 0
+			}
+			c.Score += weights.Recency * math.Pow(0.5, float64(age)/float64(weights.HalfLife))
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Date.After(candidates[j].Date)
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// TTLCache is a small in-memory cache whose entries expire after a fixed duration.
+// It holds at most a fixed number of entries, dropping the least recently used first.
+type TTLCache struct {
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.Mutex
+	order      *list.List // Most recently used at the front
+	entries    map[string]*list.Element
+}
+
+type ttlEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// NewTTLCache creates a cache whose entries live for ttl, holding at most maxEntries
+func NewTTLCache(ttl time.Duration, maxEntries int) *TTLCache {
+	return &TTLCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached value for key if it has not expired
+func (c *TTLCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*ttlEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores value under key, evicting the least recently used entry when the cache is full
+func (c *TTLCache) Set(key string, value interface{}) {
+	expiresAt := time.Now().Add(c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*ttlEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&ttlEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*ttlEntry).key)
+	}
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SearchHeadlineOptions are the ts_headline options used for highlighted snippets
+const SearchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// SafeSnippet escapes a ts_headline snippet for use as HTML, keeping only the <mark>
+// tags that highlight the matches; the text comes straight from post and product
+// content, which may hold markup of its own
+func SafeSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, "&lt;mark&gt;", "<mark>")
+	return strings.ReplaceAll(escaped, "&lt;/mark&gt;", "</mark>")
+}
+
+// BuildSearchQuery converts free text into a prefix-matching tsquery string.
+// "red sho" becomes "red:* & sho:*". Returns an empty string if the input has
+// no searchable terms.
+func BuildSearchQuery(input string) string {
+	terms := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		parts = append(parts, term+":*")
+	}
+
+	return strings.Join(parts, " & ")
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// SitemapMaxURLs is the maximum number of URLs allowed in a single sitemap file
+const SitemapMaxURLs = 50000
+
+// SitemapURL is a single <url> entry in a sitemap
+type SitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+// SitemapEntry is a single <sitemap> entry in a sitemap index
+type SitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []SitemapEntry `xml:"sitemap"`
+}
+
+// SitemapDate formats t as a sitemap lastmod value
+func SitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// RenderSitemap renders a <urlset> document
+func RenderSitemap(urls []SitemapURL) ([]byte, error) {
+	return marshalXML(sitemapURLSet{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		URLs:  urls,
+	})
+}
+
+// RenderSitemapIndex renders a <sitemapindex> document
+func RenderSitemapIndex(entries []SitemapEntry) ([]byte, error) {
+	return marshalXML(sitemapIndex{
+		XMLNS:    "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Sitemaps: entries,
+	})
+}
@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"gopkg.in/yaml.v2"
+
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 )
 
 // ContentType represents the type of content being synced
@@ -24,6 +26,7 @@ const (
 	BlogPost        ContentType = "blog_post"
 	BlogCategory    ContentType = "blog_category"
 	BlogTag         ContentType = "blog_tag"
+	BlogAuthor      ContentType = "blog_author"
 	Product         ContentType = "product"
 	ProductCategory ContentType = "product_category"
 	Page            ContentType = "page"
@@ -51,6 +54,13 @@ type FrontMatter struct {
 	Categories  []string  `yaml:"categories" json:"categories"`
 	Tags        []string  `yaml:"tags" json:"tags"`
 	Author      string    `yaml:"author" json:"author"`
+	AuthorSlug  string    `yaml:"authorSlug,omitempty" json:"authorSlug,omitempty"`
+	Series      string    `yaml:"series,omitempty" json:"series,omitempty"`
+	SeriesPart  int       `yaml:"seriesPart,omitempty" json:"seriesPart,omitempty"`
+	// TranslationKey links the language versions of a post in Hugo
+	TranslationKey string `yaml:"translationKey,omitempty" json:"translationKey,omitempty"`
+	// Aliases are the URLs of the content's previous slugs, which Hugo redirects to it
+	Aliases     []string  `yaml:"aliases,omitempty" json:"aliases,omitempty"`
 	Image       string    `yaml:"image" json:"image"`
 	// Product specific fields
 	Price      float64 `yaml:"price,omitempty" json:"price,omitempty"`
@@ -59,12 +69,33 @@ type FrontMatter struct {
 	Stock      int     `yaml:"stock,omitempty" json:"stock,omitempty"`
 	Featured   bool    `yaml:"featured,omitempty" json:"featured,omitempty"`
 	Visible    bool    `yaml:"visible,omitempty" json:"visible,omitempty"`
+	// Options are the axes a product's variants vary along, like size or flavour
+	Options  []OptionFrontMatter  `yaml:"options,omitempty" json:"options,omitempty"`
+	Variants []VariantFrontMatter `yaml:"variants,omitempty" json:"variants,omitempty"`
 	// Additional meta
 	ID        int       `yaml:"id" json:"id"`
 	CreatedAt time.Time `yaml:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time `yaml:"updatedAt" json:"updatedAt"`
 }
 
+// OptionFrontMatter is a product option axis and its values in front matter
+type OptionFrontMatter struct {
+	Name   string   `yaml:"name" json:"name"`
+	Type   string   `yaml:"type" json:"type"`
+	Values []string `yaml:"values" json:"values"`
+}
+
+// VariantFrontMatter is a product variant in front matter
+type VariantFrontMatter struct {
+	Name    string            `yaml:"name" json:"name"`
+	SKU     string            `yaml:"sku" json:"sku"`
+	Options map[string]string `yaml:"options,omitempty" json:"options,omitempty"`
+	Price   float64           `yaml:"price" json:"price"`
+	Stock   int               `yaml:"stock" json:"stock"`
+	Weight  float64           `yaml:"weight,omitempty" json:"weight,omitempty"`
+	Image   string            `yaml:"image,omitempty" json:"image,omitempty"`
+}
+
 // ContentFile represents a content file with front matter and body
 type ContentFile struct {
 	FrontMatter FrontMatter `json:"frontMatter"`
@@ -81,6 +112,8 @@ func DBToHugo(config SyncConfig, contentType ContentType) error {
 		return syncBlogCategoriesFromDB(config)
 	case BlogTag:
 		return syncBlogTagsFromDB(config)
+	case BlogAuthor:
+		return syncBlogAuthorsFromDB(config)
 	case Product:
 		return syncProductsFromDB(config)
 	case ProductCategory:
@@ -232,7 +265,9 @@ func HugoToTina(config SyncConfig) error {
 			var tinaFileName string
 			switch contentDir.contentType {
 			case "blog_post":
-				tinaFileName = fmt.Sprintf("blog_post_%s.json", fm.Slug)
+				// Keep the language suffix so translations don't overwrite each other
+				name := strings.TrimSuffix(translatedFileName(fm.Slug, fileLocale(info.Name())), ".md")
+				tinaFileName = fmt.Sprintf("blog_post_%s.json", name)
 			case "product":
 				tinaFileName = fmt.Sprintf("product_%s.json", fm.Slug)
 			case "page":
@@ -314,9 +349,15 @@ func syncBlogPostsFromDB(config SyncConfig) error {
 		SELECT 
 			p.id, p.title, p.slug, p.content, p.excerpt, 
 			p.featured_image, p.published, p.published_at, 
-			p.created_at, p.updated_at, u.name as author
+			p.created_at, p.updated_at, u.name as author,
+			COALESCE(ap.slug, '') as author_slug,
+			COALESCE(s.title, '') as series, COALESCE(sp.position, 0) as series_part,
+			COALESCE(p.locale, '') as locale, COALESCE(p.translation_group_id, '') as translation_key
 		FROM blog_posts p
 		LEFT JOIN users u ON p.author_id = u.id
+		LEFT JOIN author_profiles ap ON ap.user_id = p.author_id
+		LEFT JOIN series_posts sp ON sp.post_id = p.id
+		LEFT JOIN series s ON s.id = sp.series_id
 		ORDER BY p.published_at DESC
 	`)
 	if err != nil {
@@ -328,6 +369,8 @@ func syncBlogPostsFromDB(config SyncConfig) error {
 	for rows.Next() {
 		var (
 			id, title, slug, content, excerpt, featuredImage, author string
+			authorSlug, series, locale, translationKey               string
+			seriesPart                                               int
 			published                                                bool
 			publishedAt, createdAt, updatedAt                        time.Time
 		)
@@ -335,7 +378,8 @@ func syncBlogPostsFromDB(config SyncConfig) error {
 		if err := rows.Scan(
 			&id, &title, &slug, &content, &excerpt,
 			&featuredImage, &published, &publishedAt,
-			&createdAt, &updatedAt, &author,
+			&createdAt, &updatedAt, &author, &authorSlug,
+			&series, &seriesPart, &locale, &translationKey,
 		); err != nil {
 			return err
 		}
@@ -351,6 +395,23 @@ func syncBlogPostsFromDB(config SyncConfig) error {
 			return err
 		}
 
+		// Old slugs become Hugo aliases so renamed posts keep their inbound links
+		aliasPrefix := "/blog/"
+		if locale != "" && locale != util.DefaultLanguage() {
+			aliasPrefix = "/" + locale + "/blog/"
+		}
+		aliases, err := getSlugAliases(config.DB, "post", id, aliasPrefix)
+		if err != nil {
+			return err
+		}
+		permalinks, err := getSlugAliases(config.DB, "post_permalink", id, "")
+		if err != nil {
+			return err
+		}
+		aliases = append(aliases, permalinks...)
+		// Posts also answer at their ID, which is how digest emails link to them
+		aliases = append(aliases, aliasPrefix+id+"/")
+
 		// Create front matter
 		frontMatter := FrontMatter{
 			Title:       title,
@@ -362,6 +423,11 @@ func syncBlogPostsFromDB(config SyncConfig) error {
 			Categories:  categories,
 			Tags:        tags,
 			Author:      author,
+			AuthorSlug:  authorSlug,
+			Series:      series,
+			SeriesPart:  seriesPart,
+			TranslationKey: translationKey,
+			Aliases:     aliases,
 			Image:       featuredImage,
 			//ID:          id,
 			CreatedAt:   createdAt,
@@ -383,8 +449,8 @@ func syncBlogPostsFromDB(config SyncConfig) error {
 			return err
 		}
 
-		// Write file
-		filePath := filepath.Join(postDir, slug+".md")
+		// Write file, using Hugo's <slug>.<lang>.md naming for translations
+		filePath := filepath.Join(postDir, translatedFileName(slug, locale))
 		if err := ioutil.WriteFile(filePath, []byte(contentFile), 0644); err != nil {
 			return err
 		}
@@ -393,6 +459,57 @@ func syncBlogPostsFromDB(config SyncConfig) error {
 	return nil
 }
 
+// translatedFileName returns the content file name for a post in locale; the default
+// language keeps the plain <slug>.md name
+func translatedFileName(slug, locale string) string {
+	if locale == "" || locale == util.DefaultLanguage() {
+		return slug + ".md"
+	}
+	return slug + "." + locale + ".md"
+}
+
+// fileLocale returns the language encoded in a <slug>.<lang>.md file name,
+// or the default language if there is none
+func fileLocale(name string) string {
+	base := strings.TrimSuffix(name, ".md")
+	if i := strings.LastIndex(base, "."); i >= 0 {
+		if lang := base[i+1:]; util.IsSupportedLanguage(lang) {
+			return lang
+		}
+	}
+	return util.DefaultLanguage()
+}
+
+// getSlugAliases returns the URLs of an entity's previous slugs under prefix, oldest first.
+// Without a prefix the recorded entries are whole paths, like imported permalinks, and
+// are returned as they are.
+func getSlugAliases(db *sql.DB, entityType, entityID, prefix string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT old_slug FROM slug_redirects
+		WHERE entity_type = $1 AND entity_id = $2
+		ORDER BY created_at
+	`, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []string
+	for rows.Next() {
+		var oldSlug string
+		if err := rows.Scan(&oldSlug); err != nil {
+			return nil, err
+		}
+		if prefix == "" {
+			aliases = append(aliases, oldSlug)
+			continue
+		}
+		aliases = append(aliases, prefix+oldSlug+"/")
+	}
+
+	return aliases, rows.Err()
+}
+
 // getTagsForPost fetches all tags for a blog post
 func getTagsForPost(db *sql.DB, postID string) ([]string, error) {
 	rows, err := db.Query(`
@@ -555,6 +672,95 @@ func syncBlogTagsFromDB(config SyncConfig) error {
 	return nil
 }
 
+func syncBlogAuthorsFromDB(config SyncConfig) error {
+	// Query author profiles for users who can write posts
+	rows, err := config.DB.Query(`
+		SELECT 
+			ap.id, ap.slug, COALESCE(ap.display_name, ''), u.first_name, u.last_name,
+			COALESCE(ap.bio, ''), COALESCE(ap.avatar, ''), COALESCE(ap.website, ''),
+			COALESCE(ap.twitter, ''), COALESCE(ap.facebook, ''), COALESCE(ap.instagram, ''),
+			COALESCE(ap.linkedin, ''), COALESCE(ap.github, ''),
+			ap.created_at, ap.updated_at
+		FROM author_profiles ap
+		JOIN users u ON ap.user_id = u.id
+		WHERE u.role IN ('admin', 'contributor')
+		ORDER BY ap.slug
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Ensure directory exists
+	authorDir := filepath.Join(config.HugoDataDir, "authors")
+	if err := os.MkdirAll(authorDir, 0755); err != nil {
+		return err
+	}
+
+	// Process each author
+	for rows.Next() {
+		var (
+			id, slug, displayName, firstName, lastName     string
+			bio, avatar, website                           string
+			twitter, facebook, instagram, linkedin, github string
+			createdAt, updatedAt                           time.Time
+		)
+
+		if err := rows.Scan(
+			&id, &slug, &displayName, &firstName, &lastName,
+			&bio, &avatar, &website,
+			&twitter, &facebook, &instagram, &linkedin, &github,
+			&createdAt, &updatedAt,
+		); err != nil {
+			return err
+		}
+
+		if displayName == "" {
+			displayName = strings.TrimSpace(firstName + " " + lastName)
+		}
+
+		// Create author data
+		data := map[string]interface{}{
+			"name":    displayName,
+			"slug":    slug,
+			"bio":     bio,
+			"avatar":  avatar,
+			"website": website,
+			"social": map[string]string{
+				"twitter":   twitter,
+				"facebook":  facebook,
+				"instagram": instagram,
+				"linkedin":  linkedin,
+				"github":    github,
+			},
+			"id":        id,
+			"createdAt": createdAt,
+			"updatedAt": updatedAt,
+		}
+
+		// Convert to YAML
+		out, err := yaml.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		// Slugs are checked when profiles are saved; skip any older one that isn't plain
+		// so it can't point the file outside the data dir
+		if slug == "" || util.GenerateSlug(slug) != slug {
+			fmt.Printf("Skipping author %s with invalid slug %q\n", id, slug)
+			continue
+		}
+
+		// Write file
+		filePath := filepath.Join(authorDir, slug+".yaml")
+		if err := ioutil.WriteFile(filePath, out, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func syncProductsFromDB(config SyncConfig) error {
 	// Query products from database
 	rows, err := config.DB.Query(`
@@ -598,6 +804,21 @@ func syncProductsFromDB(config SyncConfig) error {
 			return err
 		}
 
+		aliases, err := getSlugAliases(config.DB, "product", id, "/shop/")
+		if err != nil {
+			return err
+		}
+
+		options, err := getProductOptions(config.DB, id)
+		if err != nil {
+			return err
+		}
+
+		variants, err := getProductVariants(config.DB, id)
+		if err != nil {
+			return err
+		}
+
 		// Create front matter
 		frontMatter := FrontMatter{
 			Title:       name,
@@ -610,6 +831,9 @@ func syncProductsFromDB(config SyncConfig) error {
 			Featured:    featured,
 			Visible:     visible,
 			Categories:  categories,
+			Aliases:     aliases,
+			Options:     options,
+			Variants:    variants,
 			Image:       images[0], // Set first image as main image
 			//ID:          id,
 			CreatedAt:   createdAt,
@@ -671,6 +895,64 @@ func getProductImages(db *sql.DB, productID string) ([]string, error) {
 	return images, nil
 }
 
+// getProductOptions fetches a product's option axes in display order
+func getProductOptions(db *sql.DB, productID string) ([]OptionFrontMatter, error) {
+	rows, err := db.Query(`
+		SELECT name, type, values
+		FROM product_options
+		WHERE product_id = $1
+		ORDER BY position
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var options []OptionFrontMatter
+	for rows.Next() {
+		var option OptionFrontMatter
+		var values []byte
+		if err := rows.Scan(&option.Name, &option.Type, &values); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(values, &option.Values); err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+
+	return options, rows.Err()
+}
+
+// getProductVariants fetches a product's variants with their option values
+func getProductVariants(db *sql.DB, productID string) ([]VariantFrontMatter, error) {
+	rows, err := db.Query(`
+		SELECT name, COALESCE(sku, ''), COALESCE(options, 'null'), price, stock_quantity, weight, COALESCE(image, '')
+		FROM product_variants
+		WHERE product_id = $1
+		ORDER BY name
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []VariantFrontMatter
+	for rows.Next() {
+		var variant VariantFrontMatter
+		var options []byte
+		if err := rows.Scan(&variant.Name, &variant.SKU, &options, &variant.Price, &variant.Stock, &variant.Weight, &variant.Image); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(options, &variant.Options); err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	return variants, rows.Err()
+}
+
 // getProductCategories fetches all categories for a product
 func getProductCategories(db *sql.DB, productID string) ([]string, error) {
 	rows, err := db.Query(`
@@ -854,17 +1136,29 @@ func syncBlogPostsToDB(config SyncConfig) error {
 			return err
 		}
 
+		// Translations share a slug and are told apart by the language in the file name
+		locale := fileLocale(file.Name())
+		translationKey := sql.NullString{String: frontMatter.TranslationKey, Valid: frontMatter.TranslationKey != ""}
+
+		// Keep the workflow status in step with the draft flag, since listings go by status
+		status := "published"
+		if frontMatter.Draft {
+			status = "draft"
+		}
+
 		// Check if post already exists
 		var postID int
-		err = tx.QueryRow("SELECT id FROM blog_posts WHERE slug = $1", frontMatter.Slug).Scan(&postID)
+		err = tx.QueryRow("SELECT id FROM blog_posts WHERE slug = $1 AND locale = $2", frontMatter.Slug, locale).Scan(&postID)
 		if err == nil {
 			// Post exists, update it
 			_, err = tx.Exec(`
 				UPDATE blog_posts
 				SET title = $1, content = $2, excerpt = $3, featured_image = $4,
-					published = $5, updated_at = NOW()
-				WHERE id = $6
-			`, frontMatter.Title, body, frontMatter.Description, frontMatter.Image, !frontMatter.Draft, postID)
+					published = $5, status = $6, translation_group_id = COALESCE($7, translation_group_id),
+					updated_at = NOW()
+				WHERE id = $8
+			`, frontMatter.Title, body, frontMatter.Description, frontMatter.Image, !frontMatter.Draft,
+				status, translationKey, postID)
 			if err != nil {
 				return err
 			}
@@ -884,11 +1178,12 @@ func syncBlogPostsToDB(config SyncConfig) error {
 			// Insert post
 			err = tx.QueryRow(`
 				INSERT INTO blog_posts (title, slug, content, excerpt, featured_image,
-					published, author_id, published_at, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+					published, status, author_id, published_at, locale, translation_group_id, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
 				RETURNING id
 			`, frontMatter.Title, frontMatter.Slug, body, frontMatter.Description,
-				frontMatter.Image, !frontMatter.Draft, authorID, frontMatter.Date).Scan(&postID)
+				frontMatter.Image, !frontMatter.Draft, status, authorID, frontMatter.Date,
+				locale, translationKey).Scan(&postID)
 			if err != nil {
 				return err
 			}
@@ -969,12 +1264,55 @@ func syncBlogPostsToDB(config SyncConfig) error {
 				}
 			}
 		}
+
+		// Handle series membership
+		if err = syncPostSeriesToDB(tx, postID, frontMatter.Series, frontMatter.SeriesPart); err != nil {
+			return err
+		}
 	}
 
 	// Commit transaction
 	return tx.Commit()
 }
 
+// syncPostSeriesToDB places a post in the named series, creating the series if needed.
+// An empty series name removes the post from any series.
+func syncPostSeriesToDB(tx *sql.Tx, postID interface{}, seriesName string, part int) error {
+	if _, err := tx.Exec("DELETE FROM series_posts WHERE post_id = $1", postID); err != nil {
+		return err
+	}
+	if seriesName == "" {
+		return nil
+	}
+
+	// Find or create series
+	var seriesID string
+	slug := slugify(seriesName)
+	err := tx.QueryRow("SELECT id FROM series WHERE title = $1 OR slug = $2", seriesName, slug).Scan(&seriesID)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRow(`
+			INSERT INTO series (title, slug, created_at, updated_at)
+			VALUES ($1, $2, NOW(), NOW())
+			RETURNING id
+		`, seriesName, slug).Scan(&seriesID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Append to the end of the series when no part is given
+	if part < 1 {
+		err = tx.QueryRow("SELECT COALESCE(MAX(position), 0) + 1 FROM series_posts WHERE series_id = $1", seriesID).Scan(&part)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO series_posts (series_id, post_id, position) VALUES ($1, $2, $3)",
+		seriesID, postID, part)
+	return err
+}
+
 func syncBlogCategoriesToDB(config SyncConfig) error {
 	// Get all YAML files in the categories directory
 	catDir := filepath.Join(config.HugoDataDir, "categories")
@@ -0,0 +1,812 @@
+package wxr
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/blog"
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+)
+
+const (
+	// uploadsMarker is the part of a URL that identifies a WordPress upload
+	uploadsMarker = "/wp-content/uploads/"
+	// mediaSubdir is where imported uploads go within the media directory
+	mediaSubdir = "wordpress"
+	// slugEntityPost is the slug_redirects entity type of blog posts
+	slugEntityPost = "post"
+	// slugEntityPostPermalink is the slug_redirects entity type of old post permalink
+	// paths, which are whole paths rather than slugs
+	slugEntityPostPermalink = "post_permalink"
+	// commenterRole is the role of accounts created for commenters who aren't users yet
+	commenterRole = "customer"
+)
+
+// Outcomes of importing a post or media file
+const (
+	StatusCreated     = "created"
+	StatusWouldCreate = "would_create"
+	StatusExists      = "exists"
+	StatusDownloaded  = "downloaded"
+	StatusPlanned     = "planned"
+	StatusFailed      = "failed"
+)
+
+// errDryRun rolls back the import transaction after a dry run
+var errDryRun = errors.New("dry run")
+
+// Options controls an import
+type Options struct {
+	// DryRun reports what would be imported without changing anything
+	DryRun bool
+	// MediaDir is where uploads are downloaded to; leave empty to only rewrite their URLs
+	MediaDir string
+	// MediaURL is the public URL of MediaDir
+	MediaURL string
+	// Locale is the language of the imported posts
+	Locale string
+	// HTTPClient downloads the uploads
+	HTTPClient *http.Client
+}
+
+// Report describes what an import created, or would create in a dry run
+type Report struct {
+	DryRun     bool           `json:"dry_run"`
+	Site       string         `json:"site"`
+	Summary    ReportSummary  `json:"summary"`
+	Posts      []PostResult   `json:"posts"`
+	Authors    []AuthorResult `json:"authors"`
+	Categories []string       `json:"categories_created"`
+	Tags       []string       `json:"tags_created"`
+	Media      []MediaResult  `json:"media"`
+	Redirects  []string       `json:"redirects"`
+	Skipped    map[string]int `json:"skipped"`
+	Warnings   []string       `json:"warnings"`
+}
+
+// ReportSummary counts what an import created
+type ReportSummary struct {
+	Posts           int `json:"posts"`
+	ExistingPosts   int `json:"existing_posts"`
+	Authors         int `json:"authors"`
+	Categories      int `json:"categories"`
+	Tags            int `json:"tags"`
+	Comments        int `json:"comments"`
+	SkippedComments int `json:"skipped_comments"`
+	Media           int `json:"media"`
+	FailedMedia     int `json:"failed_media"`
+	Redirects       int `json:"redirects"`
+}
+
+// PostResult is the outcome of importing one post
+type PostResult struct {
+	WordPressID  int64  `json:"wordpress_id"`
+	Title        string `json:"title"`
+	Slug         string `json:"slug"`
+	Status       string `json:"status"`
+	Published    bool   `json:"published"`
+	Author       string `json:"author"`
+	Comments     int    `json:"comments"`
+	OldPermalink string `json:"old_permalink,omitempty"`
+}
+
+// AuthorResult is the user an exported author was matched to or created as
+type AuthorResult struct {
+	Login   string `json:"login"`
+	Email   string `json:"email"`
+	UserID  string `json:"user_id"`
+	Created bool   `json:"created"`
+}
+
+// MediaResult is the outcome of moving one upload into the media directory
+type MediaResult struct {
+	Source string `json:"source"`
+	URL    string `json:"url"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Importer imports WXR exports into the blog tables
+type Importer struct {
+	db   *gorm.DB
+	opts Options
+}
+
+// NewImporter creates a new importer
+func NewImporter(db *gorm.DB, opts Options) *Importer {
+	if opts.MediaURL == "" {
+		opts.MediaURL = "/images"
+	}
+	if opts.Locale == "" {
+		opts.Locale = util.DefaultLanguage()
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Importer{db: db, opts: opts}
+}
+
+// Import imports the posts, authors, categories, tags and approved comments of an
+// export. Posts whose slug is already taken are left alone, so importing the same
+// file again only adds what is missing. Uploads are downloaded once the import has
+// been committed; failed downloads are reported rather than undoing the import.
+func (im *Importer) Import(export *Export) (*Report, error) {
+	run := &importRun{
+		opts:        im.opts,
+		export:      export,
+		report:      &Report{DryRun: im.opts.DryRun, Site: export.Channel.BaseSiteURL, Skipped: map[string]int{}},
+		authors:     make(map[string]uuid.UUID),
+		users:       make(map[string]uuid.UUID),
+		categories:  make(map[string]blog.Category),
+		tags:        make(map[string]blog.Tag),
+		attachments: make(map[string]string),
+		media:       make(map[string]string),
+	}
+	run.siteURL, _ = url.Parse(export.Channel.BaseSiteURL)
+
+	err := im.db.Transaction(func(tx *gorm.DB) error {
+		run.tx = tx
+		if err := run.importAll(); err != nil {
+			return err
+		}
+		if im.opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	im.downloadMedia(run)
+	return run.report, nil
+}
+
+// downloadMedia copies the uploads referenced by the imported posts into the media
+// directory, skipping files that are already there
+func (im *Importer) downloadMedia(run *importRun) {
+	report := run.report
+	for _, localPath := range sortedKeys(run.media) {
+		source := run.media[localPath]
+		result := MediaResult{Source: source, URL: im.opts.MediaURL + "/" + localPath}
+
+		switch {
+		case im.opts.DryRun || im.opts.MediaDir == "":
+			result.Status = StatusPlanned
+		default:
+			dest := filepath.Join(im.opts.MediaDir, filepath.FromSlash(localPath))
+			if _, err := os.Stat(dest); err == nil {
+				result.Status = StatusExists
+			} else if err := im.download(source, dest); err != nil {
+				result.Status = StatusFailed
+				result.Error = err.Error()
+				report.Summary.FailedMedia++
+			} else {
+				result.Status = StatusDownloaded
+				report.Summary.Media++
+			}
+		}
+
+		report.Media = append(report.Media, result)
+	}
+}
+
+// download saves the file at source to dest
+func (im *Importer) download(source, dest string) error {
+	resp, err := im.opts.HTTPClient.Get(source)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file so an interrupted download doesn't look complete
+	tmp := dest + ".part"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// importRun holds the state of a single import
+type importRun struct {
+	tx      *gorm.DB
+	opts    Options
+	export  *Export
+	report  *Report
+	siteURL *url.URL
+
+	authors     map[string]uuid.UUID     // WordPress login -> user ID
+	users       map[string]uuid.UUID     // Email -> user ID
+	categories  map[string]blog.Category // WordPress slug -> category
+	tags        map[string]blog.Tag      // WordPress slug -> tag
+	attachments map[string]string        // Attachment post ID -> URL
+	media       map[string]string        // Path in the media directory -> source URL
+}
+
+// importAll imports everything in the export
+func (r *importRun) importAll() error {
+	for _, author := range r.export.Channel.Authors {
+		if _, err := r.author(author.Login); err != nil {
+			return err
+		}
+	}
+
+	if err := r.importCategories(); err != nil {
+		return err
+	}
+	for _, tag := range r.export.Channel.Tags {
+		if _, err := r.tag(tag.Slug, tag.Name); err != nil {
+			return err
+		}
+	}
+
+	// Featured images refer to attachments by ID, and may come after the post
+	for _, item := range r.export.Channel.Items {
+		if item.PostType == "attachment" && item.AttachmentURL != "" {
+			r.attachments[strconv.FormatInt(item.PostID, 10)] = item.AttachmentURL
+		}
+	}
+
+	for i := range r.export.Channel.Items {
+		item := &r.export.Channel.Items[i]
+		switch {
+		case item.PostType == "attachment":
+			continue
+		case item.PostType != "post":
+			r.report.Skipped[item.PostType]++
+			continue
+		case item.Status == "trash" || item.Status == "auto-draft" || item.Status == "inherit":
+			r.report.Skipped[item.Status]++
+			continue
+		}
+
+		if err := r.importPost(item); err != nil {
+			return fmt.Errorf("importing %q: %w", item.PlainTitle(), err)
+		}
+	}
+
+	return nil
+}
+
+// importCategories imports the exported categories, then nests the new ones under
+// their parents
+func (r *importRun) importCategories() error {
+	created := make(map[string]bool)
+	for _, category := range r.export.Channel.Categories {
+		before := r.report.Summary.Categories
+		if _, err := r.category(category.Slug, category.Name, category.Description); err != nil {
+			return err
+		}
+		created[category.Slug] = r.report.Summary.Categories > before
+	}
+
+	// Existing categories keep their place in the tree
+	for _, category := range r.export.Channel.Categories {
+		if category.Parent == "" || !created[category.Slug] {
+			continue
+		}
+		parent, ok := r.categories[category.Parent]
+		if !ok {
+			r.warn("category %q has unknown parent %q", category.Name, category.Parent)
+			continue
+		}
+		child := r.categories[category.Slug]
+		if err := r.tx.Model(&blog.Category{}).Where("id = ?", child.ID).
+			Update("parent_id", parent.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importPost imports a post with its terms, comments and old permalinks
+func (r *importRun) importPost(item *Item) error {
+	title := item.PlainTitle()
+	slug := termSlug(item.PostName, title)
+	if slug == "" {
+		slug = "post-" + strconv.FormatInt(item.PostID, 10)
+	}
+
+	result := PostResult{
+		WordPressID:  item.PostID,
+		Title:        title,
+		Slug:         slug,
+		Published:    item.Status == "publish",
+		Author:       item.Creator,
+		OldPermalink: item.Link,
+	}
+
+	var count int64
+	if err := r.tx.Model(&blog.Post{}).Where("slug = ? AND locale = ?", slug, r.opts.Locale).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		result.Status = StatusExists
+		r.report.Summary.ExistingPosts++
+		r.report.Posts = append(r.report.Posts, result)
+		return nil
+	}
+
+	authorID, err := r.author(item.Creator)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	date, ok := item.Date()
+	if !ok {
+		date = now
+	}
+
+	post := blog.Post{
+		ID:        uuid.New(),
+		Slug:      slug,
+		Title:     title,
+		Content:   ToMarkdown(item.Content(), r.rewriteMedia),
+		Excerpt:   ToMarkdown(item.Excerpt(), r.rewriteMedia),
+		AuthorID:  authorID,
+		Published: result.Published,
+		Status:    postStatus(item.Status),
+		Locale:    r.opts.Locale,
+		CreatedAt: date,
+		UpdatedAt: now,
+	}
+	if post.Title == "" {
+		post.Title = slug
+	}
+	if post.Published {
+		post.PublishedAt = &date
+	}
+	if thumbnail := r.attachments[item.MetaValue("_thumbnail_id")]; thumbnail != "" {
+		post.FeaturedImage = r.rewriteMedia(thumbnail)
+	}
+
+	if err := r.tx.Create(&post).Error; err != nil {
+		return err
+	}
+
+	var categories []blog.Category
+	var tags []blog.Tag
+	for _, term := range item.Terms {
+		switch term.Domain {
+		case "category":
+			category, err := r.category(term.Nicename, term.Name, "")
+			if err != nil {
+				return err
+			}
+			categories = append(categories, category)
+		case "post_tag":
+			tag, err := r.tag(term.Nicename, term.Name)
+			if err != nil {
+				return err
+			}
+			tags = append(tags, tag)
+		}
+	}
+	if len(categories) > 0 {
+		if err := r.tx.Model(&post).Association("Categories").Append(categories); err != nil {
+			return err
+		}
+	}
+	if len(tags) > 0 {
+		if err := r.tx.Model(&post).Association("Tags").Append(tags); err != nil {
+			return err
+		}
+	}
+
+	for i := range item.Comments {
+		imported, err := r.importComment(post.ID, &item.Comments[i])
+		if err != nil {
+			return err
+		}
+		if imported {
+			result.Comments++
+		}
+	}
+
+	if err := r.recordOldPermalinks(&post, item); err != nil {
+		return err
+	}
+
+	result.Status = StatusCreated
+	if r.opts.DryRun {
+		result.Status = StatusWouldCreate
+	}
+	r.report.Summary.Posts++
+	r.report.Posts = append(r.report.Posts, result)
+	return nil
+}
+
+// importComment imports an approved reader comment, attributing it to the user with
+// the commenter's email address
+func (r *importRun) importComment(postID uuid.UUID, comment *Comment) (bool, error) {
+	if !comment.IsApprovedComment() {
+		r.report.Summary.SkippedComments++
+		return false, nil
+	}
+
+	content := ToMarkdown(comment.Content, r.rewriteMedia)
+	if content == "" {
+		r.report.Summary.SkippedComments++
+		return false, nil
+	}
+
+	userID, ok, err := r.commenter(comment)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		r.report.Summary.SkippedComments++
+		r.warn("skipped comment %d by %q: no email address", comment.ID, comment.Author)
+		return false, nil
+	}
+
+	now := time.Now()
+	date, found := comment.Time()
+	if !found {
+		date = now
+	}
+
+	if err := r.tx.Create(&blog.Comment{
+		ID:        uuid.New(),
+		PostID:    postID,
+		UserID:    userID,
+		Content:   content,
+		Approved:  true,
+		CreatedAt: date,
+		UpdatedAt: now,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	r.report.Summary.Comments++
+	return true, nil
+}
+
+// recordOldPermalinks keeps the post's WordPress slug as a slug redirect and its
+// permalink path as a redirect of its own kind, so links to either keep working
+func (r *importRun) recordOldPermalinks(post *blog.Post, item *Item) error {
+	type redirect struct{ entityType, oldSlug string }
+	var redirects []redirect
+
+	if wpSlug := decodeSlug(item.PostName); wpSlug != "" && wpSlug != post.Slug {
+		redirects = append(redirects, redirect{slugEntityPost, wpSlug})
+	}
+
+	// Plain ?p=123 permalinks have no path to keep
+	if link, err := url.Parse(item.Link); err == nil && link.RawQuery == "" {
+		permalink := link.Path
+		if permalink != "" && permalink != "/" && strings.Trim(permalink, "/") != "blog/"+post.Slug {
+			redirects = append(redirects, redirect{slugEntityPostPermalink, permalink})
+		}
+	}
+
+	for _, old := range redirects {
+		now := time.Now()
+		result := r.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&blog.SlugRedirect{
+			EntityType: old.entityType,
+			EntityID:   post.ID.String(),
+			OldSlug:    old.oldSlug,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		r.report.Summary.Redirects++
+		r.report.Redirects = append(r.report.Redirects, old.oldSlug+" -> "+post.Slug)
+	}
+
+	return nil
+}
+
+// postStatus maps a WordPress post status to the editorial workflow status: published
+// posts stay published, ones pending review go to review and the rest are drafts
+func postStatus(wpStatus string) string {
+	switch wpStatus {
+	case "publish":
+		return "published"
+	case "pending":
+		return "in_review"
+	default:
+		return "draft"
+	}
+}
+
+// author returns the user for a WordPress login, matching exported authors to users by
+// email and creating a contributor account with an author profile for new ones
+func (r *importRun) author(login string) (uuid.UUID, error) {
+	if id, ok := r.authors[login]; ok {
+		return id, nil
+	}
+
+	author := Author{Login: login}
+	for _, exported := range r.export.Channel.Authors {
+		if exported.Login == login {
+			author = exported
+			break
+		}
+	}
+
+	email := strings.ToLower(strings.TrimSpace(author.Email))
+	if email == "" {
+		// Authors without an email still need a unique address to sign in with later
+		email = util.GenerateSlug(login) + "@wordpress.invalid"
+		r.warn("author %q has no email address; created as %s", login, email)
+	}
+
+	firstName, lastName := author.FirstName, author.LastName
+	if firstName == "" && lastName == "" {
+		firstName = author.DisplayName
+	}
+	if firstName == "" {
+		firstName = login
+	}
+
+	id, created, err := r.user(email, models.RoleContributor, firstName, lastName)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := r.ensureAuthorProfile(id, author); err != nil {
+		return uuid.Nil, err
+	}
+
+	r.authors[login] = id
+	r.report.Authors = append(r.report.Authors, AuthorResult{Login: login, Email: email, UserID: id.String(), Created: created})
+	if created {
+		r.report.Summary.Authors++
+	}
+	return id, nil
+}
+
+// ensureAuthorProfile gives an author a public profile if they don't have one yet
+func (r *importRun) ensureAuthorProfile(userID uuid.UUID, author Author) error {
+	var count int64
+	if err := r.tx.Model(&blog.AuthorProfile{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	base := termSlug(author.Login, author.DisplayName)
+	if base == "" {
+		base = "author"
+	}
+	slug := base
+	for n := 2; ; n++ {
+		if err := r.tx.Model(&blog.AuthorProfile{}).Where("slug = ?", slug).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			break
+		}
+		slug = base + "-" + strconv.Itoa(n)
+	}
+
+	now := time.Now()
+	return r.tx.Create(&blog.AuthorProfile{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Slug:        slug,
+		DisplayName: author.DisplayName,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}).Error
+}
+
+// commenter returns the user for a comment's email address, creating an account if
+// needed; comments without an email can't be attributed to anyone
+func (r *importRun) commenter(comment *Comment) (uuid.UUID, bool, error) {
+	email := strings.ToLower(strings.TrimSpace(comment.AuthorEmail))
+	if email == "" {
+		return uuid.Nil, false, nil
+	}
+
+	firstName, lastName := comment.Author, ""
+	if parts := strings.Fields(comment.Author); len(parts) > 1 {
+		firstName, lastName = strings.Join(parts[:len(parts)-1], " "), parts[len(parts)-1]
+	}
+
+	id, _, err := r.user(email, commenterRole, firstName, lastName)
+	return id, err == nil, err
+}
+
+// user returns the ID of the user with email, creating them with the given role if
+// they don't exist. New accounts get a random password and must reset it to sign in.
+func (r *importRun) user(email, role, firstName, lastName string) (uuid.UUID, bool, error) {
+	if id, ok := r.users[email]; ok {
+		return id, false, nil
+	}
+
+	var existing models.User
+	err := r.tx.Where("LOWER(email) = ?", email).First(&existing).Error
+	if err == nil {
+		r.users[email] = existing.ID
+		return existing.ID, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, false, err
+	}
+
+	password, err := util.GenerateRandomString(32)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	user := models.User{
+		Email:     email,
+		Role:      role,
+		FirstName: firstName,
+		LastName:  lastName,
+	}
+	if err := user.SetPassword(password); err != nil {
+		return uuid.Nil, false, err
+	}
+	if err := r.tx.Create(&user).Error; err != nil {
+		return uuid.Nil, false, err
+	}
+
+	r.users[email] = user.ID
+	return user.ID, true, nil
+}
+
+// category returns the category for a WordPress slug, matching existing categories by
+// slug or name and creating missing ones
+func (r *importRun) category(wpSlug, name, description string) (blog.Category, error) {
+	if category, ok := r.categories[wpSlug]; ok {
+		return category, nil
+	}
+
+	name = strings.TrimSpace(html.UnescapeString(name))
+	slug := termSlug(wpSlug, name)
+	if name == "" {
+		name = slug
+	}
+
+	var category blog.Category
+	err := r.tx.Where("slug = ? OR LOWER(name) = LOWER(?)", slug, name).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		now := time.Now()
+		category = blog.Category{
+			ID:          uuid.New(),
+			Name:        name,
+			Slug:        slug,
+			Description: strings.TrimSpace(html.UnescapeString(description)),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		err = r.tx.Create(&category).Error
+		if err == nil {
+			r.report.Summary.Categories++
+			r.report.Categories = append(r.report.Categories, name)
+		}
+	}
+	if err != nil {
+		return blog.Category{}, err
+	}
+
+	r.categories[wpSlug] = category
+	return category, nil
+}
+
+// tag returns the tag for a WordPress slug, matching existing tags by slug or name and
+// creating missing ones
+func (r *importRun) tag(wpSlug, name string) (blog.Tag, error) {
+	if tag, ok := r.tags[wpSlug]; ok {
+		return tag, nil
+	}
+
+	name = strings.TrimSpace(html.UnescapeString(name))
+	slug := termSlug(wpSlug, name)
+	if name == "" {
+		name = slug
+	}
+
+	var tag blog.Tag
+	err := r.tx.Where("slug = ? OR LOWER(name) = LOWER(?)", slug, name).First(&tag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		now := time.Now()
+		tag = blog.Tag{ID: uuid.New(), Name: name, Slug: slug, CreatedAt: now, UpdatedAt: now}
+		err = r.tx.Create(&tag).Error
+		if err == nil {
+			r.report.Summary.Tags++
+			r.report.Tags = append(r.report.Tags, name)
+		}
+	}
+	if err != nil {
+		return blog.Tag{}, err
+	}
+
+	r.tags[wpSlug] = tag
+	return tag, nil
+}
+
+// rewriteMedia points WordPress upload URLs at the media directory and remembers
+// them for downloading; other URLs are returned unchanged
+func (r *importRun) rewriteMedia(raw string) string {
+	index := strings.Index(raw, uploadsMarker)
+	if index < 0 {
+		return raw
+	}
+
+	source, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if !source.IsAbs() && r.siteURL != nil {
+		source = r.siteURL.ResolveReference(source)
+	}
+
+	uploadPath := strings.TrimPrefix(path.Clean("/"+raw[index+len(uploadsMarker):]), "/")
+	if i := strings.IndexAny(uploadPath, "?#"); i >= 0 {
+		uploadPath = uploadPath[:i]
+	}
+	if uploadPath == "" || uploadPath == "." {
+		return raw
+	}
+
+	localPath := mediaSubdir + "/" + uploadPath
+	r.media[localPath] = source.String()
+	return r.opts.MediaURL + "/" + localPath
+}
+
+// warn adds a warning to the report
+func (r *importRun) warn(format string, args ...interface{}) {
+	r.report.Warnings = append(r.report.Warnings, fmt.Sprintf(format, args...))
+}
+
+// termSlug returns a usable slug for a WordPress slug, falling back to one generated
+// from the name when the WordPress slug has characters our slugs don't allow
+func termSlug(wpSlug, name string) string {
+	if slug := util.GenerateSlug(strings.ToLower(decodeSlug(wpSlug))); slug != "" {
+		return slug
+	}
+	return util.GenerateSlug(strings.ToLower(name))
+}
+
+// sortedKeys returns the keys of m in order, so reports list media consistently
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,396 @@
+package wxr
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	captionPattern   = regexp.MustCompile(`(?s)\[caption[^\]]*\](.*?)\[/caption\]`)
+	paragraphPattern = regexp.MustCompile(`(?i)<p[\s>]`)
+	preBlockPattern  = regexp.MustCompile(`(?is)<pre[\s>].*?</pre>`)
+	blankLinePattern = regexp.MustCompile(`\n[ \t]*\n`)
+	blockTagPattern  = regexp.MustCompile(`(?i)^<(?:/?)(?:p|div|h[1-6]|ul|ol|li|blockquote|pre|table|figure|hr|iframe|section|dl|form|address)[\s/>]`)
+	extraNewlines    = regexp.MustCompile(`\n{3,}`)
+	spacePattern     = regexp.MustCompile(`\s+`)
+	backtickRun      = regexp.MustCompile("`+")
+	languageClass    = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([\w+#-]+)`)
+	markdownSpecial  = strings.NewReplacer(`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`)
+)
+
+// rawElements are kept as HTML in the Markdown, which the renderer passes through
+// its sanitizer
+var rawElements = map[atom.Atom]bool{
+	atom.Table: true, atom.Iframe: true, atom.Video: true, atom.Audio: true,
+	atom.Object: true, atom.Embed: true, atom.Dl: true, atom.Form: true,
+}
+
+// blockElements start a new Markdown block
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Figure: true, atom.Figcaption: true,
+	atom.Address: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Ul: true, atom.Ol: true, atom.Blockquote: true,
+	atom.Pre: true, atom.Hr: true,
+}
+
+// ToMarkdown converts WordPress post HTML to Markdown. Every image source and link
+// is passed through rewriteURL so uploads can be moved to the media directory.
+func ToMarkdown(content string, rewriteURL func(string) string) string {
+	content = captionPattern.ReplaceAllString(content, "$1")
+	if !paragraphPattern.MatchString(content) {
+		content = autoParagraph(content)
+	}
+
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return strings.TrimSpace(content)
+	}
+
+	c := &converter{rewriteURL: rewriteURL}
+	body := findBody(doc)
+	if body == nil {
+		return ""
+	}
+
+	markdown := c.blocks(body, "\n\n")
+	return strings.TrimSpace(extraNewlines.ReplaceAllString(markdown, "\n\n"))
+}
+
+// autoParagraph wraps the blank-line separated paragraphs of classic editor content in
+// <p> tags, as WordPress does when displaying a post, and turns single newlines into
+// line breaks
+func autoParagraph(content string) string {
+	// Keep preformatted blocks intact while splitting the rest into paragraphs
+	var pres []string
+	content = preBlockPattern.ReplaceAllStringFunc(content, func(pre string) string {
+		pres = append(pres, pre)
+		return "\n\n<!--wxr-pre-" + strconv.Itoa(len(pres)-1) + "-->\n\n"
+	})
+
+	var sb strings.Builder
+	for _, chunk := range blankLinePattern.Split(strings.ReplaceAll(content, "\r\n", "\n"), -1) {
+		chunk = strings.TrimSpace(chunk)
+		switch {
+		case chunk == "":
+		case strings.HasPrefix(chunk, "<!--wxr-pre-"):
+			index, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(chunk, "<!--wxr-pre-"), "-->"))
+			if index < len(pres) {
+				sb.WriteString(pres[index])
+			}
+		case blockTagPattern.MatchString(chunk):
+			sb.WriteString(chunk)
+		default:
+			sb.WriteString("<p>" + strings.ReplaceAll(chunk, "\n", "<br>\n") + "</p>")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// findBody returns the <body> element of a parsed document
+func findBody(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Body {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if body := findBody(child); body != nil {
+			return body
+		}
+	}
+	return nil
+}
+
+// converter renders an HTML tree as Markdown
+type converter struct {
+	rewriteURL func(string) string
+}
+
+// blocks renders the children of n as Markdown blocks joined by sep, gathering runs of
+// inline content into paragraphs
+func (c *converter) blocks(n *html.Node, sep string) string {
+	var out []string
+	var inline strings.Builder
+	flush := func() {
+		// Drop the space a newline after a <br> collapses to
+		text := strings.ReplaceAll(strings.TrimSpace(inline.String()), "  \n ", "  \n")
+		if text != "" {
+			out = append(out, text)
+		}
+		inline.Reset()
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (blockElements[child.DataAtom] || rawElements[child.DataAtom]) {
+			flush()
+			if block := c.block(child); block != "" {
+				out = append(out, block)
+			}
+			continue
+		}
+		inline.WriteString(c.inline(child))
+	}
+	flush()
+
+	return strings.Join(out, sep)
+}
+
+// block renders a block-level element
+func (c *converter) block(n *html.Node) string {
+	if rawElements[n.DataAtom] {
+		return c.raw(n)
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := collapseSpace(c.inlineChildren(n))
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", level) + " " + text
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Blockquote:
+		return prefixLines(c.blocks(n, "\n\n"), "> ", ">")
+	case atom.Pre:
+		return c.codeBlock(n)
+	case atom.Hr:
+		return "---"
+	default:
+		return c.blocks(n, "\n\n")
+	}
+}
+
+// list renders an ordered or unordered list, indenting nested content under each item
+func (c *converter) list(n *html.Node) string {
+	var items []string
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		number = start
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+
+		content := c.blocks(child, "\n")
+		lines := strings.Split(content, "\n")
+		indent := strings.Repeat(" ", len(marker))
+		for i := range lines {
+			if i == 0 {
+				lines[i] = marker + lines[i]
+			} else if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// codeBlock renders <pre> as a fenced code block, keeping the language of
+// syntax-highlighted blocks
+func (c *converter) codeBlock(n *html.Node) string {
+	code := strings.Trim(textContent(n), "\n")
+	if code == "" {
+		return ""
+	}
+
+	language := ""
+	for node := n; node != nil; node = node.FirstChild {
+		if match := languageClass.FindStringSubmatch(attr(node, "class")); match != nil {
+			language = match[1]
+			break
+		}
+	}
+
+	// Use a fence longer than any run of backticks inside the code
+	fence := "```"
+	for _, run := range backtickRun.FindAllString(code, -1) {
+		if len(run) >= len(fence) {
+			fence = strings.Repeat("`", len(run)+1)
+		}
+	}
+
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// raw renders an element as HTML, rewriting the URLs it loads
+func (c *converter) raw(n *html.Node) string {
+	var rewrite func(*html.Node)
+	rewrite = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			for i, a := range node.Attr {
+				if a.Key == "src" || a.Key == "href" || a.Key == "poster" {
+					node.Attr[i].Val = c.rewriteURL(a.Val)
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			rewrite(child)
+		}
+	}
+	rewrite(n)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// inlineChildren renders the children of n as inline Markdown
+func (c *converter) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.inline(child))
+	}
+	return sb.String()
+}
+
+// inline renders a node as inline Markdown
+func (c *converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return markdownSpecial.Replace(spacePattern.ReplaceAllString(n.Data, " "))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	if rawElements[n.DataAtom] {
+		return c.raw(n)
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript:
+		return ""
+	case atom.Br:
+		return "  \n"
+	case atom.Strong, atom.B:
+		return wrapInline(c.inlineChildren(n), "**")
+	case atom.Em, atom.I, atom.Cite:
+		return wrapInline(c.inlineChildren(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return wrapInline(c.inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Tt, atom.Samp:
+		code := textContent(n)
+		if strings.TrimSpace(code) == "" {
+			return code
+		}
+		if strings.Contains(code, "`") {
+			return "`` " + code + " ``"
+		}
+		return "`" + code + "`"
+	case atom.A:
+		text := c.inlineChildren(n)
+		href := strings.TrimSpace(attr(n, "href"))
+		if href == "" || strings.TrimSpace(text) == "" {
+			return text
+		}
+		return "[" + strings.TrimSpace(text) + "](" + linkDestination(c.rewriteURL(href)) + linkTitle(attr(n, "title")) + ")"
+	case atom.Img:
+		src := strings.TrimSpace(attr(n, "src"))
+		if src == "" {
+			return ""
+		}
+		alt := markdownSpecial.Replace(collapseSpace(attr(n, "alt")))
+		return "![" + alt + "](" + linkDestination(c.rewriteURL(src)) + linkTitle(attr(n, "title")) + ")"
+	default:
+		if blockElements[n.DataAtom] {
+			// Block content inside inline content, such as a paragraph inside a link
+			return " " + c.inlineChildren(n) + " "
+		}
+		return c.inlineChildren(n)
+	}
+}
+
+// wrapInline wraps text in emphasis markers, keeping surrounding spaces outside them
+// so the Markdown stays valid
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:len(text)-len(strings.TrimLeft(text, " \n"))]
+	trailing := text[len(strings.TrimRight(text, " \n")):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+// linkDestination quotes a URL that would otherwise end the link early
+func linkDestination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+// linkTitle renders the optional title of a link or image
+func linkTitle(title string) string {
+	title = collapseSpace(title)
+	if title == "" {
+		return ""
+	}
+	return ` "` + strings.ReplaceAll(title, `"`, `\"`) + `"`
+}
+
+// prefixLines prefixes every line of text, using emptyPrefix for blank lines
+func prefixLines(text, prefix, emptyPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// textContent returns the text of a node and its descendants exactly as written
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Br {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+// collapseSpace trims s and collapses runs of whitespace into single spaces
+func collapseSpace(s string) string {
+	return strings.TrimSpace(spacePattern.ReplaceAllString(s, " "))
+}
+
+// attr returns the value of an element's attribute, or "" if it isn't set
+func attr(n *html.Node, key string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,218 @@
+// Package wxr imports WordPress eXtended RSS (WXR) exports into the blog.
+package wxr
+
+import (
+	"encoding/xml"
+	"errors"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// wpDateLayout is the format of the post and comment dates in an export
+const wpDateLayout = "2006-01-02 15:04:05"
+
+// ErrNotWXR is returned when a file parses as XML but isn't a WordPress export
+var ErrNotWXR = errors.New("file is not a WordPress WXR export")
+
+// Export is a parsed WXR file
+type Export struct {
+	Channel Channel `xml:"channel"`
+}
+
+// Channel holds the site details and everything exported from it
+type Channel struct {
+	Title       string     `xml:"title"`
+	WXRVersion  string     `xml:"wxr_version"`
+	BaseSiteURL string     `xml:"base_site_url"`
+	BaseBlogURL string     `xml:"base_blog_url"`
+	Authors     []Author   `xml:"author"`
+	Categories  []Category `xml:"category"`
+	Tags        []Tag      `xml:"tag"`
+	Items       []Item     `xml:"item"`
+}
+
+// Author is a WordPress user who wrote exported posts
+type Author struct {
+	ID          int64  `xml:"author_id"`
+	Login       string `xml:"author_login"`
+	Email       string `xml:"author_email"`
+	DisplayName string `xml:"author_display_name"`
+	FirstName   string `xml:"author_first_name"`
+	LastName    string `xml:"author_last_name"`
+}
+
+// Category is a WordPress category; Parent is the parent's slug
+type Category struct {
+	TermID      int64  `xml:"term_id"`
+	Slug        string `xml:"category_nicename"`
+	Parent      string `xml:"category_parent"`
+	Name        string `xml:"cat_name"`
+	Description string `xml:"category_description"`
+}
+
+// Tag is a WordPress tag
+type Tag struct {
+	TermID      int64  `xml:"term_id"`
+	Slug        string `xml:"tag_slug"`
+	Name        string `xml:"tag_name"`
+	Description string `xml:"tag_description"`
+}
+
+// Item is an exported post, page, attachment or other WordPress post type
+type Item struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	PubDate       string    `xml:"pubDate"`
+	Creator       string    `xml:"creator"`
+	Encoded       []encoded `xml:"encoded"`
+	PostID        int64     `xml:"post_id"`
+	PostDate      string    `xml:"post_date"`
+	PostDateGMT   string    `xml:"post_date_gmt"`
+	PostName      string    `xml:"post_name"`
+	Status        string    `xml:"status"`
+	PostParent    int64     `xml:"post_parent"`
+	PostType      string    `xml:"post_type"`
+	AttachmentURL string    `xml:"attachment_url"`
+	Terms         []Term    `xml:"category"`
+	Meta          []Meta    `xml:"postmeta"`
+	Comments      []Comment `xml:"comment"`
+}
+
+// encoded is a content:encoded or excerpt:encoded element, told apart by namespace
+type encoded struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// Term is a category or tag assigned to an item
+type Term struct {
+	Domain   string `xml:"domain,attr"`
+	Nicename string `xml:"nicename,attr"`
+	Name     string `xml:",chardata"`
+}
+
+// Meta is a custom field on an item
+type Meta struct {
+	Key   string `xml:"meta_key"`
+	Value string `xml:"meta_value"`
+}
+
+// Comment is a comment, pingback or trackback on an item
+type Comment struct {
+	ID          int64  `xml:"comment_id"`
+	Author      string `xml:"comment_author"`
+	AuthorEmail string `xml:"comment_author_email"`
+	Date        string `xml:"comment_date"`
+	DateGMT     string `xml:"comment_date_gmt"`
+	Content     string `xml:"comment_content"`
+	Approved    string `xml:"comment_approved"`
+	Type        string `xml:"comment_type"`
+	Parent      int64  `xml:"comment_parent"`
+	UserID      int64  `xml:"comment_user_id"`
+}
+
+// Parse reads a WXR export
+func Parse(r io.Reader) (*Export, error) {
+	decoder := xml.NewDecoder(r)
+	// Titles and descriptions outside CDATA sections may use HTML entities
+	decoder.Entity = xml.HTMLEntity
+
+	var export Export
+	if err := decoder.Decode(&export); err != nil {
+		return nil, err
+	}
+	if export.Channel.WXRVersion == "" {
+		return nil, ErrNotWXR
+	}
+	return &export, nil
+}
+
+// Content returns the item's HTML body
+func (i *Item) Content() string {
+	return i.encodedValue("/content/")
+}
+
+// Excerpt returns the item's hand-written excerpt, if any
+func (i *Item) Excerpt() string {
+	return i.encodedValue("excerpt")
+}
+
+// encodedValue returns the encoded element whose namespace contains space
+func (i *Item) encodedValue(space string) string {
+	for _, e := range i.Encoded {
+		if strings.Contains(e.XMLName.Space, space) {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+// MetaValue returns the value of a custom field, or "" if the item doesn't have it
+func (i *Item) MetaValue(key string) string {
+	for _, meta := range i.Meta {
+		if meta.Key == key {
+			return meta.Value
+		}
+	}
+	return ""
+}
+
+// PlainTitle returns the title with any HTML entities decoded
+func (i *Item) PlainTitle() string {
+	return strings.TrimSpace(html.UnescapeString(i.Title))
+}
+
+// Date returns when the item was published, or written for drafts
+func (i *Item) Date() (time.Time, bool) {
+	if t, ok := parseDate(i.PostDateGMT, time.UTC); ok {
+		return t, true
+	}
+	if t, ok := parseDate(i.PostDate, time.Local); ok {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC1123Z, strings.TrimSpace(i.PubDate)); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// Time returns when the comment was written
+func (c *Comment) Time() (time.Time, bool) {
+	if t, ok := parseDate(c.DateGMT, time.UTC); ok {
+		return t, true
+	}
+	return parseDate(c.Date, time.Local)
+}
+
+// IsApprovedComment reports whether the comment is an approved reader comment rather
+// than a pingback, trackback or a comment waiting in moderation or marked as spam
+func (c *Comment) IsApprovedComment() bool {
+	if c.Approved != "1" {
+		return false
+	}
+	return c.Type == "" || c.Type == "comment"
+}
+
+// decodeSlug decodes the percent-encoding WordPress uses for non-ASCII slugs
+func decodeSlug(slug string) string {
+	if decoded, err := url.PathUnescape(slug); err == nil {
+		slug = decoded
+	}
+	return strings.TrimSpace(slug)
+}
+
+// parseDate parses a WordPress date; unset dates are exported as all zeros
+func parseDate(value string, loc *time.Location) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.HasPrefix(value, "0000") {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(wpDateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -20,16 +20,20 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/adrianmcmains/blog-ecommerce/api/controllers"
+	"github.com/adrianmcmains/blog-ecommerce/api/utils"
 	"github.com/adrianmcmains/blog-ecommerce/internal/handler"
 	"github.com/adrianmcmains/blog-ecommerce/internal/models"
 	"github.com/adrianmcmains/blog-ecommerce/internal/repository"
 	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/database"
 	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 
 	"github.com/gin-gonic/gin"
@@ -60,6 +64,7 @@ func main() {
     
     // Initialize repositories
     repos := repository.NewRepository(db)
+    initPostStore(repos)
     
     // Create token repository
     tokenRepo := repository.NewTokenRepository(db)
@@ -74,6 +79,33 @@ func main() {
     
     services = service.NewService(repos, tokenRepo, jwtSecret, jwtTTL, refreshTTL)
 
+    // Email editorial workflow changes and newsletters when SMTP is configured
+    if emailService, err := utils.NewEmailService(); err == nil {
+        services.Blog.SetNotifier(emailService)
+        services.Newsletter.SetMailer(emailService)
+        services.Shop.SetMailer(emailService)
+    } else {
+        logger.Printf("Post workflow, newsletter and subscription emails disabled: %v", err)
+    }
+
+    // Charge subscription renewals to saved payment methods when a provider is configured
+    if sqlDB, err := db.DB(); err != nil {
+        logger.Printf("Subscription renewal charges disabled: %v", err)
+    } else if payments, err := controllers.NewPaymentController(sqlDB); err != nil {
+        logger.Printf("Subscription renewal charges disabled: %v", err)
+    } else {
+        services.Shop.SetRenewalCharger(payments)
+    }
+
+    // Check hourly for subscribers due their weekly digest
+    go services.Newsletter.RunDigests(context.Background(), time.Hour)
+
+    // Every minute, release stock held for checkouts that weren't paid for in time
+    go services.Shop.RunReservationExpiry(context.Background(), time.Minute)
+
+    // Hourly, place subscription renewal orders that are due and send their reminders
+    go services.Shop.RunSubscriptions(context.Background(), time.Hour)
+
     // Set up router
     r := gin.Default()
 
@@ -110,14 +142,14 @@ func main() {
             shop.GET("/products", handlers.Shop.ListProducts)
             shop.GET("/products/:id", getProduct)
             shop.POST("/products", authMiddleware(), createProduct)
-            shop.PUT("/products/:id", authMiddleware(), updateProduct)
+            shop.PUT("/products/:id", authMiddleware(), handlers.Shop.UpdateProduct)
             shop.DELETE("/products/:id", authMiddleware(), deleteProduct)
 
             // Cart and Order routes
             shop.POST("/cart", authMiddleware(), addToCart)
             shop.GET("/cart", authMiddleware(), getCart)
             shop.DELETE("/cart/:id", authMiddleware(), removeFromCart)
-            shop.POST("/orders", authMiddleware(), createOrder)
+            shop.POST("/orders", authMiddleware(), handlers.Shop.CreateOrder)
             shop.GET("/orders", authMiddleware(), listOrders)
         }
     }
@@ -211,10 +243,15 @@ func initDB() {
         log.Fatal("Failed to connect to database:", err)
     }
 
+    if err := database.BackfillOrderCurrency(db); err != nil {
+        log.Fatal("Failed to backfill order currencies:", err)
+    }
+
     // Auto migrate schemas
     err = db.AutoMigrate(
         &models.User{},
         &models.Post{},
+        &models.ReviewComment{},
         &models.Category{},
         &models.Tag{},
         &models.Product{},
@@ -222,12 +259,53 @@ func initDB() {
         &models.CartItem{},
         &models.Order{},
         &models.OrderItem{},
+        &models.PostProductClick{},
+        &models.PostConversion{},
+        &models.SlugRedirect{},
+        &models.Subscriber{},
+        &models.Promotion{},
+        &models.PromotionRedemption{},
+        &models.OrderItemDiscount{},
+        &models.TaxZone{},
+        &models.TaxRate{},
+        &models.OrderTaxLine{},
+        &models.ShippingZone{},
+        &models.ShippingMethod{},
+        &models.Currency{},
+        &models.ProductPrice{},
+        &models.StockReservation{},
+        &models.StockMovement{},
+        &models.ProductOption{},
+        &models.BundleComponent{},
+        &models.OrderItemComponent{},
+        &models.Subscription{},
+        &models.GiftCard{},
+        &models.StoreCredit{},
+        &models.CreditTransaction{},
     )
     if err != nil {
         log.Fatal("Failed to migrate database:", err)
     }
 }
 
+// initPostStore switches posts to MongoDB when POSTS_BACKEND=mongo
+func initPostStore(repos *repository.Repository) {
+    backend, err := repository.PostsBackend()
+    if err != nil {
+        log.Fatal(err)
+    }
+    if backend != repository.PostsBackendMongo {
+        return
+    }
+
+    _, mongoDB, err := repository.ConnectMongo(context.Background())
+    if err != nil {
+        log.Fatal("Failed to connect to MongoDB:", err)
+    }
+    repos.PostStore = repository.NewMongoPostRepository(mongoDB)
+    logger.Printf("Storing posts in MongoDB")
+}
+
 // Auth Handlers
 func registerHandler(c *gin.Context) {
     var input models.RegisterInput
@@ -413,37 +491,6 @@ func createProduct(c *gin.Context) {
     c.JSON(http.StatusCreated, product)
 }
 
-func updateProduct(c *gin.Context) {
-    id := c.Param("id")
-    
-    var product models.Product
-    if err := db.First(&product, id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
-        return
-    }
-    
-    var input models.CreateProductInput
-    if err := c.ShouldBindJSON(&input); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-        return
-    }
-    
-    // Update fields
-    product.Name = input.Name
-    product.Slug = util.GenerateSlug(input.Name)
-    product.Description = input.Description
-    product.Price = input.Price
-    product.StockQuantity = input.StockQuantity
-    product.Image = input.Image
-    
-    if err := db.Save(&product).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
-        return
-    }
-    
-    c.JSON(http.StatusOK, product)
-}
-
 func deleteProduct(c *gin.Context) {
     id := c.Param("id")
     
@@ -550,115 +597,6 @@ func removeFromCart(c *gin.Context) {
     c.JSON(http.StatusOK, gin.H{"message": "Item removed from cart"})
 }
 
-func createOrder(c *gin.Context) {
-    var input models.CreateOrderInput
-    if err := c.ShouldBindJSON(&input); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-        return
-    }
-    
-    userID, _ := c.Get("userID")
-    userIDStr, ok := userID.(string)
-    if !ok {
-        userIDStr = userID.(uuid.UUID).String()
-    }
-    
-    userUUID, err := uuid.Parse(userIDStr)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-        return
-    }
-    
-    // Get cart items
-    var cartItems []models.CartItem
-    var totalAmount float64
-    
-    for _, cartID := range input.CartIDs {
-        var cartItem models.CartItem
-        if err := db.Preload("Product").Preload("Variant").First(&cartItem, cartID).Error; err != nil {
-            c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
-            return
-        }
-        
-        cartItems = append(cartItems, cartItem)
-        
-        // Calculate price
-        price := cartItem.Product.Price
-        if cartItem.Variant != nil {
-            price = cartItem.Variant.Price
-        }
-        
-        totalAmount += price * float64(cartItem.Quantity)
-    }
-    
-    // Create order
-    order := models.Order{
-        UserID:      userUUID,
-        Status:      "pending",
-        TotalAmount: totalAmount,
-        Address: models.Address{
-            Street:     input.Address.Street,
-            City:       input.Address.City,
-            State:      input.Address.State,
-            Country:    input.Address.Country,
-            PostalCode: input.Address.PostalCode,
-        },
-    }
-    
-    // Add order items
-    for _, cartItem := range cartItems {
-        price := cartItem.Product.Price
-        if cartItem.Variant != nil {
-            price = cartItem.Variant.Price
-        }
-        
-        orderItem := models.OrderItem{
-            ProductID:   cartItem.ProductID,
-            Quantity:    cartItem.Quantity,
-            PriceAtTime: price,
-        }
-        
-        if cartItem.VariantID != nil {
-            orderItem.VariantID = cartItem.VariantID
-        }
-        
-        order.Items = append(order.Items, orderItem)
-    }
-    
-    // Create transaction
-    tx := db.Begin()
-    
-    // Save order
-    if err := tx.Create(&order).Error; err != nil {
-        tx.Rollback()
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
-        return
-    }
-    
-    // Clear cart items
-    for _, cartItem := range cartItems {
-        if err := tx.Delete(&cartItem).Error; err != nil {
-            tx.Rollback()
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
-            return
-        }
-        
-        // Update product stock
-        if err := tx.Model(&models.Product{}).
-                  Where("id = ?", cartItem.ProductID).
-                  Update("stock_quantity", gorm.Expr("stock_quantity - ?", cartItem.Quantity)).
-                  Error; err != nil {
-            tx.Rollback()
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update stock"})
-            return
-        }
-    }
-    
-    tx.Commit()
-    
-    c.JSON(http.StatusCreated, order)
-}
-
 func listOrders(c *gin.Context) {
     userID, _ := c.Get("userID")
     
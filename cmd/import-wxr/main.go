@@ -0,0 +1,94 @@
+// Command import-wxr imports a WordPress WXR export into the blog.
+//
+//	go run ./cmd/import-wxr -dry-run export.xml
+//	go run ./cmd/import-wxr export.xml
+//
+// Posts, categories, tags, authors and approved comments are imported, post HTML is
+// converted to Markdown and uploads are downloaded into the media directory. The
+// report is printed as JSON. Posts whose slug is already taken are skipped, so an
+// export can be imported again after fixing problems the report points out.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/adrianmcmains/blog-ecommerce/api/wxr"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Printf("No .env file found")
+	}
+
+	defaultMediaDir := os.Getenv("MEDIA_DIR")
+	if defaultMediaDir == "" {
+		defaultMediaDir = "static/images"
+	}
+
+	dryRun := flag.Bool("dry-run", false, "report what would be imported without changing anything")
+	mediaDir := flag.String("media-dir", defaultMediaDir, "directory to download uploads into")
+	mediaURL := flag.String("media-url", "/images", "public URL of the media directory")
+	skipMedia := flag.Bool("skip-media", false, "rewrite upload URLs without downloading the files")
+	locale := flag.String("locale", "", "language of the imported posts (default DEFAULT_LANGUAGE)")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatalf("Usage: import-wxr [flags] <export.xml>")
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to open export: %v", err)
+	}
+	defer file.Close()
+
+	export, err := wxr.Parse(file)
+	if err != nil {
+		log.Fatalf("Failed to parse export: %v", err)
+	}
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		dsn = "host=localhost user=postgres password=postgres dbname=blog_ecommerce port=5432 sslmode=disable"
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	if *skipMedia {
+		*mediaDir = ""
+	}
+
+	report, err := wxr.NewImporter(db, wxr.Options{
+		DryRun:   *dryRun,
+		MediaDir: *mediaDir,
+		MediaURL: *mediaURL,
+		Locale:   *locale,
+	}).Import(export)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	summary := report.Summary
+	if *dryRun {
+		log.Printf("Dry run: would import %d posts, %d comments, %d authors, %d categories and %d tags (%d posts already present)",
+			summary.Posts, summary.Comments, summary.Authors, summary.Categories, summary.Tags, summary.ExistingPosts)
+		return
+	}
+	log.Printf("Imported %d posts, %d comments, %d authors, %d categories and %d tags (%d posts already present); downloaded %d files, %d failed",
+		summary.Posts, summary.Comments, summary.Authors, summary.Categories, summary.Tags, summary.ExistingPosts, summary.Media, summary.FailedMedia)
+}
@@ -0,0 +1,117 @@
+// Command migrate-posts copies blog posts between the Postgres and MongoDB post stores.
+//
+//	go run ./cmd/migrate-posts -from postgres -to mongo
+//
+// Posts keep their IDs. Posts that already exist in the destination are skipped, so
+// the command can be re-run safely after an interrupted copy.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/repository"
+	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	from := flag.String("from", repository.PostsBackendPostgres, "store to copy posts from (postgres or mongo)")
+	to := flag.String("to", repository.PostsBackendMongo, "store to copy posts to (postgres or mongo)")
+	batch := flag.Int("batch", 200, "number of posts to read at a time")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("No .env file found")
+	}
+
+	if *from == *to {
+		log.Fatalf("Source and destination are both %q", *from)
+	}
+	if *batch < 1 {
+		*batch = 200
+	}
+
+	ctx := context.Background()
+
+	source, err := openPostStore(ctx, *from)
+	if err != nil {
+		log.Fatalf("Failed to open %s post store: %v", *from, err)
+	}
+	dest, err := openPostStore(ctx, *to)
+	if err != nil {
+		log.Fatalf("Failed to open %s post store: %v", *to, err)
+	}
+
+	copied, skipped, err := copyPosts(ctx, source, dest, *batch)
+	if err != nil {
+		log.Fatalf("Migration stopped after copying %d posts: %v", copied, err)
+	}
+	log.Printf("Copied %d posts from %s to %s (%d already present)", copied, *from, *to, skipped)
+}
+
+// openPostStore connects to the named post store
+func openPostStore(ctx context.Context, backend string) (repository.PostRepository, error) {
+	switch backend {
+	case repository.PostsBackendPostgres:
+		dsn := os.Getenv("DATABASE_URL")
+		if dsn == "" {
+			dsn = "host=localhost user=postgres password=postgres dbname=blog_ecommerce port=5432 sslmode=disable"
+		}
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		if err := db.AutoMigrate(&models.Post{}); err != nil {
+			return nil, err
+		}
+		return &service.PostRepoAdapter{Posts: repository.NewPostsRepo(db)}, nil
+	case repository.PostsBackendMongo:
+		_, mongoDB, err := repository.ConnectMongo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return repository.NewMongoPostRepository(mongoDB), nil
+	default:
+		return nil, fmt.Errorf("unknown post store %q: use %s or %s", backend, repository.PostsBackendPostgres, repository.PostsBackendMongo)
+	}
+}
+
+// copyPosts copies every post from source to dest in batches, skipping posts dest
+// already has
+func copyPosts(ctx context.Context, source, dest repository.PostRepository, batch int) (copied, skipped int, err error) {
+	for page := 1; ; page++ {
+		posts, total, err := source.Find(ctx, models.PostQuery{Page: page, Limit: batch})
+		if err != nil {
+			return copied, skipped, err
+		}
+
+		for _, post := range posts {
+			_, err := dest.GetByID(ctx, post.ID.String())
+			if err == nil {
+				skipped++
+				continue
+			}
+			if !errors.Is(err, repository.ErrPostNotFound) && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return copied, skipped, err
+			}
+			if err := dest.Create(ctx, post); err != nil {
+				return copied, skipped, err
+			}
+			copied++
+		}
+
+		if len(posts) < batch || int64(page*batch) >= total {
+			return copied, skipped, nil
+		}
+		log.Printf("Processed %d of %d posts", page*batch, total)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/adrianmcmains/blog-ecommerce/internal/models"
 	"github.com/adrianmcmains/blog-ecommerce/internal/service"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -33,6 +34,7 @@ func (h *BlogHandler) GetAllPosts(c *gin.Context) {
 	// Get filter parameters
 	category := c.Query("category")
 	authorID := c.Query("author")
+	lang := util.ResolveLanguage(c.Query("lang"), c.GetHeader("Accept-Language"))
 	
 	// Validate pagination parameters
 	if page < 1 {
@@ -42,8 +44,20 @@ func (h *BlogHandler) GetAllPosts(c *gin.Context) {
 		limit = 10
 	}
 	
+	query := models.PostQuery{
+		Category: category,
+		Tag:      c.Query("tag"),
+		AuthorID: authorID,
+		Search:   c.Query("q"),
+		Page:     page,
+		Limit:    limit,
+	}
+	if published, err := strconv.ParseBool(c.Query("published")); err == nil {
+		query.Published = &published
+	}
+	
 	// Get posts from service
-	posts, total, err := h.services.Blog.ListPosts(c.Request.Context(), page, limit, category, authorID)
+	posts, total, err := h.services.Blog.ListPostsInLanguage(c.Request.Context(), query, lang)
 	if err != nil {
 		h.logger.Printf("Failed to get posts: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get posts"})
@@ -57,6 +71,7 @@ func (h *BlogHandler) GetAllPosts(c *gin.Context) {
 			"limit": limit,
 			"total": total,
 			"pages": (total + int64(limit) - 1) / int64(limit),
+			"lang":  lang,
 		},
 	})
 }
@@ -85,6 +100,8 @@ func toServicePostInput(input models.PostInput) service.PostInput {
 		Categories:    input.Categories,
 		Tags:          input.Tags,
 		Published:     input.Published,
+		Locale:        input.Locale,
+		TranslationOf: input.TranslationOf,
 	}
 }
 
@@ -110,6 +127,11 @@ func (h *BlogHandler) CreatePost(c *gin.Context) {
 	// Create post
 	post, err := h.services.Blog.CreatePost(c.Request.Context(), serviceInput, userID.(string))
 	if err != nil {
+		if err == service.ErrUnsupportedLanguage || err == service.ErrTranslationExists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		
 		h.logger.Printf("Failed to create post: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
@@ -150,6 +172,10 @@ func (h *BlogHandler) UpdatePost(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 			return
 		}
+		if err == service.ErrUnsupportedLanguage || err == service.ErrTranslationExists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		
 		h.logger.Printf("Failed to update post: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"github.com/adrianmcmains/blog-ecommerce/internal/models"
 	"github.com/adrianmcmains/blog-ecommerce/internal/service"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ShopHandler handles shop related requests
@@ -125,6 +127,10 @@ func (h *ShopHandler) UpdateProduct(c *gin.Context) {
     }
 
     product, err := h.services.Shop.UpdateProduct(c.Request.Context(), id, input)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
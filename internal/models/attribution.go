@@ -0,0 +1,56 @@
+package models
+
+// ProductCard is a product embedded in a blog post, with live price and stock
+type ProductCard struct {
+	ID            string  `json:"id"`
+	Slug          string  `json:"slug"`
+	Name          string  `json:"name"`
+	Price         float64 `json:"price"`
+	Image         string  `json:"image"`
+	StockQuantity int     `json:"stock_quantity"`
+	InStock       bool    `json:"in_stock"`
+}
+
+// PostProductClick records a reader clicking through from a post to an embedded product
+type PostProductClick struct {
+	Base
+	PostID    string `json:"post_id" gorm:"not null;index"`
+	ProductID string `json:"product_id" gorm:"not null;index"`
+	VisitorID string `json:"visitor_id" gorm:"index"`
+	UserID    string `json:"user_id" gorm:"index"`
+}
+
+// PostConversion attributes an ordered product to the post the buyer last clicked it from
+type PostConversion struct {
+	Base
+	PostID    string  `json:"post_id" gorm:"not null;index"`
+	ProductID string  `json:"product_id" gorm:"not null"`
+	OrderID   string  `json:"order_id" gorm:"not null;index"`
+	Quantity  int     `json:"quantity" gorm:"not null"`
+	Revenue   float64 `json:"revenue" gorm:"not null"`
+}
+
+// PostAttributionStats summarises the clicks and orders a post has driven
+type PostAttributionStats struct {
+	PostID  string  `json:"post_id"`
+	Title   string  `json:"title"`
+	Clicks  int64   `json:"clicks"`
+	Orders  int64   `json:"orders"`
+	Units   int64   `json:"units"`
+	Revenue float64 `json:"revenue"`
+}
+
+// ProductAttributionStats breaks a post's attribution down by product
+type ProductAttributionStats struct {
+	ProductID string  `json:"product_id"`
+	Name      string  `json:"name"`
+	Clicks    int64   `json:"clicks"`
+	Orders    int64   `json:"orders"`
+	Units     int64   `json:"units"`
+	Revenue   float64 `json:"revenue"`
+}
+
+// TrackClickInput is used for recording a click-through from a post to a product
+type TrackClickInput struct {
+	ProductID string `json:"product_id" binding:"required"`
+}
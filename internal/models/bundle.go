@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Product types
+const (
+	ProductSimple = "simple"
+	// ProductBundle is a kit of other products sold at its own price; it has no stock
+	// of its own, only its components'
+	ProductBundle = "bundle"
+)
+
+// BundleComponent is a product, or one of its variants, that goes into a bundle and
+// how many of it each bundle holds
+type BundleComponent struct {
+	Base
+	BundleID  uuid.UUID       `gorm:"type:uuid;index;not null" json:"bundle_id"`
+	ProductID uuid.UUID       `gorm:"type:uuid;index;not null" json:"product_id"`
+	Product   *Product        `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	VariantID *uuid.UUID      `gorm:"type:uuid" json:"variant_id,omitempty"`
+	Variant   *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+	Quantity  int             `gorm:"not null" json:"quantity"`
+}
+
+// OrderItemComponent is what a bundle order line breaks down into for fulfilment,
+// with the share of the line's revenue allocated to it
+type OrderItemComponent struct {
+	Base
+	OrderItemID uuid.UUID  `gorm:"type:uuid;index;not null" json:"order_item_id"`
+	ProductID   uuid.UUID  `gorm:"type:uuid;index;not null" json:"product_id"`
+	VariantID   *uuid.UUID `gorm:"type:uuid" json:"variant_id,omitempty"`
+	Name        string     `json:"name"`
+	SKU         string     `json:"sku"`
+	// Quantity is the total to pick for the line: the bundle quantity times the
+	// component's quantity in each bundle
+	Quantity int `gorm:"not null" json:"quantity"`
+	// Revenue is the component's share of the line's total after discounts, in the
+	// order's currency, in proportion to the component's own price
+	Revenue float64 `gorm:"not null;default:0" json:"revenue"`
+}
+
+// BundleComponentInput adds a product or variant to a bundle
+type BundleComponentInput struct {
+	ProductID string `json:"product_id" binding:"required"`
+	VariantID string `json:"variant_id"` // Required when the product has variants
+	Quantity  int    `json:"quantity" binding:"required,gte=1"`
+}
+
+// ProductRevenueFilter selects the orders a product revenue report covers
+type ProductRevenueFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+// ProductRevenueRow is what a product or variant sold, on its own and in bundles,
+// in the base currency
+type ProductRevenueRow struct {
+	ProductID string  `json:"product_id"`
+	VariantID *string `json:"variant_id,omitempty"`
+	Name      string  `json:"name"`
+	SKU       string  `json:"sku"`
+	Quantity  int64   `json:"quantity"`
+	Revenue   float64 `json:"revenue"`
+	// BundleRevenue is the part of Revenue allocated from bundles
+	BundleRevenue float64 `json:"bundle_revenue"`
+}
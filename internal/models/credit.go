@@ -0,0 +1,123 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProductGiftCard is a digital gift card; buying one issues a card with a code worth
+// the price paid, and it has no stock
+const ProductGiftCard = "gift_card"
+
+// Gift card statuses
+const (
+	// GiftCardPending cards were bought in an order that hasn't been paid for yet
+	GiftCardPending = "pending"
+	GiftCardActive  = "active"
+	// GiftCardVoid cards were canceled with their order and can't be used
+	GiftCardVoid = "void"
+)
+
+// Credit transaction types
+const (
+	CreditIssue      = "issue"
+	CreditRedemption = "redemption"
+	// CreditRefund gives back what an order took when it's canceled
+	CreditRefund = "refund"
+	CreditVoid   = "void"
+)
+
+// ErrCreditLedgerImmutable is returned when something tries to change or delete a
+// credit ledger entry
+var ErrCreditLedgerImmutable = errors.New("credit ledger entries can't be changed")
+
+// GiftCard is a code worth a balance in the base currency that pays for orders
+type GiftCard struct {
+	Base
+	Code         string  `gorm:"size:32;uniqueIndex;not null" json:"code"`
+	InitialValue float64 `gorm:"not null" json:"initial_value"`
+	Balance      float64 `gorm:"not null" json:"balance"`
+	Currency     string  `gorm:"size:3;not null" json:"currency"`
+	Status       string  `gorm:"size:20;not null;default:'active'" json:"status"`
+	// PurchaserID and OrderID are set on cards bought in the shop
+	PurchaserID *uuid.UUID `gorm:"type:uuid;index" json:"purchaser_id,omitempty"`
+	OrderID     *uuid.UUID `gorm:"type:uuid;index" json:"order_id,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// StoreCredit is a customer's balance of credit in the base currency
+type StoreCredit struct {
+	Base
+	UserID  uuid.UUID `gorm:"type:uuid;uniqueIndex;not null" json:"user_id"`
+	Balance float64   `gorm:"not null;default:0" json:"balance"`
+}
+
+// CreditTransaction is an immutable entry in the ledger of a gift card's or a
+// customer's store credit balance
+type CreditTransaction struct {
+	Base
+	// GiftCardID is set for gift card entries and UserID for store credit ones
+	GiftCardID *uuid.UUID `gorm:"type:uuid;index" json:"gift_card_id,omitempty"`
+	UserID     *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	Type       string     `gorm:"size:20;not null" json:"type"`
+	// Amount is the signed change to the balance, and Balance what it came to
+	Amount  float64    `gorm:"not null" json:"amount"`
+	Balance float64    `gorm:"not null" json:"balance"`
+	Reason  string     `json:"reason,omitempty"`
+	ActorID *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"`
+	OrderID *uuid.UUID `gorm:"type:uuid;index" json:"order_id,omitempty"`
+}
+
+// BeforeUpdate keeps ledger entries from being changed
+func (t *CreditTransaction) BeforeUpdate(tx *gorm.DB) error {
+	return ErrCreditLedgerImmutable
+}
+
+// BeforeDelete keeps ledger entries from being deleted
+func (t *CreditTransaction) BeforeDelete(tx *gorm.DB) error {
+	return ErrCreditLedgerImmutable
+}
+
+// GiftCardInput issues a gift card from the admin, e.g. as a goodwill gesture
+type GiftCardInput struct {
+	Amount    float64    `json:"amount" binding:"required,gt=0"`
+	Reason    string     `json:"reason" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	ActorID   string     `json:"-"`
+}
+
+// StoreCreditInput issues store credit to a customer, e.g. for a refund or goodwill
+type StoreCreditInput struct {
+	UserID  string  `json:"user_id" binding:"required"`
+	Amount  float64 `json:"amount" binding:"required,gt=0"`
+	Reason  string  `json:"reason" binding:"required"`
+	ActorID string  `json:"-"`
+}
+
+// ApplyCreditInput pays part or all of a pending order with gift cards and store credit
+type ApplyCreditInput struct {
+	OrderID        string   `json:"-"`
+	UserID         string   `json:"-"`
+	GiftCardCodes  []string `json:"gift_card_codes"`
+	UseStoreCredit bool     `json:"use_store_credit"`
+}
+
+// CreditLedgerFilter pages through a gift card's or a customer's credit ledger
+type CreditLedgerFilter struct {
+	GiftCardID *uuid.UUID
+	UserID     *uuid.UUID
+	Page       int
+	Limit      int
+}
+
+// GiftCardBalance is what a code balance lookup shows, without the full code
+type GiftCardBalance struct {
+	Code      string     `json:"code"`
+	Balance   float64    `json:"balance"`
+	Currency  string     `json:"currency"`
+	Status    string     `json:"status"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// Currency is a currency customers can shop in besides the base currency, and its
+// exchange rate
+type Currency struct {
+	Base
+	Code   string `gorm:"size:3;uniqueIndex;not null" json:"code"`
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+	// Rate is how much one unit of the base currency is worth in this currency
+	Rate   float64 `gorm:"not null" json:"rate"`
+	Active bool    `gorm:"not null" json:"active"`
+}
+
+// ProductPrice sets a product's or variant's price in a currency instead of converting
+// its base price at the exchange rate
+type ProductPrice struct {
+	Base
+	ProductID uuid.UUID  `gorm:"type:uuid;index;not null" json:"product_id"`
+	VariantID *uuid.UUID `gorm:"type:uuid;index" json:"variant_id,omitempty"`
+	Currency  string     `gorm:"size:3;not null" json:"currency"`
+	Price     float64    `gorm:"not null" json:"price"`
+}
+
+// CurrencyInput creates or updates a currency
+type CurrencyInput struct {
+	Code   string  `json:"code" binding:"required,len=3"`
+	Name   string  `json:"name"`
+	Symbol string  `json:"symbol"`
+	Rate   float64 `json:"rate" binding:"required,gt=0"`
+	Active *bool   `json:"active"` // Defaults to true
+}
+
+// ProductPriceInput sets a product's or variant's price in a currency
+type ProductPriceInput struct {
+	Currency  string  `json:"currency" binding:"required,len=3"`
+	VariantID string  `json:"variant_id"`
+	Price     float64 `json:"price" binding:"required,gt=0"`
+}
@@ -6,7 +6,7 @@ type RegisterInput struct {
     Password  string `json:"password" binding:"required,min=6"`
     FirstName string `json:"first_name" binding:"required"`
     LastName  string `json:"last_name" binding:"required"`
-    Role      string `json:"role" binding:"required,oneof=admin customer contributor"`
+    Role      string `json:"role" binding:"required,oneof=admin customer contributor editor"`
 }
 
 type LoginInput struct {
@@ -16,12 +16,14 @@ type LoginInput struct {
 
 // Blog inputs
 type CreatePostInput struct {
-    Title      string   `json:"title" binding:"required"`
-    Content    string   `json:"content" binding:"required"`
-    AuthorID   string   `json:"-"`
-    Image      string   `json:"image"`
-    Categories []string `json:"categories"`
-    Tags       []string `json:"tags"`
+    Title         string   `json:"title" binding:"required"`
+    Content       string   `json:"content" binding:"required"`
+    AuthorID      string   `json:"-"`
+    Image         string   `json:"image"`
+    Categories    []string `json:"categories"`
+    Tags          []string `json:"tags"`
+    Locale        string   `json:"locale"`         // Defaults to the site's default language
+    TranslationOf string   `json:"translation_of"` // ID of the post this one translates
 }
 
 type UpdatePostInput struct {
@@ -30,6 +32,7 @@ type UpdatePostInput struct {
     Image      *string   `json:"image"`
     Categories *[]string `json:"categories"`
     Tags       *[]string `json:"tags"`
+    Locale     *string   `json:"locale"`
 }
 
 type PostFilter struct {
@@ -46,16 +49,31 @@ type CreateProductInput struct {
     Description   string         `json:"description" binding:"required"`
     Price         float64        `json:"price" binding:"required,gt=0"`
     StockQuantity int           `json:"stock_quantity" binding:"required,gte=0"`
+    SKU           string         `json:"sku"`
+    Type          string         `json:"type" binding:"omitempty,oneof=simple bundle gift_card"` // Defaults to "simple"
+    // Components are what a bundle is made of; bundles have no stock or variants of their own
+    Components    []BundleComponentInput `json:"components" binding:"dive"`
     Image         string         `json:"image"`
     Categories    []string       `json:"categories"`
-    Variants      []VariantInput `json:"variants"`
+    // Options generate the product's variants as every combination of their values;
+    // Variants then set the SKU, price, stock, weight and image of particular combinations
+    Options       []ProductOptionInput `json:"options" binding:"dive"`
+    Variants      []VariantInput `json:"variants" binding:"dive"`
+    TaxClass      string         `json:"tax_class"` // Defaults to "standard"
+    Weight        float64        `json:"weight" binding:"gte=0"` // kg
+    Length        float64        `json:"length" binding:"gte=0"` // cm
+    Width         float64        `json:"width" binding:"gte=0"`
+    Height        float64        `json:"height" binding:"gte=0"`
 }
 
 type VariantInput struct {
-    Name          string  `json:"name" binding:"required"`
-    Price         float64 `json:"price" binding:"required,gt=0"`
-    StockQuantity int    `json:"stock_quantity" binding:"required,gte=0"`
-    SKU           string  `json:"sku" binding:"required"`
+    Name          string  `json:"name"` // Required unless the product has options
+    Options       VariantOptions `json:"options"`
+    Price         float64 `json:"price" binding:"gte=0"` // Defaults to the product's price
+    StockQuantity int    `json:"stock_quantity" binding:"gte=0"`
+    SKU           string  `json:"sku"` // Generated from the product's SKU or slug if empty
+    Weight        float64 `json:"weight" binding:"gte=0"`
+    Image         string  `json:"image"`
 }
 
 type ProductFilter struct {
@@ -65,11 +83,16 @@ type ProductFilter struct {
     MinPrice  string  `form:"min_price"`
     MaxPrice  string  `form:"max_price"`
     InStock   *bool   `form:"in_stock"`
+    // Options are "axis:value" pairs, e.g. option=Flavour:Chocolate; products match when
+    // one of their variants has all of them
+    Options   []string `form:"option"`
 }
 
 type AddToCartInput struct {
     ProductID  string `json:"product_id" binding:"required"`
     VariantID  string `json:"variant_id"`
+    // Options pick the variant by its option values when VariantID isn't given
+    Options    VariantOptions `json:"options"`
     Quantity   int    `json:"quantity" binding:"required,gt=0"`
     UserID     string `json:"-"`
 }
@@ -78,6 +101,26 @@ type CreateOrderInput struct {
     UserID   string        `json:"-"`
     Address  AddressInput  `json:"address" binding:"required"`
     CartIDs  []string     `json:"cart_ids" binding:"required"`
+    CouponCodes []string  `json:"coupon_codes"`
+    // ShippingMethodID is required when shipping methods are available for the address
+    ShippingMethodID string `json:"shipping_method_id"`
+    Currency    string    `json:"currency"` // Defaults to the base currency
+    // GiftCardCodes and UseStoreCredit pay for part or all of the order up front
+    GiftCardCodes  []string `json:"gift_card_codes"`
+    UseStoreCredit bool     `json:"use_store_credit"`
+}
+
+// QuoteInput prices cart items before checkout
+type QuoteInput struct {
+    UserID      string   `json:"-"`
+    CartIDs     []string `json:"cart_ids" binding:"required"`
+    CouponCodes []string `json:"coupon_codes"`
+    // Country and State are where the order would ship; tax is only worked out when
+    // a country is given
+    Country     string   `json:"country"`
+    State       string   `json:"state"`
+    ShippingMethodID string `json:"shipping_method_id"`
+    Currency    string   `json:"currency"`
 }
 
 type AddressInput struct {
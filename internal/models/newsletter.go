@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Newsletter subscriber statuses; subscribers stay pending until they confirm by email
+const (
+	SubscriberPending      = "pending"
+	SubscriberActive       = "active"
+	SubscriberUnsubscribed = "unsubscribed"
+)
+
+// Subscriber is someone signed up for the blog newsletter, with or without an account
+type Subscriber struct {
+	Base
+	Email  string `gorm:"uniqueIndex;not null" json:"email"`
+	UserID string `gorm:"index" json:"user_id,omitempty"`
+	// Categories limits the digest to posts in these categories; empty means every post
+	Categories       []string   `gorm:"serializer:json" json:"categories"`
+	Status           string     `gorm:"size:20;not null;default:'pending';index" json:"status"`
+	ConfirmToken     string     `gorm:"index" json:"-"`
+	UnsubscribeToken string     `gorm:"uniqueIndex;not null" json:"-"`
+	ConfirmedAt      *time.Time `json:"confirmed_at"`
+	UnsubscribedAt   *time.Time `json:"unsubscribed_at"`
+	LastDigestAt     *time.Time `json:"last_digest_at"`
+	// PendingCategories and PendingUserID hold a change to an active subscription
+	// until it is confirmed by email
+	PendingCategories []string `gorm:"serializer:json" json:"-"`
+	PendingUserID     string   `json:"-"`
+}
+
+// ChangePending reports whether an active subscriber has a change waiting to be confirmed
+func (s *Subscriber) ChangePending() bool {
+	return s.Status == SubscriberActive && s.ConfirmToken != ""
+}
+
+// SubscribeInput is a newsletter sign-up; logged-in users may leave out the email
+type SubscribeInput struct {
+	Email      string   `json:"email" binding:"omitempty,email"`
+	Categories []string `json:"categories"`
+}
+
+// SubscriberStats counts newsletter subscribers by status
+type SubscriberStats struct {
+	Total        int64 `json:"total"`
+	Pending      int64 `json:"pending"`
+	Active       int64 `json:"active"`
+	Unsubscribed int64 `json:"unsubscribed"`
+}
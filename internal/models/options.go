@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Product option types
+const (
+	OptionText   = "text"   // e.g. a flavour
+	OptionNumber = "number" // e.g. a capsule count; values must be numbers
+	OptionSize   = "size"   // e.g. S, M, L
+	OptionColor  = "color"
+)
+
+// ProductOption is an axis a product varies along, like its size or flavour, and the
+// values it comes in. The product's variants are the combinations of its options' values.
+type ProductOption struct {
+	Base
+	ProductID uuid.UUID `gorm:"type:uuid;index;not null" json:"product_id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Type      string    `gorm:"size:20;not null;default:'text'" json:"type"`
+	Values    []string  `gorm:"type:jsonb;serializer:json" json:"values"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+}
+
+// VariantOptions are a variant's values on its product's option axes, by axis name
+type VariantOptions map[string]string
+
+// Get returns the value for an axis, matching its name without regard to case
+func (o VariantOptions) Get(axis string) (string, bool) {
+	if value, ok := o[axis]; ok {
+		return value, true
+	}
+	for name, value := range o {
+		if strings.EqualFold(name, axis) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Matches reports whether the options have every value in want, ignoring case
+func (o VariantOptions) Matches(want VariantOptions) bool {
+	for axis, value := range want {
+		have, ok := o.Get(axis)
+		if !ok || !strings.EqualFold(strings.TrimSpace(have), strings.TrimSpace(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProductOptionInput is an option axis of a product and its values
+type ProductOptionInput struct {
+	Name   string   `json:"name" binding:"required"`
+	Type   string   `json:"type" binding:"omitempty,oneof=text number size color"` // Defaults to "text"
+	Values []string `json:"values" binding:"required,min=1"`
+}
+
+// ProductOptionsInput replaces a product's option axes and regenerates its variants
+type ProductOptionsInput struct {
+	Options  []ProductOptionInput `json:"options" binding:"dive"`
+	Variants []VariantInput       `json:"variants" binding:"dive"`
+}
+
+// VariantUpdateInput changes a variant's details; its stock changes through the stock ledger
+type VariantUpdateInput struct {
+	Name   string  `json:"name"`
+	SKU    string  `json:"sku" binding:"required"`
+	Price  float64 `json:"price" binding:"required,gt=0"`
+	Weight float64 `json:"weight" binding:"gte=0"`
+	Image  string  `json:"image"`
+}
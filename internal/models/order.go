@@ -1,6 +1,8 @@
 package models
 
 import (
+    "math"
+
     "github.com/google/uuid"
 )
 
@@ -10,9 +12,30 @@ type Order struct {
     User        User         `gorm:"foreignKey:UserID" json:"user"`
     Items       []OrderItem  `json:"items"`
     Status      string       `gorm:"not null;default:'pending'" json:"status"`
+    Subtotal    float64      `gorm:"not null;default:0" json:"subtotal"`
+    DiscountTotal float64    `gorm:"not null;default:0" json:"discount_total"`
+    FreeShipping bool        `gorm:"not null;default:false" json:"free_shipping"`
+    TaxTotal    float64      `gorm:"not null;default:0" json:"tax_total"`
+    PricesIncludeTax bool    `gorm:"not null;default:false" json:"prices_include_tax"`
+    ShippingMethodID *uuid.UUID `gorm:"type:uuid" json:"shipping_method_id,omitempty"`
+    ShippingMethod string    `json:"shipping_method"`
+    ShippingCost float64     `gorm:"not null;default:0" json:"shipping_cost"`
     TotalAmount float64      `gorm:"not null" json:"total_amount"`
+    // CreditApplied is the part of TotalAmount paid with gift cards and store credit;
+    // the rest is charged through a payment provider
+    CreditApplied float64    `gorm:"not null;default:0" json:"credit_applied"`
+    // Currency is what the order's amounts are in, and ExchangeRate the rate from the
+    // base currency it was placed at
+    Currency    string       `gorm:"size:3;not null" json:"currency"`
+    ExchangeRate float64     `gorm:"not null;default:1" json:"exchange_rate"`
     Address     Address      `gorm:"embedded" json:"address"`
     PaymentID   string       `json:"payment_id"`
+    // SubscriptionID is set on the orders a subscription placed
+    SubscriptionID *uuid.UUID `gorm:"type:uuid;index" json:"subscription_id,omitempty"`
+    // GiftCards are the gift cards bought in the order
+    GiftCards   []GiftCard   `gorm:"foreignKey:OrderID" json:"gift_cards,omitempty"`
+    Promotions  []PromotionRedemption `gorm:"foreignKey:OrderID" json:"promotions,omitempty"`
+    TaxLines    []OrderTaxLine `gorm:"foreignKey:OrderID" json:"tax_lines,omitempty"`
 }
 
 type OrderItem struct {
@@ -24,6 +47,33 @@ type OrderItem struct {
     Variant     *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
     Quantity    int          `gorm:"not null" json:"quantity"`
     PriceAtTime float64      `gorm:"not null" json:"price_at_time"`
+    DiscountAmount float64   `gorm:"not null;default:0" json:"discount_amount"`
+    Discounts   []OrderItemDiscount `gorm:"foreignKey:OrderItemID" json:"discounts,omitempty"`
+    TaxAmount   float64      `gorm:"not null;default:0" json:"tax_amount"`
+    TaxIncluded bool         `gorm:"not null;default:false" json:"tax_included"`
+    // Components break a bundle line down into the products to pick
+    Components  []OrderItemComponent `gorm:"foreignKey:OrderItemID" json:"components,omitempty"`
+}
+
+// LineTotal is the line's price before discounts
+func (i *OrderItem) LineTotal() float64 {
+    return i.PriceAtTime * float64(i.Quantity)
+}
+
+// RefundAmount is what refunding quantity units of the line gives back: their price
+// less their share of the line's discounts, plus their tax if it was added on top
+func (i *OrderItem) RefundAmount(quantity int) float64 {
+    if i.Quantity == 0 || quantity <= 0 {
+        return 0
+    }
+    if quantity > i.Quantity {
+        quantity = i.Quantity
+    }
+    net := i.LineTotal() - i.DiscountAmount
+    if !i.TaxIncluded {
+        net += i.TaxAmount
+    }
+    return math.Round(net*float64(quantity)/float64(i.Quantity)*100) / 100
 }
 
 type Address struct {
@@ -32,4 +82,26 @@ type Address struct {
     State      string `json:"state"`
     Country    string `json:"country"`
     PostalCode string `json:"postal_code"`
-}
\ No newline at end of file
+}
+
+// OrderQuote prices a cart as it would be ordered, without placing the order
+type OrderQuote struct {
+    Currency      string                `json:"currency"`
+    ExchangeRate  float64               `json:"exchange_rate"`
+    Items         []OrderItem           `json:"items"`
+    Subtotal      float64               `json:"subtotal"`
+    DiscountTotal float64               `json:"discount_total"`
+    // SubscriptionDiscount is the part of DiscountTotal a subscription takes off
+    SubscriptionDiscount float64        `json:"subscription_discount,omitempty"`
+    FreeShipping  bool                  `json:"free_shipping"`
+    TaxTotal      float64               `json:"tax_total"`
+    PricesIncludeTax bool               `json:"prices_include_tax"`
+    TaxLines      []OrderTaxLine        `json:"tax_lines"`
+    ShippingMethodID *uuid.UUID         `json:"shipping_method_id,omitempty"`
+    ShippingMethod string               `json:"shipping_method,omitempty"`
+    ShippingCost  float64               `json:"shipping_cost"`
+    // ShippingOptions are the methods that can ship to the quote's address
+    ShippingOptions []ShippingOption    `json:"shipping_options"`
+    Total         float64               `json:"total"`
+    Promotions    []PromotionRedemption `json:"promotions"`
+}
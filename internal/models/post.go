@@ -18,6 +18,11 @@ type Post struct {
 	Tags          []string   `json:"tags" gorm:"type:text[]"`
 	Published     bool       `json:"published" gorm:"not null;default:false"`
 	PublishedAt   *time.Time `json:"published_at"`
+	// Status is the post's place in the editorial workflow; Published mirrors status "published"
+	Status string `json:"status" gorm:"size:20;not null;default:'draft';index"`
+	// Locale is the post's language; translations of a post share a TranslationGroupID
+	Locale             string `json:"locale" gorm:"size:10;not null;default:'en';index"`
+	TranslationGroupID string `json:"translation_group_id" gorm:"index"`
 }
 
 // PostInput is used for creating and updating posts in the API layer
@@ -29,4 +34,48 @@ type PostInput struct {
 	Categories    []string `json:"categories"`
 	Tags          []string `json:"tags"`
 	Published     bool     `json:"published"`
+	Locale        string   `json:"locale"`
+	TranslationOf string   `json:"translation_of"`
+}
+
+// PostQuery filters and paginates posts the same way in every post store.
+// A zero Page or Limit returns all matching posts.
+type PostQuery struct {
+	Category  string
+	Tag       string
+	AuthorID  string
+	Search    string
+	Published *bool
+	// PublishedAfter keeps posts published at or after this time
+	PublishedAfter *time.Time
+	Status         string
+	// TranslationGroupID keeps the language versions of one post
+	TranslationGroupID string
+	// IDs restricts the query to these posts
+	IDs []string
+	// Locales restricts the query to posts written in these languages
+	Locales []string
+	Page    int
+	Limit   int
+}
+
+// PagePosts returns the page of posts selected by page and limit; a zero page or
+// limit returns them all
+func PagePosts(posts []Post, page, limit int) []*Post {
+	start, end := 0, len(posts)
+	if page > 0 && limit > 0 {
+		start = (page - 1) * limit
+		if start > len(posts) {
+			start = len(posts)
+		}
+		if start+limit < end {
+			end = start + limit
+		}
+	}
+
+	result := make([]*Post, 0, end-start)
+	for i := start; i < end; i++ {
+		result = append(result, &posts[i])
+	}
+	return result
 }
@@ -10,11 +10,29 @@ type Product struct {
     Slug          string         `gorm:"unique;not null" json:"slug"`
     Description   string         `gorm:"type:text" json:"description"`
     Price         float64        `gorm:"not null" json:"price"`
+    // SKU identifies a product without variants in the stock ledger; variants have their own
+    SKU           string         `gorm:"index" json:"sku,omitempty"`
     StockQuantity int           `gorm:"not null" json:"stock_quantity"`
+    // AvailableStock is the stock on hand less what checkouts in progress have reserved
+    AvailableStock int           `gorm:"-" json:"available_stock"`
     Image         string         `json:"image"`
     Status        string         `gorm:"not null;default:'active'" json:"status"`
+    Type          string         `gorm:"size:20;not null;default:'simple'" json:"type"`
+    TaxClass      string         `gorm:"size:50;not null;default:'standard'" json:"tax_class"`
+    // Weight is in kg and the dimensions in cm; weight-based shipping charges for the
+    // weight or the volumetric weight of the dimensions, whichever is more
+    Weight        float64        `gorm:"not null;default:0" json:"weight"`
+    Length        float64        `gorm:"not null;default:0" json:"length"`
+    Width         float64        `gorm:"not null;default:0" json:"width"`
+    Height        float64        `gorm:"not null;default:0" json:"height"`
+    // Currency is the currency Price is shown in; prices are stored in the base currency
+    Currency      string         `gorm:"-" json:"currency,omitempty"`
     Categories    []Category     `gorm:"many2many:product_categories;" json:"categories"`
+    // Options are the axes, like size or flavour, the product's variants are combinations of
+    Options       []ProductOption `gorm:"constraint:OnDelete:CASCADE" json:"options,omitempty"`
     Variants      []ProductVariant `json:"variants,omitempty"`
+    // Components are what a bundle is made of
+    Components    []BundleComponent `gorm:"foreignKey:BundleID;constraint:OnDelete:CASCADE" json:"components,omitempty"`
     CartItems     []CartItem     `json:"cart_items,omitempty"`
 }
 
@@ -22,9 +40,16 @@ type ProductVariant struct {
     Base
     ProductID     uuid.UUID `json:"product_id"`
     Name          string    `gorm:"not null" json:"name"`
+    // Options are the variant's value on each of the product's option axes, by axis name
+    Options       VariantOptions `gorm:"type:jsonb;serializer:json" json:"options,omitempty"`
     Price         float64   `gorm:"not null" json:"price"`
     StockQuantity int      `gorm:"not null" json:"stock_quantity"`
+    AvailableStock int     `gorm:"-" json:"available_stock"`
     SKU           string    `gorm:"unique" json:"sku"`
+    // Weight is in kg; zero means the product's weight
+    Weight        float64   `gorm:"not null;default:0" json:"weight"`
+    // Image is shown for the variant instead of the product's image when set
+    Image         string    `json:"image,omitempty"`
 }
 
 type CartItem struct {
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Promotion types
+const (
+	PromotionPercentage   = "percentage"
+	PromotionFixedAmount  = "fixed_amount"
+	PromotionFreeShipping = "free_shipping"
+	PromotionBuyXGetY     = "buy_x_get_y"
+)
+
+// Promotion is a discount applied at checkout, either automatically or when the
+// customer enters its coupon code
+type Promotion struct {
+	Base
+	Name        string `gorm:"not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+	// Code is what customers enter at checkout; promotions without one apply automatically
+	Code *string `gorm:"uniqueIndex" json:"code,omitempty"`
+	Type string  `gorm:"size:20;not null" json:"type"`
+	// Value is the percentage off for percentage promotions and the amount off for fixed ones
+	Value float64 `json:"value"`
+	// For every BuyQuantity eligible units, GetQuantity more get GetPercent off, cheapest first
+	BuyQuantity int     `json:"buy_quantity"`
+	GetQuantity int     `json:"get_quantity"`
+	GetPercent  float64 `json:"get_percent"`
+	// CategoryIDs limits the discount to products in these categories or their
+	// subcategories; empty means every product
+	CategoryIDs    []string `gorm:"serializer:json" json:"category_ids"`
+	MinSubtotal    float64  `json:"min_subtotal"`
+	FirstOrderOnly bool     `json:"first_order_only"`
+	// UsageLimit and PerUserLimit cap redemptions overall and per customer; 0 is unlimited
+	UsageLimit   int        `json:"usage_limit"`
+	PerUserLimit int        `json:"per_user_limit"`
+	UsageCount   int        `gorm:"not null;default:0" json:"usage_count"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	// Stackable promotions combine with each other; others are only ever applied alone
+	Stackable bool `json:"stackable"`
+	// Priority orders promotions when applying them, highest first
+	Priority int  `gorm:"not null;default:0" json:"priority"`
+	Active   bool `gorm:"not null" json:"active"`
+}
+
+// PromotionRedemption records a promotion used on an order and the total it took off
+type PromotionRedemption struct {
+	Base
+	PromotionID uuid.UUID `gorm:"type:uuid;index;not null" json:"promotion_id"`
+	OrderID     uuid.UUID `gorm:"type:uuid;index" json:"order_id"`
+	UserID      uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
+	Name        string    `json:"name"`
+	Code        string    `json:"code,omitempty"`
+	Type        string    `json:"type"`
+	Amount      float64   `gorm:"not null" json:"amount"`
+}
+
+// OrderItemDiscount is the share of a promotion taken off one order line, so refunds
+// of that line give back what the customer actually paid
+type OrderItemDiscount struct {
+	Base
+	OrderItemID uuid.UUID `gorm:"type:uuid;index" json:"order_item_id"`
+	PromotionID uuid.UUID `gorm:"type:uuid;index;not null" json:"promotion_id"`
+	Code        string    `json:"code,omitempty"`
+	Amount      float64   `gorm:"not null" json:"amount"`
+}
+
+// PromotionInput creates or updates a promotion
+type PromotionInput struct {
+	Name           string     `json:"name" binding:"required"`
+	Description    string     `json:"description"`
+	Code           string     `json:"code"`
+	Type           string     `json:"type" binding:"required,oneof=percentage fixed_amount free_shipping buy_x_get_y"`
+	Value          float64    `json:"value" binding:"gte=0"`
+	BuyQuantity    int        `json:"buy_quantity" binding:"gte=0"`
+	GetQuantity    int        `json:"get_quantity" binding:"gte=0"`
+	GetPercent     float64    `json:"get_percent" binding:"gte=0,lte=100"`
+	CategoryIDs    []string   `json:"category_ids"`
+	MinSubtotal    float64    `json:"min_subtotal" binding:"gte=0"`
+	FirstOrderOnly bool       `json:"first_order_only"`
+	UsageLimit     int        `json:"usage_limit" binding:"gte=0"`
+	PerUserLimit   int        `json:"per_user_limit" binding:"gte=0"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	Stackable      bool       `json:"stackable"`
+	Priority       int        `json:"priority"`
+	Active         *bool      `json:"active"` // Defaults to true
+}
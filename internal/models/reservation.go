@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stock reservation statuses
+const (
+	ReservationActive    = "active"
+	ReservationConverted = "converted"
+	ReservationReleased  = "released"
+)
+
+// StockReservation holds stock for an order between checkout and payment. Active
+// reservations that haven't expired count against a product's available stock; on
+// payment they are converted into a stock decrement, and on expiry or cancellation
+// they are released.
+type StockReservation struct {
+	Base
+	ProductID uuid.UUID `gorm:"type:uuid;index;not null" json:"product_id"`
+	// VariantID is set when the reservation is for one of the product's variants, whose
+	// stock is then the one held
+	VariantID *uuid.UUID `gorm:"type:uuid;index" json:"variant_id,omitempty"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	OrderID   uuid.UUID  `gorm:"type:uuid;index;not null" json:"order_id"`
+	Quantity  int        `gorm:"not null" json:"quantity"`
+	ExpiresAt time.Time  `gorm:"index;not null" json:"expires_at"`
+	Status    string     `gorm:"size:20;index;not null;default:'active'" json:"status"`
+}
+
+// ReservedStock is how much of a product or variant active reservations hold
+type ReservedStock struct {
+	ProductID uuid.UUID
+	VariantID *uuid.UUID
+	Quantity  int
+}
@@ -0,0 +1,75 @@
+package models
+
+// Post workflow statuses
+const (
+	PostStatusDraft            = "draft"
+	PostStatusInReview         = "in_review"
+	PostStatusChangesRequested = "changes_requested"
+	PostStatusApproved         = "approved"
+	PostStatusPublished        = "published"
+)
+
+// Roles that take part in the editorial workflow
+const (
+	RoleAdmin       = "admin"
+	RoleEditor      = "editor"
+	RoleContributor = "contributor"
+)
+
+// PostTransition is a move between two workflow statuses
+type PostTransition struct {
+	From string
+	To   string
+	// EditorOnly transitions can only be made by admins and editors
+	EditorOnly bool
+}
+
+// PostTransitions lists every allowed workflow transition
+var PostTransitions = []PostTransition{
+	{From: PostStatusDraft, To: PostStatusInReview},
+	{From: PostStatusInReview, To: PostStatusDraft},
+	{From: PostStatusChangesRequested, To: PostStatusInReview},
+	{From: PostStatusChangesRequested, To: PostStatusDraft},
+	{From: PostStatusInReview, To: PostStatusChangesRequested, EditorOnly: true},
+	{From: PostStatusInReview, To: PostStatusApproved, EditorOnly: true},
+	{From: PostStatusApproved, To: PostStatusChangesRequested, EditorOnly: true},
+	{From: PostStatusApproved, To: PostStatusPublished, EditorOnly: true},
+	{From: PostStatusPublished, To: PostStatusDraft, EditorOnly: true},
+}
+
+// FindPostTransition returns the transition from one status to another, if it is allowed
+func FindPostTransition(from, to string) (PostTransition, bool) {
+	for _, t := range PostTransitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return PostTransition{}, false
+}
+
+// IsEditorRole reports whether role may approve and publish posts
+func IsEditorRole(role string) bool {
+	return role == RoleAdmin || role == RoleEditor
+}
+
+// ReviewComment is a reviewer's note on a post submission
+type ReviewComment struct {
+	Base
+	PostID     string `json:"post_id" gorm:"not null;index"`
+	AuthorID   string `json:"author_id" gorm:"not null"`
+	AuthorName string `json:"author_name"`
+	Body       string `json:"body" gorm:"type:text;not null"`
+	// Status is the post status the comment was left on, or the status it moved the post to
+	Status string `json:"status"`
+}
+
+// ReviewCommentInput is used for adding a review comment
+type ReviewCommentInput struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// PostTransitionInput is used for moving a post through the workflow
+type PostTransitionInput struct {
+	Status  string `json:"status" binding:"required,oneof=draft in_review changes_requested approved published"`
+	Comment string `json:"comment"`
+}
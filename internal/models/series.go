@@ -0,0 +1,29 @@
+package models
+
+import "github.com/google/uuid"
+
+// SeriesPosts is a series with the IDs of its posts in order
+type SeriesPosts struct {
+	ID      uuid.UUID
+	Slug    string
+	Title   string
+	PostIDs []string
+}
+
+// SeriesNavItem is a neighbouring post in a series, linked by ID like every post
+type SeriesNavItem struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+}
+
+// SeriesNavigation describes where a post sits within its series
+type SeriesNavigation struct {
+	ID       uuid.UUID      `json:"id"`
+	Slug     string         `json:"slug"`
+	Title    string         `json:"title"`
+	Position int            `json:"position"`
+	Total    int            `json:"total"`
+	Prev     *SeriesNavItem `json:"prev"`
+	Next     *SeriesNavItem `json:"next"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// Shipping method types
+const (
+	ShippingFlatRate    = "flat_rate"
+	ShippingWeightBased = "weight_based"
+	ShippingPriceTiered = "price_tiered"
+	ShippingFreeOver    = "free_over"
+	ShippingLocalPickup = "local_pickup"
+)
+
+// ShippingZone is a group of places that share shipping methods. A zone with no
+// countries covers everywhere no other zone does.
+type ShippingZone struct {
+	Base
+	Name string `gorm:"not null" json:"name"`
+	// Countries are matched against the order address's country, ignoring case
+	Countries []string `gorm:"serializer:json" json:"countries"`
+	// Regions limits the zone to these states of its countries; empty covers them whole
+	Regions []string         `gorm:"serializer:json" json:"regions"`
+	Methods []ShippingMethod `gorm:"foreignKey:ShippingZoneID;constraint:OnDelete:CASCADE" json:"methods"`
+}
+
+// ShippingMethod is a way of shipping to a zone and how it is charged
+type ShippingMethod struct {
+	Base
+	ShippingZoneID uuid.UUID `gorm:"type:uuid;index;not null" json:"shipping_zone_id"`
+	Name           string    `gorm:"not null" json:"name"`
+	Description    string    `json:"description"`
+	Type           string    `gorm:"size:20;not null" json:"type"`
+	// Cost is the charge for flat rate, local pickup and free-over methods, and the
+	// charge for weight-based and price-tiered methods when no tier matches
+	Cost float64 `gorm:"not null;default:0" json:"cost"`
+	// Tiers set the cost from the order's weight in kg for weight-based methods, or from
+	// its subtotal after discounts for price-tiered ones
+	Tiers []ShippingTier `gorm:"serializer:json" json:"tiers,omitempty"`
+	// FreeOver makes the method free once the subtotal after discounts reaches it
+	FreeOver float64 `gorm:"not null;default:0" json:"free_over"`
+	Position int     `gorm:"not null;default:0" json:"position"`
+	Active   bool    `gorm:"not null" json:"active"`
+}
+
+// ShippingTier charges Cost from Min upwards, until the next tier's Min
+type ShippingTier struct {
+	Min  float64 `json:"min" binding:"gte=0"`
+	Cost float64 `json:"cost" binding:"gte=0"`
+}
+
+// ShippingOption is a shipping method available for a cart and what it costs
+type ShippingOption struct {
+	MethodID    uuid.UUID `json:"method_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Zone        string    `json:"zone"`
+	Cost        float64   `json:"cost"`
+}
+
+// ShippingZoneInput creates or updates a shipping zone
+type ShippingZoneInput struct {
+	Name      string   `json:"name" binding:"required"`
+	Countries []string `json:"countries"`
+	Regions   []string `json:"regions"`
+}
+
+// ShippingMethodInput creates or updates a shipping method
+type ShippingMethodInput struct {
+	Name        string         `json:"name" binding:"required"`
+	Description string         `json:"description"`
+	Type        string         `json:"type" binding:"required,oneof=flat_rate weight_based price_tiered free_over local_pickup"`
+	Cost        float64        `json:"cost" binding:"gte=0"`
+	Tiers       []ShippingTier `json:"tiers" binding:"dive"`
+	FreeOver    float64        `json:"free_over" binding:"gte=0"`
+	Position    int            `json:"position"`
+	Active      *bool          `json:"active"` // Defaults to true
+}
+
+// ShippingQuoteInput asks which shipping methods can deliver cart items to an address
+type ShippingQuoteInput struct {
+	UserID      string   `json:"-"`
+	CartIDs     []string `json:"cart_ids" binding:"required"`
+	CouponCodes []string `json:"coupon_codes"`
+	Country     string   `json:"country" binding:"required"`
+	State       string   `json:"state"`
+	Currency    string   `json:"currency"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// Entity types whose previous slugs are kept for redirects
+const (
+	SlugEntityPost            = "post"
+	SlugEntityProduct         = "product"
+	SlugEntityCategory        = "category"
+	SlugEntityProductCategory = "product_category"
+)
+
+// SlugRedirect records a slug an entity used to have, so old URLs can redirect to the current one
+type SlugRedirect struct {
+	Base
+	EntityType string `json:"entity_type" gorm:"size:32;not null;uniqueIndex:idx_slug_redirects_old_slug"`
+	EntityID   string `json:"entity_id" gorm:"not null;index;uniqueIndex:idx_slug_redirects_old_slug"`
+	OldSlug    string `json:"old_slug" gorm:"not null;uniqueIndex:idx_slug_redirects_old_slug"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Stock movement types
+const (
+	StockSale         = "sale"
+	StockCancellation = "cancellation"
+	StockAdjustment   = "adjustment"
+	StockReturn       = "return"
+	StockTake         = "stock_take"
+)
+
+// ErrStockLedgerImmutable is returned when something tries to change or remove a
+// stock ledger entry
+var ErrStockLedgerImmutable = errors.New("stock ledger entries can't be changed")
+
+// StockMovement is an entry in the stock ledger: one change to a product's or
+// variant's stock on hand, why it happened and who made it. Entries are never
+// updated or deleted; a correction is a new entry.
+type StockMovement struct {
+	Base
+	ProductID uuid.UUID  `gorm:"type:uuid;index;not null" json:"product_id"`
+	VariantID *uuid.UUID `gorm:"type:uuid;index" json:"variant_id,omitempty"`
+	// SKU is the product's or variant's SKU when the entry was made
+	SKU  string `gorm:"index" json:"sku"`
+	Type string `gorm:"size:20;not null" json:"type"`
+	// Quantity is the change to stock on hand: negative for sales, positive for
+	// cancellations and returns, either for adjustments and stock-takes
+	Quantity int `gorm:"not null" json:"quantity"`
+	// Balance is the stock on hand after the change
+	Balance int        `gorm:"not null" json:"balance"`
+	Reason  string     `gorm:"type:text" json:"reason"`
+	ActorID *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"`
+	OrderID *uuid.UUID `gorm:"type:uuid;index" json:"order_id,omitempty"`
+}
+
+// BeforeUpdate keeps ledger entries from being changed
+func (m *StockMovement) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStockLedgerImmutable
+}
+
+// BeforeDelete keeps ledger entries from being removed
+func (m *StockMovement) BeforeDelete(tx *gorm.DB) error {
+	return ErrStockLedgerImmutable
+}
+
+// StockAdjustmentInput records a manual change to a product's or variant's stock
+type StockAdjustmentInput struct {
+	ProductID string `json:"product_id" binding:"required"`
+	VariantID string `json:"variant_id"`
+	Type      string `json:"type" binding:"required,oneof=adjustment return stock_take"`
+	// Quantity is the change for adjustments and returns, and the counted stock for
+	// stock-takes
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason" binding:"required"`
+	ActorID  string `json:"-"`
+}
+
+// StockLedgerFilter selects the ledger entries of a SKU
+type StockLedgerFilter struct {
+	SKU   string
+	Page  int
+	Limit int
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Subscription statuses
+const (
+	SubscriptionActive   = "active"
+	SubscriptionPaused   = "paused"
+	SubscriptionCanceled = "canceled"
+)
+
+// Subscription reorders a product for a customer every IntervalDays days, shipped to
+// the address and paid with the payment method they subscribed with
+type Subscription struct {
+	Base
+	UserID    uuid.UUID       `gorm:"type:uuid;index;not null" json:"user_id"`
+	User      *User           `gorm:"foreignKey:UserID" json:"-"`
+	ProductID uuid.UUID       `gorm:"type:uuid;index;not null" json:"product_id"`
+	Product   *Product        `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	VariantID *uuid.UUID      `gorm:"type:uuid" json:"variant_id,omitempty"`
+	Variant   *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+	Quantity  int             `gorm:"not null" json:"quantity"`
+	// IntervalDays is how often the product is reordered: 30, 60 or 90 days
+	IntervalDays int `gorm:"not null" json:"interval_days"`
+	// DiscountPercent is taken off every renewal, as it was when the customer subscribed
+	DiscountPercent  float64        `gorm:"not null;default:0" json:"discount_percent"`
+	Status           string         `gorm:"size:20;not null;default:'active';index" json:"status"`
+	Address          Address        `gorm:"embedded" json:"address"`
+	PaymentMethodID  *uuid.UUID     `gorm:"type:uuid" json:"payment_method_id,omitempty"`
+	PaymentMethod    *PaymentMethod `gorm:"foreignKey:PaymentMethodID" json:"-"`
+	ShippingMethodID string         `json:"shipping_method_id,omitempty"`
+	Currency         string         `gorm:"size:3;not null" json:"currency"`
+	// NextRenewalAt is when the next order is placed
+	NextRenewalAt  time.Time  `gorm:"index;not null" json:"next_renewal_at"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at,omitempty"`
+	// RenewalOrderID is the renewal order waiting to be paid for, if any
+	RenewalOrderID *uuid.UUID `gorm:"type:uuid" json:"renewal_order_id,omitempty"`
+	LastOrderID    *uuid.UUID `gorm:"type:uuid" json:"last_order_id,omitempty"`
+	// FailedAttempts counts the renewals in a row that weren't paid for; RetryAt is
+	// when the next one is tried
+	FailedAttempts int        `gorm:"not null;default:0" json:"failed_attempts"`
+	RetryAt        *time.Time `json:"retry_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CanceledAt     *time.Time `json:"canceled_at,omitempty"`
+}
+
+// SubscriptionInput subscribes the current user to a product
+type SubscriptionInput struct {
+	UserID       string         `json:"-"`
+	ProductID    string         `json:"product_id" binding:"required"`
+	VariantID    string         `json:"variant_id"`
+	Options      VariantOptions `json:"options"`
+	Quantity     int            `json:"quantity" binding:"required,gte=1"`
+	IntervalDays int            `json:"interval_days" binding:"required,oneof=30 60 90"`
+	Address      AddressInput   `json:"address" binding:"required"`
+	// PaymentMethodID is one of the user's saved payment methods that renewals are
+	// charged to; without one, the customer is emailed to pay for each renewal
+	PaymentMethodID  string `json:"payment_method_id"`
+	ShippingMethodID string `json:"shipping_method_id"`
+	Currency         string `json:"currency"` // Defaults to the base currency
+}
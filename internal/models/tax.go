@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultTaxClass is the tax class of products that don't set one
+const DefaultTaxClass = "standard"
+
+// TaxZone is where a set of tax rates applies: a whole country, one region of it, or
+// with no country, everywhere no other zone covers
+type TaxZone struct {
+	Base
+	Name string `gorm:"not null" json:"name"`
+	// Country is matched against the order address's country, ignoring case
+	Country string `gorm:"size:100;index" json:"country"`
+	// Region is matched against the address's state; empty covers the whole country
+	Region string `gorm:"size:100" json:"region"`
+	// PricesIncludeTax means product prices already contain the tax, so it is taken out
+	// of them rather than added on top
+	PricesIncludeTax bool      `gorm:"not null;default:false" json:"prices_include_tax"`
+	Rates            []TaxRate `gorm:"foreignKey:TaxZoneID;constraint:OnDelete:CASCADE" json:"rates"`
+}
+
+// TaxRate is the tax charged in a zone on products of one tax class. A zone with no
+// rate for a class charges no tax on it.
+type TaxRate struct {
+	Base
+	TaxZoneID uuid.UUID `gorm:"type:uuid;index;not null" json:"tax_zone_id"`
+	Name      string    `gorm:"not null" json:"name"`
+	TaxClass  string    `gorm:"size:50;not null" json:"tax_class"`
+	// Rate is a percentage, e.g. 18 for 18%
+	Rate float64 `gorm:"not null" json:"rate"`
+}
+
+// OrderTaxLine is the tax one rate added to an order
+type OrderTaxLine struct {
+	Base
+	OrderID   uuid.UUID `gorm:"type:uuid;index" json:"order_id"`
+	TaxZoneID uuid.UUID `gorm:"type:uuid;index" json:"tax_zone_id"`
+	TaxRateID uuid.UUID `gorm:"type:uuid" json:"tax_rate_id"`
+	ZoneName  string    `json:"zone_name"`
+	Name      string    `json:"name"`
+	TaxClass  string    `json:"tax_class"`
+	Rate      float64   `json:"rate"`
+	Inclusive bool      `json:"inclusive"`
+	// TaxableAmount is what the rate was charged on, after discounts and excluding the tax
+	TaxableAmount float64 `gorm:"not null" json:"taxable_amount"`
+	Amount        float64 `gorm:"not null" json:"amount"`
+}
+
+// TaxZoneInput creates or updates a tax zone along with its rates
+type TaxZoneInput struct {
+	Name             string         `json:"name" binding:"required"`
+	Country          string         `json:"country"`
+	Region           string         `json:"region"`
+	PricesIncludeTax bool           `json:"prices_include_tax"`
+	Rates            []TaxRateInput `json:"rates" binding:"dive"`
+}
+
+// TaxRateInput is one rate of a TaxZoneInput
+type TaxRateInput struct {
+	Name     string  `json:"name" binding:"required"`
+	TaxClass string  `json:"tax_class"` // Defaults to DefaultTaxClass
+	Rate     float64 `json:"rate" binding:"gte=0,lte=100"`
+}
+
+// TaxReportFilter selects the orders a tax report covers
+type TaxReportFilter struct {
+	From   time.Time
+	To     time.Time
+	Period string // day, week, month, quarter or year
+}
+
+// TaxReportRow is the tax collected in one zone over one period
+type TaxReportRow struct {
+	TaxZoneID     string    `json:"tax_zone_id"`
+	Zone          string    `json:"zone"`
+	Period        time.Time `json:"period"`
+	Orders        int64     `json:"orders"`
+	TaxableAmount float64   `json:"taxable_amount"`
+	TaxAmount     float64   `json:"tax_amount"`
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// unpaidOrderStatuses are the statuses of orders that haven't been, or are no longer,
+// paid for; conversions are recorded when an order is placed, so these don't count
+var unpaidOrderStatuses = []string{"pending", "payment_pending", "payment_failed", "payment_canceled", "canceled", "refunded"}
+
+// AttributionRepo implements the Attribution interface
+type AttributionRepo struct {
+	db *gorm.DB
+}
+
+// NewAttributionRepo creates a new AttributionRepo
+func NewAttributionRepo(db *gorm.DB) Attribution {
+	return &AttributionRepo{
+		db: db,
+	}
+}
+
+// CreateClick implements the CreateClick method of the Attribution interface
+func (r *AttributionRepo) CreateClick(ctx context.Context, click *models.PostProductClick) error {
+	return r.db.WithContext(ctx).Create(click).Error
+}
+
+// LatestClicks implements the LatestClicks method of the Attribution interface
+func (r *AttributionRepo) LatestClicks(ctx context.Context, visitorID, userID string, productIDs []string, since time.Time) ([]models.PostProductClick, error) {
+	var clicks []models.PostProductClick
+	if len(productIDs) == 0 || (visitorID == "" && userID == "") {
+		return clicks, nil
+	}
+
+	// Match clicks from this browser or, once known, this account
+	who := r.db.Where("1 = 0")
+	if visitorID != "" {
+		who = who.Or("visitor_id = ?", visitorID)
+	}
+	if userID != "" {
+		who = who.Or("user_id = ?", userID)
+	}
+
+	err := r.db.WithContext(ctx).
+		Select("DISTINCT ON (product_id) *").
+		Where("product_id IN ? AND created_at >= ?", productIDs, since).
+		Where(who).
+		Order("product_id, created_at DESC").
+		Find(&clicks).Error
+	if err != nil {
+		return nil, err
+	}
+	return clicks, nil
+}
+
+// CreateConversions implements the CreateConversions method of the Attribution interface
+func (r *AttributionRepo) CreateConversions(ctx context.Context, conversions []models.PostConversion) error {
+	if len(conversions) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&conversions).Error
+}
+
+// PostStats implements the PostStats method of the Attribution interface. Posts may
+// live in another store, so the stats carry only post IDs.
+func (r *AttributionRepo) PostStats(ctx context.Context, since time.Time, limit int) ([]models.PostAttributionStats, error) {
+	var stats []models.PostAttributionStats
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT ids.post_id,
+			COALESCE(c.clicks, 0) AS clicks,
+			COALESCE(v.orders, 0) AS orders,
+			COALESCE(v.units, 0) AS units,
+			COALESCE(v.revenue, 0) AS revenue
+		FROM (
+			SELECT post_id FROM post_product_clicks WHERE created_at >= @since
+			UNION
+			SELECT pc.post_id FROM post_conversions pc
+			JOIN orders o ON o.id::text = pc.order_id
+			WHERE pc.created_at >= @since AND o.status NOT IN @unpaid
+		) ids
+		LEFT JOIN (
+			SELECT post_id, COUNT(*) AS clicks
+			FROM post_product_clicks
+			WHERE created_at >= @since
+			GROUP BY post_id
+		) c ON c.post_id = ids.post_id
+		LEFT JOIN (
+			SELECT pc.post_id, COUNT(DISTINCT pc.order_id) AS orders, SUM(pc.quantity) AS units, SUM(pc.revenue) AS revenue
+			FROM post_conversions pc
+			JOIN orders o ON o.id::text = pc.order_id
+			WHERE pc.created_at >= @since AND o.status NOT IN @unpaid
+			GROUP BY pc.post_id
+		) v ON v.post_id = ids.post_id
+		ORDER BY revenue DESC, clicks DESC
+		LIMIT @limit
+	`, map[string]interface{}{"since": since, "limit": limit, "unpaid": unpaidOrderStatuses}).Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// PostProductStats implements the PostProductStats method of the Attribution interface
+func (r *AttributionRepo) PostProductStats(ctx context.Context, postID string, since time.Time) ([]models.ProductAttributionStats, error) {
+	var stats []models.ProductAttributionStats
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT ids.product_id, COALESCE(pr.name, '') AS name,
+			COALESCE(c.clicks, 0) AS clicks,
+			COALESCE(v.orders, 0) AS orders,
+			COALESCE(v.units, 0) AS units,
+			COALESCE(v.revenue, 0) AS revenue
+		FROM (
+			SELECT product_id FROM post_product_clicks WHERE post_id = @post AND created_at >= @since
+			UNION
+			SELECT pc.product_id FROM post_conversions pc
+			JOIN orders o ON o.id::text = pc.order_id
+			WHERE pc.post_id = @post AND pc.created_at >= @since AND o.status NOT IN @unpaid
+		) ids
+		LEFT JOIN products pr ON pr.id::text = ids.product_id
+		LEFT JOIN (
+			SELECT product_id, COUNT(*) AS clicks
+			FROM post_product_clicks
+			WHERE post_id = @post AND created_at >= @since
+			GROUP BY product_id
+		) c ON c.product_id = ids.product_id
+		LEFT JOIN (
+			SELECT pc.product_id, COUNT(DISTINCT pc.order_id) AS orders, SUM(pc.quantity) AS units, SUM(pc.revenue) AS revenue
+			FROM post_conversions pc
+			JOIN orders o ON o.id::text = pc.order_id
+			WHERE pc.post_id = @post AND pc.created_at >= @since AND o.status NOT IN @unpaid
+			GROUP BY pc.product_id
+		) v ON v.product_id = ids.product_id
+		ORDER BY revenue DESC, clicks DESC
+	`, map[string]interface{}{"post": postID, "since": since, "unpaid": unpaidOrderStatuses}).Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
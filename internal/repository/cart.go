@@ -31,6 +31,10 @@ func (r *CartItemsRepo) GetByID(ctx context.Context, id string) (*models.CartIte
     
     if err := r.db.WithContext(ctx).
         Preload("Product").
+        Preload("Product.Categories").
+        Preload("Product.Components").
+        Preload("Product.Components.Product").
+        Preload("Product.Components.Variant").
         Preload("Variant").
         First(&item, uuid).Error; err != nil {
         return nil, err
@@ -50,6 +54,10 @@ func (r *CartItemsRepo) GetUserCart(ctx context.Context, userID string) ([]model
     if err := r.db.WithContext(ctx).
         Where("user_id = ?", userUUID).
         Preload("Product").
+        Preload("Product.Categories").
+        Preload("Product.Components").
+        Preload("Product.Components.Product").
+        Preload("Product.Components.Variant").
         Preload("Variant").
         Find(&items).Error; err != nil {
         return nil, err
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errCreditShort is returned inside a credit transaction when an entry would take a
+// balance below zero, or an order's credit past its total, so the transaction rolls back
+var errCreditShort = errors.New("not enough credit")
+
+// CreditsRepo implements the Credits interface
+type CreditsRepo struct {
+	db *gorm.DB
+}
+
+// NewCreditsRepo creates a new CreditsRepo
+func NewCreditsRepo(db *gorm.DB) Credits {
+	return &CreditsRepo{
+		db: db,
+	}
+}
+
+// CreateGiftCard implements the CreateGiftCard method of the Credits interface
+func (r *CreditsRepo) CreateGiftCard(ctx context.Context, card *models.GiftCard, entry *models.CreditTransaction) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(card).Error; err != nil {
+			return err
+		}
+		entry.GiftCardID = &card.ID
+		entry.Type = models.CreditIssue
+		entry.Amount = card.Balance
+		entry.Balance = card.Balance
+		if entry.OrderID == nil {
+			entry.OrderID = card.OrderID
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+// GetGiftCardByCode implements the GetGiftCardByCode method of the Credits interface
+func (r *CreditsRepo) GetGiftCardByCode(ctx context.Context, code string) (*models.GiftCard, error) {
+	var card models.GiftCard
+	if err := r.db.WithContext(ctx).First(&card, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// GetStoreCredit implements the GetStoreCredit method of the Credits interface
+func (r *CreditsRepo) GetStoreCredit(ctx context.Context, userID uuid.UUID) (*models.StoreCredit, error) {
+	var credit models.StoreCredit
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&credit).Error
+	if err != nil {
+		return nil, err
+	}
+	if credit.ID == uuid.Nil {
+		credit.UserID = userID
+	}
+	return &credit, nil
+}
+
+// Record implements the Record method of the Credits interface
+func (r *CreditsRepo) Record(ctx context.Context, entries []models.CreditTransaction) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range entries {
+			if err := recordCreditTransaction(tx, &entries[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errCreditShort) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Redeem implements the Redeem method of the Credits interface
+func (r *CreditsRepo) Redeem(ctx context.Context, orderID uuid.UUID, applied float64, entries []models.CreditTransaction) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The order has to still be awaiting payment, and can't be paid for twice over;
+		// the small margin allows for float rounding when credit settles the whole total
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ? AND credit_applied + ? <= total_amount + 0.000001", orderID, "pending", applied).
+			Update("credit_applied", gorm.Expr("credit_applied + ?", applied))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCreditShort
+		}
+
+		for i := range entries {
+			entries[i].OrderID = &orderID
+			if err := recordCreditTransaction(tx, &entries[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errCreditShort) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ActivateOrderGiftCards implements the ActivateOrderGiftCards method of the Credits interface
+func (r *CreditsRepo) ActivateOrderGiftCards(ctx context.Context, orderID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.GiftCard{}).
+		Where("order_id = ? AND status = ?", orderID, models.GiftCardPending).
+		Update("status", models.GiftCardActive).Error
+}
+
+// ReleaseOrder implements the ReleaseOrder method of the Credits interface
+func (r *CreditsRepo) ReleaseOrder(ctx context.Context, orderID uuid.UUID, actorID *uuid.UUID, reason string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Give back what the order took from each gift card and store credit balance,
+		// less anything already given back
+		var taken []struct {
+			GiftCardID *uuid.UUID
+			UserID     *uuid.UUID
+			Amount     float64
+		}
+		err := tx.Model(&models.CreditTransaction{}).
+			Select("gift_card_id, user_id, SUM(amount) AS amount").
+			Where("order_id = ? AND type IN ?", orderID, []string{models.CreditRedemption, models.CreditRefund}).
+			Group("gift_card_id, user_id").
+			Having("SUM(amount) < 0").
+			Scan(&taken).Error
+		if err != nil {
+			return err
+		}
+		for _, t := range taken {
+			entry := models.CreditTransaction{
+				GiftCardID: t.GiftCardID,
+				UserID:     t.UserID,
+				Type:       models.CreditRefund,
+				Amount:     -t.Amount,
+				Reason:     reason,
+				ActorID:    actorID,
+				OrderID:    &orderID,
+			}
+			if err := recordCreditTransaction(tx, &entry); err != nil {
+				return err
+			}
+		}
+
+		// Void the gift cards bought in the order, with whatever is left on them
+		var cards []models.GiftCard
+		err = tx.Where("order_id = ? AND status <> ?", orderID, models.GiftCardVoid).Find(&cards).Error
+		if err != nil {
+			return err
+		}
+		for _, card := range cards {
+			entry := models.CreditTransaction{
+				GiftCardID: &card.ID,
+				Type:       models.CreditVoid,
+				Reason:     reason,
+				ActorID:    actorID,
+				OrderID:    &orderID,
+			}
+			if err := recordCreditTransaction(tx, &entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// History implements the History method of the Credits interface
+func (r *CreditsRepo) History(ctx context.Context, filter models.CreditLedgerFilter) ([]models.CreditTransaction, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.CreditTransaction{})
+	if filter.GiftCardID != nil {
+		query = query.Where("gift_card_id = ?", *filter.GiftCardID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page > 0 && filter.Limit > 0 {
+		query = query.Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit)
+	}
+
+	var entries []models.CreditTransaction
+	if err := query.Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// recordCreditTransaction applies an entry's change to its gift card or store credit
+// balance and saves it to the ledger. The change is made in one conditional statement,
+// so a balance can't be spent twice however many checkouts run at once; errCreditShort
+// is returned if it would go below zero, or a gift card being redeemed can't be used.
+// A void entry takes whatever is left on its gift card.
+func recordCreditTransaction(tx *gorm.DB, entry *models.CreditTransaction) error {
+	now := time.Now()
+	var row *sql.Row
+	switch {
+	case entry.GiftCardID != nil && entry.Type == models.CreditVoid:
+		var left float64
+		if err := tx.Raw("SELECT balance FROM gift_cards WHERE id = ? FOR UPDATE", *entry.GiftCardID).
+			Row().Scan(&left); err != nil {
+			return err
+		}
+		entry.Amount = -left
+		row = tx.Raw(`
+			UPDATE gift_cards
+			SET balance = 0, status = ?, updated_at = ?
+			WHERE id = ?
+			RETURNING balance
+		`, models.GiftCardVoid, now, *entry.GiftCardID).Row()
+	case entry.GiftCardID != nil && entry.Type == models.CreditRedemption:
+		row = tx.Raw(`
+			UPDATE gift_cards
+			SET balance = balance + ?, updated_at = ?
+			WHERE id = ? AND balance + ? >= 0 AND status = ?
+				AND (expires_at IS NULL OR expires_at > ?)
+			RETURNING balance
+		`, entry.Amount, now, *entry.GiftCardID, entry.Amount, models.GiftCardActive, now).Row()
+	case entry.GiftCardID != nil:
+		row = tx.Raw(`
+			UPDATE gift_cards
+			SET balance = balance + ?, updated_at = ?
+			WHERE id = ? AND balance + ? >= 0
+			RETURNING balance
+		`, entry.Amount, now, *entry.GiftCardID, entry.Amount).Row()
+	case entry.UserID != nil && entry.Amount < 0:
+		row = tx.Raw(`
+			UPDATE store_credits
+			SET balance = balance + ?, updated_at = ?
+			WHERE user_id = ? AND balance + ? >= 0
+			RETURNING balance
+		`, entry.Amount, now, *entry.UserID, entry.Amount).Row()
+	case entry.UserID != nil:
+		row = tx.Raw(`
+			INSERT INTO store_credits (id, user_id, balance, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (user_id) DO UPDATE
+			SET balance = store_credits.balance + EXCLUDED.balance, updated_at = EXCLUDED.updated_at
+			RETURNING balance
+		`, uuid.New(), *entry.UserID, entry.Amount, now, now).Row()
+	default:
+		return errors.New("credit transaction needs a gift card or a user")
+	}
+
+	err := row.Scan(&entry.Balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errCreditShort
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Create(entry).Error
+}
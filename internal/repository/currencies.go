@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CurrenciesRepo implements the Currencies interface
+type CurrenciesRepo struct {
+	db *gorm.DB
+}
+
+// NewCurrenciesRepo creates a new CurrenciesRepo
+func NewCurrenciesRepo(db *gorm.DB) Currencies {
+	return &CurrenciesRepo{
+		db: db,
+	}
+}
+
+// Create implements the Create method of the Currencies interface
+func (r *CurrenciesRepo) Create(ctx context.Context, currency *models.Currency) error {
+	return r.db.WithContext(ctx).Create(currency).Error
+}
+
+// GetByCode implements the GetByCode method of the Currencies interface
+func (r *CurrenciesRepo) GetByCode(ctx context.Context, code string) (*models.Currency, error) {
+	var currency models.Currency
+	if err := r.db.WithContext(ctx).First(&currency, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &currency, nil
+}
+
+// List implements the List method of the Currencies interface
+func (r *CurrenciesRepo) List(ctx context.Context, activeOnly bool) ([]models.Currency, error) {
+	var currencies []models.Currency
+	query := r.db.WithContext(ctx).Order("code")
+	if activeOnly {
+		query = query.Where("active")
+	}
+	err := query.Find(&currencies).Error
+	return currencies, err
+}
+
+// Update implements the Update method of the Currencies interface
+func (r *CurrenciesRepo) Update(ctx context.Context, currency *models.Currency) error {
+	return r.db.WithContext(ctx).Save(currency).Error
+}
+
+// Delete implements the Delete method of the Currencies interface
+func (r *CurrenciesRepo) Delete(ctx context.Context, code string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("currency = ?", code).Delete(&models.ProductPrice{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("code = ?", code).Delete(&models.Currency{}).Error
+	})
+}
+
+// SetProductPrice implements the SetProductPrice method of the Currencies interface
+func (r *CurrenciesRepo) SetProductPrice(ctx context.Context, price *models.ProductPrice) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := productPriceScope(tx, price.ProductID, price.VariantID, price.Currency).
+			Delete(&models.ProductPrice{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(price).Error
+	})
+}
+
+// DeleteProductPrice implements the DeleteProductPrice method of the Currencies interface
+func (r *CurrenciesRepo) DeleteProductPrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string) error {
+	return productPriceScope(r.db.WithContext(ctx), productID, variantID, currency).
+		Delete(&models.ProductPrice{}).Error
+}
+
+// ProductPrices implements the ProductPrices method of the Currencies interface
+func (r *CurrenciesRepo) ProductPrices(ctx context.Context, productIDs []uuid.UUID, currency string) ([]models.ProductPrice, error) {
+	var prices []models.ProductPrice
+	if len(productIDs) == 0 {
+		return prices, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("product_id IN ? AND currency = ?", productIDs, currency).
+		Find(&prices).Error
+	return prices, err
+}
+
+// productPriceScope selects the price override for a product or one of its variants
+func productPriceScope(db *gorm.DB, productID uuid.UUID, variantID *uuid.UUID, currency string) *gorm.DB {
+	db = db.Where("product_id = ? AND currency = ?", productID, currency)
+	if variantID == nil {
+		return db.Where("variant_id IS NULL")
+	}
+	return db.Where("variant_id = ?", *variantID)
+}
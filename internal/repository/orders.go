@@ -35,6 +35,11 @@ func (r *OrdersRepo) GetByID(ctx context.Context, id string) (*models.Order, err
         Preload("Items").
         Preload("Items.Product").
         Preload("Items.Variant").
+        Preload("Items.Discounts").
+        Preload("Items.Components").
+        Preload("Promotions").
+        Preload("TaxLines").
+        Preload("GiftCards").
         First(&order, uuid).Error; err != nil {
         return nil, err
     }
@@ -82,6 +87,10 @@ func (r *OrdersRepo) List(ctx context.Context, filter models.OrderFilter) ([]mod
         Preload("Items").
         Preload("Items.Product").
         Preload("Items.Variant").
+        Preload("Items.Discounts").
+        Preload("Items.Components").
+        Preload("Promotions").
+        Preload("TaxLines").
         Order("created_at DESC").
         Offset(offset).
         Limit(pageSize).
@@ -99,4 +108,75 @@ func (r *OrdersRepo) Update(ctx context.Context, id string, order *models.Order)
     }
     
     return r.db.WithContext(ctx).Model(&models.Order{}).Where("id = ?", uuid).Updates(order).Error
-}
\ No newline at end of file
+}
+
+// CountByUser implements the CountByUser method of the Orders interface
+func (r *OrdersRepo) CountByUser(ctx context.Context, userID string) (int64, error) {
+    var count int64
+    err := r.db.WithContext(ctx).Model(&models.Order{}).
+        Where("user_id = ? AND status <> ?", userID, "canceled").
+        Count(&count).Error
+    return count, err
+}
+
+// CancelPending implements the CancelPending method of the Orders interface
+func (r *OrdersRepo) CancelPending(ctx context.Context, id uuid.UUID) (bool, error) {
+    // Checking the status in the same statement keeps a payment landing now from being undone
+    result := r.db.WithContext(ctx).Model(&models.Order{}).
+        Where("id = ? AND status = ?", id, "pending").
+        Update("status", "canceled")
+    if result.Error != nil {
+        return false, result.Error
+    }
+    return result.RowsAffected > 0, nil
+}
+
+// MarkPaid implements the MarkPaid method of the Orders interface
+func (r *OrdersRepo) MarkPaid(ctx context.Context, id uuid.UUID) (bool, error) {
+    result := r.db.WithContext(ctx).Model(&models.Order{}).
+        Where("id = ? AND status = ?", id, "pending").
+        Update("status", "processing")
+    if result.Error != nil {
+        return false, result.Error
+    }
+    return result.RowsAffected > 0, nil
+}
+
+// ProductRevenue implements the ProductRevenue method of the Orders interface
+func (r *OrdersRepo) ProductRevenue(ctx context.Context, filter models.ProductRevenueFilter) ([]models.ProductRevenueRow, error) {
+    var rows []models.ProductRevenueRow
+    err := r.db.WithContext(ctx).Raw(`
+        WITH sold AS (
+            SELECT i.product_id, i.variant_id, i.quantity,
+                (i.price_at_time * i.quantity - i.discount_amount) / o.exchange_rate AS revenue,
+                0 AS bundle_revenue
+            FROM order_items i
+            JOIN orders o ON o.id = i.order_id
+            WHERE o.status <> 'canceled' AND o.created_at >= @from AND o.created_at < @to
+                AND NOT EXISTS (SELECT 1 FROM order_item_components c WHERE c.order_item_id = i.id)
+            UNION ALL
+            SELECT c.product_id, c.variant_id, c.quantity,
+                c.revenue / o.exchange_rate AS revenue,
+                c.revenue / o.exchange_rate AS bundle_revenue
+            FROM order_item_components c
+            JOIN order_items i ON i.id = c.order_item_id
+            JOIN orders o ON o.id = i.order_id
+            WHERE o.status <> 'canceled' AND o.created_at >= @from AND o.created_at < @to
+        )
+        SELECT s.product_id::text AS product_id, s.variant_id::text AS variant_id,
+            p.name || COALESCE(' - ' || v.name, '') AS name,
+            COALESCE(v.sku, p.sku, '') AS sku,
+            SUM(s.quantity) AS quantity,
+            SUM(s.revenue) AS revenue,
+            SUM(s.bundle_revenue) AS bundle_revenue
+        FROM sold s
+        JOIN products p ON p.id = s.product_id
+        LEFT JOIN product_variants v ON v.id = s.variant_id
+        GROUP BY s.product_id, s.variant_id, p.name, p.sku, v.name, v.sku
+        ORDER BY revenue DESC
+    `, map[string]interface{}{"from": filter.From, "to": filter.To}).Scan(&rows).Error
+    if err != nil {
+        return nil, err
+    }
+    return rows, nil
+}
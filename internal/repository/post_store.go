@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Post storage backends, chosen with POSTS_BACKEND
+const (
+	PostsBackendPostgres = "postgres"
+	PostsBackendMongo    = "mongo"
+)
+
+// PostsBackend returns the configured post storage backend, defaulting to Postgres
+func PostsBackend() (string, error) {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("POSTS_BACKEND")))
+	switch backend {
+	case "", PostsBackendPostgres, "postgresql":
+		return PostsBackendPostgres, nil
+	case PostsBackendMongo, "mongodb":
+		return PostsBackendMongo, nil
+	default:
+		return "", fmt.Errorf("unknown POSTS_BACKEND %q: use %q or %q", backend, PostsBackendPostgres, PostsBackendMongo)
+	}
+}
+
+// ConnectMongo connects to the MongoDB server at MONGODB_URI and returns the
+// MONGODB_DATABASE database
+func ConnectMongo(ctx context.Context) (*mongo.Client, *mongo.Database, error) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	dbName := os.Getenv("MONGODB_DATABASE")
+	if dbName == "" {
+		dbName = "blog_ecommerce"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, nil, err
+	}
+
+	return client, client.Database(dbName), nil
+}
@@ -3,9 +3,11 @@ package repository
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -16,53 +18,218 @@ type MongoPostRepository struct {
 	collection *mongo.Collection
 }
 
+// postDocument is how a post is stored in MongoDB; the ID is kept as a string so
+// posts keep the same IDs as in Postgres
+type postDocument struct {
+	ID                 string     `bson:"_id"`
+	Title              string     `bson:"title"`
+	Content            string     `bson:"content"`
+	Excerpt            string     `bson:"excerpt"`
+	FeaturedImage      string     `bson:"featured_image"`
+	AuthorID           string     `bson:"author_id"`
+	AuthorName         string     `bson:"author_name"`
+	Categories         []string   `bson:"categories"`
+	Tags               []string   `bson:"tags"`
+	Published          bool       `bson:"published"`
+	PublishedAt        *time.Time `bson:"published_at,omitempty"`
+	Status             string     `bson:"status"`
+	Locale             string     `bson:"locale"`
+	TranslationGroupID string     `bson:"translation_group_id"`
+	CreatedAt          time.Time  `bson:"created_at"`
+	UpdatedAt          time.Time  `bson:"updated_at"`
+}
+
+// ErrPostNotFound is returned by the MongoDB post store for a post that doesn't exist;
+// the Postgres store returns gorm.ErrRecordNotFound
+var ErrPostNotFound = errors.New("post not found")
+
 // NewMongoPostRepository creates a new MongoDB-based post repository
 func NewMongoPostRepository(db *mongo.Database) *MongoPostRepository {
 	collection := db.Collection("posts")
-	
+
 	// Create indexes
-	titleIndex := mongo.IndexModel{
-		Keys: bson.M{"title": 1},
+	createdIndex := mongo.IndexModel{
+		Keys: bson.M{"created_at": -1},
 	}
-	
-	slugIndex := mongo.IndexModel{
-		Keys:    bson.M{"slug": 1},
-		Options: options.Index().SetUnique(true),
-	}
-	
+
 	authorIndex := mongo.IndexModel{
 		Keys: bson.M{"author_id": 1},
 	}
-	
+
 	categoryIndex := mongo.IndexModel{
 		Keys: bson.M{"categories": 1},
 	}
-	
+
+	tagIndex := mongo.IndexModel{
+		Keys: bson.M{"tags": 1},
+	}
+
+	statusIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: -1}},
+	}
+
+	translationIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "translation_group_id", Value: 1}, {Key: "locale", Value: 1}},
+	}
+
+	// Full-text search over the same fields as the Postgres backend
+	textIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "excerpt", Value: "text"}, {Key: "content", Value: "text"}},
+		Options: options.Index().SetWeights(bson.M{"title": 10, "excerpt": 5, "content": 1}),
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	
+
 	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
-		titleIndex,
-		slugIndex,
+		createdIndex,
 		authorIndex,
 		categoryIndex,
+		tagIndex,
+		statusIndex,
+		translationIndex,
+		textIndex,
 	})
 	if err != nil {
 		// Log the error but don't fail
-		// log.Printf("Failed to create post indexes: %v", err)
+		log.Printf("Failed to create post indexes: %v", err)
 	}
-	
+
 	return &MongoPostRepository{
 		collection: collection,
 	}
 }
 
+// toPostDocument converts a post into its MongoDB representation
+func toPostDocument(post *models.Post) *postDocument {
+	return &postDocument{
+		ID:                 post.ID.String(),
+		Title:              post.Title,
+		Content:            post.Content,
+		Excerpt:            post.Excerpt,
+		FeaturedImage:      post.FeaturedImage,
+		AuthorID:           post.AuthorID,
+		AuthorName:         post.AuthorName,
+		Categories:         post.Categories,
+		Tags:               post.Tags,
+		Published:          post.Published,
+		PublishedAt:        post.PublishedAt,
+		Status:             post.Status,
+		Locale:             post.Locale,
+		TranslationGroupID: post.TranslationGroupID,
+		CreatedAt:          post.CreatedAt,
+		UpdatedAt:          post.UpdatedAt,
+	}
+}
+
+// toPost converts a stored document back into a post
+func (d *postDocument) toPost() models.Post {
+	id, _ := uuid.Parse(d.ID)
+	return models.Post{
+		Base: models.Base{
+			ID:        id,
+			CreatedAt: d.CreatedAt,
+			UpdatedAt: d.UpdatedAt,
+		},
+		Title:              d.Title,
+		Content:            d.Content,
+		Excerpt:            d.Excerpt,
+		FeaturedImage:      d.FeaturedImage,
+		AuthorID:           d.AuthorID,
+		AuthorName:         d.AuthorName,
+		Categories:         d.Categories,
+		Tags:               d.Tags,
+		Published:          d.Published,
+		PublishedAt:        d.PublishedAt,
+		Status:             d.Status,
+		Locale:             d.Locale,
+		TranslationGroupID: d.TranslationGroupID,
+	}
+}
+
+// postQueryFilter builds the MongoDB filter for a post query
+func postQueryFilter(query models.PostQuery) bson.M {
+	filter := bson.M{}
+	if query.Category != "" {
+		filter["categories"] = query.Category
+	}
+	if query.Tag != "" {
+		filter["tags"] = query.Tag
+	}
+	if query.AuthorID != "" {
+		filter["author_id"] = query.AuthorID
+	}
+	if query.Search != "" {
+		filter["$text"] = bson.M{"$search": query.Search}
+	}
+	if query.Published != nil {
+		filter["published"] = *query.Published
+	}
+	if query.PublishedAfter != nil {
+		filter["published_at"] = bson.M{"$gte": *query.PublishedAfter}
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	if query.TranslationGroupID != "" {
+		filter["translation_group_id"] = query.TranslationGroupID
+	}
+	if len(query.IDs) > 0 {
+		filter["_id"] = bson.M{"$in": query.IDs}
+	}
+	if len(query.Locales) > 0 {
+		filter["locale"] = bson.M{"$in": query.Locales}
+	}
+	return filter
+}
+
+// find runs a query and decodes the matching posts
+func (r *MongoPostRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Post, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []postDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	posts := make([]models.Post, 0, len(docs))
+	for i := range docs {
+		posts = append(posts, docs[i].toPost())
+	}
+	return posts, nil
+}
+
+// pageOptions sorts newest first and applies pagination when page and limit are set
+func pageOptions(sortField string, page, limit int) *options.FindOptions {
+	opts := options.Find().SetSort(bson.D{{Key: sortField, Value: -1}})
+	if page > 0 && limit > 0 {
+		opts.SetSkip(int64((page - 1) * limit)).SetLimit(int64(limit))
+	}
+	return opts
+}
+
 // Create inserts a new post into the database
 func (r *MongoPostRepository) Create(ctx context.Context, post *models.Post) error {
-	_, err := r.collection.InsertOne(ctx, post)
+	// Match GORM's hooks so posts get the same IDs and timestamps in either store
+	if post.ID == uuid.Nil {
+		post.ID = uuid.New()
+	}
+	now := time.Now()
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = now
+	}
+	if post.UpdatedAt.IsZero() {
+		post.UpdatedAt = now
+	}
+
+	_, err := r.collection.InsertOne(ctx, toPostDocument(post))
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
-			return errors.New("post with this slug already exists")
+			return errors.New("post already exists")
 		}
 		return err
 	}
@@ -71,50 +238,51 @@ func (r *MongoPostRepository) Create(ctx context.Context, post *models.Post) err
 
 // GetByID retrieves a post by ID
 func (r *MongoPostRepository) GetByID(ctx context.Context, id string) (*models.Post, error) {
-	var post models.Post
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&post)
+	var doc postDocument
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("post not found")
+			return nil, ErrPostNotFound
 		}
 		return nil, err
 	}
+	post := doc.toPost()
 	return &post, nil
 }
 
 // GetAll retrieves all posts with pagination
 func (r *MongoPostRepository) GetAll(ctx context.Context, page, limit int) ([]*models.Post, error) {
-	skip := (page - 1) * limit
-	
-	options := options.Find().
-		SetSort(bson.M{"created_at": -1}).
-		SetSkip(int64(skip)).
-		SetLimit(int64(limit))
-	
-	cursor, err := r.collection.Find(ctx, bson.M{}, options)
+	posts, _, err := r.Find(ctx, models.PostQuery{Page: page, Limit: limit})
+	return posts, err
+}
+
+// Find retrieves the posts matching query, newest first, with the total count
+func (r *MongoPostRepository) Find(ctx context.Context, query models.PostQuery) ([]*models.Post, int64, error) {
+	filter := postQueryFilter(query)
+
+	total, err := r.collection.CountDocuments(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	defer cursor.Close(ctx)
-	
-	var posts []*models.Post
-	if err := cursor.All(ctx, &posts); err != nil {
-		return nil, err
+
+	posts, err := r.find(ctx, filter, pageOptions("created_at", query.Page, query.Limit))
+	if err != nil {
+		return nil, 0, err
 	}
-	
-	return posts, nil
+
+	return models.PagePosts(posts, 0, 0), total, nil
 }
 
 // Update updates a post in the database
 func (r *MongoPostRepository) Update(ctx context.Context, post *models.Post) error {
 	post.UpdatedAt = time.Now()
-	
-	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": post.ID}, post)
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": post.ID.String()}, toPostDocument(post))
 	if err != nil {
 		return err
 	}
 	if result.MatchedCount == 0 {
-		return errors.New("post not found")
+		return ErrPostNotFound
 	}
 	return nil
 }
@@ -126,62 +294,114 @@ func (r *MongoPostRepository) Delete(ctx context.Context, id string) error {
 		return err
 	}
 	if result.DeletedCount == 0 {
-		return errors.New("post not found")
+		return ErrPostNotFound
 	}
 	return nil
 }
 
 // GetByCategory retrieves posts by category
 func (r *MongoPostRepository) GetByCategory(ctx context.Context, category string, page, limit int) ([]*models.Post, error) {
-	skip := (page - 1) * limit
-	
-	filter := bson.M{"categories": category}
-	
-	options := options.Find().
-		SetSort(bson.M{"created_at": -1}).
-		SetSkip(int64(skip)).
-		SetLimit(int64(limit))
-	
-	cursor, err := r.collection.Find(ctx, filter, options)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-	
-	var posts []*models.Post
-	if err := cursor.All(ctx, &posts); err != nil {
-		return nil, err
-	}
-	
-	return posts, nil
+	posts, _, err := r.Find(ctx, models.PostQuery{Category: category, Page: page, Limit: limit})
+	return posts, err
 }
 
 // GetByAuthor retrieves posts by author
 func (r *MongoPostRepository) GetByAuthor(ctx context.Context, authorID string, page, limit int) ([]*models.Post, error) {
-	skip := (page - 1) * limit
-	
-	filter := bson.M{"author_id": authorID}
-	
-	options := options.Find().
-		SetSort(bson.M{"created_at": -1}).
-		SetSkip(int64(skip)).
-		SetLimit(int64(limit))
-	
-	cursor, err := r.collection.Find(ctx, filter, options)
+	posts, _, err := r.Find(ctx, models.PostQuery{AuthorID: authorID, Page: page, Limit: limit})
+	return posts, err
+}
+
+// Count returns the total number of posts
+func (r *MongoPostRepository) Count(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{})
+}
+
+// GetByLocale retrieves one post per translation group in locale, falling back to the
+// fallback locale for groups that have not been translated
+func (r *MongoPostRepository) GetByLocale(ctx context.Context, locale, fallback string, query models.PostQuery) ([]*models.Post, int64, error) {
+	query.Locales = []string{locale, fallback}
+
+	// Keep the preferred version of each translation group; posts that were never
+	// translated are a group of their own
+	page := bson.A{bson.M{"$skip": int64(0)}} // a facet needs at least one stage
+	if query.Page > 0 && query.Limit > 0 {
+		page = bson.A{
+			bson.M{"$skip": int64((query.Page - 1) * query.Limit)},
+			bson.M{"$limit": int64(query.Limit)},
+		}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: postQueryFilter(query)}},
+		{{Key: "$addFields", Value: bson.M{
+			"_group": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$translation_group_id", ""}}, ""}},
+				"$_id",
+				"$translation_group_id",
+			}},
+			"_preference": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$locale", locale}}, 0, 1}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_group", Value: 1}, {Key: "_preference", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$_group", "post": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$post"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"posts": page,
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer cursor.Close(ctx)
-	
-	var posts []*models.Post
-	if err := cursor.All(ctx, &posts); err != nil {
+
+	var results []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Posts []postDocument `bson:"posts"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return []*models.Post{}, 0, nil
+	}
+
+	var total int64
+	if len(results[0].Total) > 0 {
+		total = results[0].Total[0].Count
+	}
+	posts := make([]models.Post, 0, len(results[0].Posts))
+	for i := range results[0].Posts {
+		posts = append(posts, results[0].Posts[i].toPost())
+	}
+	return models.PagePosts(posts, 0, 0), total, nil
+}
+
+// GetTranslations retrieves all posts in a translation group
+func (r *MongoPostRepository) GetTranslations(ctx context.Context, groupID string) ([]*models.Post, error) {
+	posts, err := r.find(ctx, bson.M{"translation_group_id": groupID}, options.Find())
+	if err != nil {
 		return nil, err
 	}
-	
-	return posts, nil
+	return models.PagePosts(posts, 0, 0), nil
 }
 
-// Count returns the total number of posts
-func (r *MongoPostRepository) Count(ctx context.Context) (int64, error) {
-	return r.collection.CountDocuments(ctx, bson.M{})
-}
\ No newline at end of file
+// GetByStatus retrieves posts in a workflow status with pagination
+func (r *MongoPostRepository) GetByStatus(ctx context.Context, status string, page, limit int) ([]*models.Post, int64, error) {
+	filter := bson.M{"status": status}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	posts, err := r.find(ctx, filter, pageOptions("updated_at", page, limit))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return models.PagePosts(posts, 0, 0), total, nil
+}
@@ -2,10 +2,13 @@ package repository
 
 import (
     "context"
+    "errors"
     "strconv"
+    "strings"
     "github.com/adrianmcmains/blog-ecommerce/internal/models"
     "github.com/google/uuid"
     "gorm.io/gorm"
+    "gorm.io/gorm/clause"
 )
 
 type ProductsRepo struct {
@@ -32,7 +35,11 @@ func (r *ProductsRepo) GetByID(ctx context.Context, id string) (*models.Product,
     
     if err := r.db.WithContext(ctx).
         Preload("Categories").
+        Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
         Preload("Variants").
+        Preload("Components").
+        Preload("Components.Product").
+        Preload("Components.Variant").
         First(&product, uuid).Error; err != nil {
         return nil, err
     }
@@ -40,6 +47,28 @@ func (r *ProductsRepo) GetByID(ctx context.Context, id string) (*models.Product,
     return &product, nil
 }
 
+func (r *ProductsRepo) GetBySlugs(ctx context.Context, slugs []string) ([]models.Product, error) {
+    var products []models.Product
+    if len(slugs) == 0 {
+        return products, nil
+    }
+    
+    if err := r.db.WithContext(ctx).Where("slug IN ?", slugs).Find(&products).Error; err != nil {
+        return nil, err
+    }
+    
+    return products, nil
+}
+
+// categoryTreeBySlugSQL selects the category with a slug and every category nested under it
+const categoryTreeBySlugSQL = `
+    WITH RECURSIVE tree AS (
+        SELECT id FROM categories WHERE slug = ?
+        UNION
+        SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+    )
+    SELECT id FROM tree`
+
 func (r *ProductsRepo) List(ctx context.Context, filter models.ProductFilter) ([]models.Product, int64, error) {
     var products []models.Product
     var total int64
@@ -48,9 +77,9 @@ func (r *ProductsRepo) List(ctx context.Context, filter models.ProductFilter) ([
     
     // Apply filters
     if filter.Category != "" {
-        query = query.Joins("JOIN product_categories ON products.id = product_categories.product_id").
-            Joins("JOIN categories ON product_categories.category_id = categories.id").
-            Where("categories.slug = ?", filter.Category)
+        // Include products filed under any of the category's subcategories
+        query = query.Where("products.id IN (SELECT product_id FROM product_categories WHERE category_id IN (?))",
+            r.db.Raw(categoryTreeBySlugSQL, filter.Category))
     }
     
     if filter.MinPrice != "" {
@@ -68,7 +97,24 @@ func (r *ProductsRepo) List(ctx context.Context, filter models.ProductFilter) ([
     }
     
     if filter.InStock != nil && *filter.InStock {
-        query = query.Where("stock_quantity > 0")
+        // Bundles have no stock of their own; their availability comes from their components.
+        // Gift cards are never out of stock.
+        query = query.Where("type IN ? OR stock_quantity > 0", []string{models.ProductBundle, models.ProductGiftCard})
+    }
+    
+    // Every option has to be on the same variant, ignoring case
+    if len(filter.Options) > 0 {
+        variants := r.db.Table("product_variants v").Select("1").Where("v.product_id = products.id")
+        for _, option := range filter.Options {
+            axis, value, ok := strings.Cut(option, ":")
+            if !ok {
+                continue
+            }
+            variants = variants.Where(
+                "EXISTS (SELECT 1 FROM jsonb_each_text(v.options) o WHERE LOWER(o.key) = LOWER(?) AND LOWER(o.value) = LOWER(?))",
+                strings.TrimSpace(axis), strings.TrimSpace(value))
+        }
+        query = query.Where("EXISTS (?)", variants)
     }
     
     // Count total records
@@ -92,7 +138,11 @@ func (r *ProductsRepo) List(ctx context.Context, filter models.ProductFilter) ([
     
     if err := query.
         Preload("Categories").
+        Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
         Preload("Variants").
+        Preload("Components").
+        Preload("Components.Product").
+        Preload("Components.Variant").
         Order("created_at DESC").
         Offset(offset).
         Limit(pageSize).
@@ -109,7 +159,9 @@ func (r *ProductsRepo) Update(ctx context.Context, id string, product *models.Pr
         return err
     }
     
-    return r.db.WithContext(ctx).Model(&models.Product{}).Where("id = ?", uuid).Updates(product).Error
+    // Stock only changes through the stock ledger
+    return r.db.WithContext(ctx).Model(&models.Product{}).Where("id = ?", uuid).
+        Omit("stock_quantity", "Components").Updates(product).Error
 }
 
 func (r *ProductsRepo) Delete(ctx context.Context, id string) error {
@@ -121,15 +173,94 @@ func (r *ProductsRepo) Delete(ctx context.Context, id string) error {
     return r.db.WithContext(ctx).Delete(&models.Product{}, uuid).Error
 }
 
-func (r *ProductsRepo) UpdateStock(ctx context.Context, id string, quantity int) error {
-    uuid, err := uuid.Parse(id)
-    if err != nil {
-        return err
-    }
-    
-    return r.db.WithContext(ctx).
-        Model(&models.Product{}).
-        Where("id = ?", uuid).
-        Update("stock_quantity", gorm.Expr("stock_quantity - ?", quantity)).
-        Error
-}
\ No newline at end of file
+// ErrVariantOrdered is returned when removing a variant that orders refer to
+var ErrVariantOrdered = errors.New("variant has been ordered and can't be removed")
+
+// ReplaceVariants implements the ReplaceVariants method of the Products interface
+func (r *ProductsRepo) ReplaceVariants(ctx context.Context, productID uuid.UUID, options []models.ProductOption, variants []models.ProductVariant) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("product_id = ?", productID).Delete(&models.ProductOption{}).Error; err != nil {
+            return err
+        }
+        if len(options) > 0 {
+            if err := tx.Create(&options).Error; err != nil {
+                return err
+            }
+        }
+        
+        var keep []uuid.UUID
+        for _, variant := range variants {
+            if variant.ID != uuid.Nil {
+                keep = append(keep, variant.ID)
+            }
+        }
+        removed := tx.Model(&models.ProductVariant{}).Select("id").Where("product_id = ?", productID)
+        if len(keep) > 0 {
+            removed = removed.Where("id NOT IN ?", keep)
+        }
+        
+        // Orders keep pointing at their variants, so those can't go
+        var ordered int64
+        if err := tx.Model(&models.OrderItem{}).Where("variant_id IN (?)", removed).Count(&ordered).Error; err != nil {
+            return err
+        }
+        if ordered > 0 {
+            return ErrVariantOrdered
+        }
+        if err := tx.Where("variant_id IN (?)", removed).Delete(&models.CartItem{}).Error; err != nil {
+            return err
+        }
+        if err := tx.Where("id IN (?)", removed).Delete(&models.ProductVariant{}).Error; err != nil {
+            return err
+        }
+        
+        // Stock only changes through the stock ledger, so new variants start with none
+        for i := range variants {
+            variant := &variants[i]
+            variant.ProductID = productID
+            if variant.ID == uuid.Nil {
+                variant.StockQuantity = 0
+                if err := tx.Create(variant).Error; err != nil {
+                    return err
+                }
+                continue
+            }
+            if err := tx.Omit("stock_quantity", "created_at").Save(variant).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+// UpdateVariant implements the UpdateVariant method of the Products interface
+func (r *ProductsRepo) UpdateVariant(ctx context.Context, variant *models.ProductVariant) error {
+    return r.db.WithContext(ctx).Omit("stock_quantity").Save(variant).Error
+}
+
+// ReplaceComponents implements the ReplaceComponents method of the Products interface
+func (r *ProductsRepo) ReplaceComponents(ctx context.Context, bundleID uuid.UUID, components []models.BundleComponent) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("bundle_id = ?", bundleID).Delete(&models.BundleComponent{}).Error; err != nil {
+            return err
+        }
+        for i := range components {
+            components[i].BundleID = bundleID
+        }
+        if len(components) == 0 {
+            return nil
+        }
+        // The components' products are saved on their own, not through the bundle
+        return tx.Omit(clause.Associations).Create(&components).Error
+    })
+}
+
+// CountBundlesWith implements the CountBundlesWith method of the Products interface
+func (r *ProductsRepo) CountBundlesWith(ctx context.Context, productID uuid.UUID) (int64, error) {
+    var count int64
+    err := r.db.WithContext(ctx).Model(&models.BundleComponent{}).
+        Where("product_id = ?", productID).
+        Distinct("bundle_id").
+        Count(&count).Error
+    return count, err
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// categoryTreeSQL selects categories and every category nested under them
+const categoryTreeSQL = `
+	WITH RECURSIVE tree AS (
+		SELECT id FROM categories WHERE id IN ?
+		UNION
+		SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+	)
+	SELECT id FROM tree`
+
+// PromotionsRepo implements the Promotions interface
+type PromotionsRepo struct {
+	db *gorm.DB
+}
+
+// NewPromotionsRepo creates a new PromotionsRepo
+func NewPromotionsRepo(db *gorm.DB) Promotions {
+	return &PromotionsRepo{
+		db: db,
+	}
+}
+
+// Create implements the Create method of the Promotions interface
+func (r *PromotionsRepo) Create(ctx context.Context, promotion *models.Promotion) error {
+	return r.db.WithContext(ctx).Create(promotion).Error
+}
+
+// GetByID implements the GetByID method of the Promotions interface
+func (r *PromotionsRepo) GetByID(ctx context.Context, id string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := r.db.WithContext(ctx).First(&promotion, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// GetByCode implements the GetByCode method of the Promotions interface
+func (r *PromotionsRepo) GetByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := r.db.WithContext(ctx).
+		Where("UPPER(code) = ?", strings.ToUpper(code)).
+		First(&promotion).Error; err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// List implements the List method of the Promotions interface
+func (r *PromotionsRepo) List(ctx context.Context) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	err := r.db.WithContext(ctx).Order("priority DESC, created_at DESC").Find(&promotions).Error
+	return promotions, err
+}
+
+// ListAutomatic implements the ListAutomatic method of the Promotions interface
+func (r *PromotionsRepo) ListAutomatic(ctx context.Context, at time.Time) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	err := r.db.WithContext(ctx).
+		Where("active AND code IS NULL").
+		Where("starts_at IS NULL OR starts_at <= ?", at).
+		Where("ends_at IS NULL OR ends_at > ?", at).
+		Order("priority DESC, created_at").
+		Find(&promotions).Error
+	return promotions, err
+}
+
+// Update implements the Update method of the Promotions interface
+func (r *PromotionsRepo) Update(ctx context.Context, promotion *models.Promotion) error {
+	// Usage is only ever changed by Redeem and Release
+	return r.db.WithContext(ctx).Omit("usage_count").Save(promotion).Error
+}
+
+// Delete implements the Delete method of the Promotions interface
+func (r *PromotionsRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&models.Promotion{}, "id = ?", id).Error
+}
+
+// Redeem implements the Redeem method of the Promotions interface
+func (r *PromotionsRepo) Redeem(ctx context.Context, id uuid.UUID) (bool, error) {
+	// Checking the limit in the same statement keeps concurrent checkouts from overshooting it
+	result := r.db.WithContext(ctx).Model(&models.Promotion{}).
+		Where("id = ? AND (usage_limit = 0 OR usage_count < usage_limit)", id).
+		Update("usage_count", gorm.Expr("usage_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Release implements the Release method of the Promotions interface
+func (r *PromotionsRepo) Release(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.Promotion{}).
+		Where("id = ? AND usage_count > 0", id).
+		Update("usage_count", gorm.Expr("usage_count - 1")).Error
+}
+
+// CountUserRedemptions implements the CountUserRedemptions method of the Promotions interface
+func (r *PromotionsRepo) CountUserRedemptions(ctx context.Context, promotionID uuid.UUID, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.PromotionRedemption{}).
+		Where("promotion_id = ? AND user_id = ?", promotionID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// ExpandCategories implements the ExpandCategories method of the Promotions interface
+func (r *PromotionsRepo) ExpandCategories(ctx context.Context, categoryIDs []string) ([]string, error) {
+	if len(categoryIDs) == 0 {
+		return nil, nil
+	}
+
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).Raw(categoryTreeSQL, categoryIDs).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+
+	expanded := make([]string, len(ids))
+	for i, id := range ids {
+		expanded[i] = id.String()
+	}
+	return expanded, nil
+}
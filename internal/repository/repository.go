@@ -2,9 +2,12 @@ package repository
 
 import (
     "context"
+    "time"
     
     "github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 ) 
 
 // PostRepository defines the interface for post data access
@@ -14,6 +17,13 @@ type PostRepository interface {
 	GetAll(ctx context.Context, page, limit int) ([]*models.Post, error)
 	GetByCategory(ctx context.Context, category string, page, limit int) ([]*models.Post, error)
 	GetByAuthor(ctx context.Context, authorID string, page, limit int) ([]*models.Post, error)
+	// GetByLocale lists one post per translation group in locale, falling back to the
+	// fallback locale for groups without a translation, and returns the total count
+	GetByLocale(ctx context.Context, locale, fallback string, query models.PostQuery) ([]*models.Post, int64, error)
+	// Find lists the posts matching query, newest first, and returns the total count
+	Find(ctx context.Context, query models.PostQuery) ([]*models.Post, int64, error)
+	GetTranslations(ctx context.Context, groupID string) ([]*models.Post, error)
+	GetByStatus(ctx context.Context, status string, page, limit int) ([]*models.Post, int64, error)
 	Update(ctx context.Context, post *models.Post) error
 	Delete(ctx context.Context, id string) error
 	Count(ctx context.Context) (int64, error)
@@ -38,6 +48,10 @@ type Posts interface {
     Create(ctx context.Context, post *models.Post) error
     GetByID(ctx context.Context, id string) (*models.Post, error)
     List(ctx context.Context, filter models.PostFilter) ([]models.Post, int64, error)
+    Find(ctx context.Context, query models.PostQuery) ([]models.Post, int64, error)
+    // FindLocalized is Find keeping one post per translation group, in locale where
+    // there is one and in fallback otherwise
+    FindLocalized(ctx context.Context, locale, fallback string, query models.PostQuery) ([]models.Post, int64, error)
     Update(ctx context.Context, id string, post *models.Post) error
     Delete(ctx context.Context, id string) error
 }
@@ -45,10 +59,20 @@ type Posts interface {
 type Products interface {
     Create(ctx context.Context, product *models.Product) error
     GetByID(ctx context.Context, id string) (*models.Product, error)
+    GetBySlugs(ctx context.Context, slugs []string) ([]models.Product, error)
     List(ctx context.Context, filter models.ProductFilter) ([]models.Product, int64, error)
+    // Update saves the product's details; its stock only changes through the StockLedger
     Update(ctx context.Context, id string, product *models.Product) error
     Delete(ctx context.Context, id string) error
-    UpdateStock(ctx context.Context, id string, quantity int) error
+    // ReplaceVariants replaces a product's options and saves its variants, removing the
+    // ones not among them; it returns ErrVariantOrdered if an order refers to one of those
+    ReplaceVariants(ctx context.Context, productID uuid.UUID, options []models.ProductOption, variants []models.ProductVariant) error
+    // UpdateVariant saves a variant's details but not its stock
+    UpdateVariant(ctx context.Context, variant *models.ProductVariant) error
+    // ReplaceComponents replaces what a bundle is made of
+    ReplaceComponents(ctx context.Context, bundleID uuid.UUID, components []models.BundleComponent) error
+    // CountBundlesWith counts the bundles a product is a component of
+    CountBundlesWith(ctx context.Context, productID uuid.UUID) (int64, error)
 }
 
 type CartItems interface {
@@ -65,14 +89,213 @@ type Orders interface {
     GetByID(ctx context.Context, id string) (*models.Order, error)
     List(ctx context.Context, filter models.OrderFilter) ([]models.Order, int64, error)
     Update(ctx context.Context, id string, order *models.Order) error
+    // CountByUser counts the user's orders that weren't canceled
+    CountByUser(ctx context.Context, userID string) (int64, error)
+    // CancelPending cancels an order still awaiting payment, returning false if it isn't
+    CancelPending(ctx context.Context, id uuid.UUID) (bool, error)
+    // MarkPaid moves an order awaiting payment on to processing, returning false if it isn't
+    MarkPaid(ctx context.Context, id uuid.UUID) (bool, error)
+    // ProductRevenue totals what products and variants sold for in the base currency,
+    // counting bundles by the revenue allocated to their components
+    ProductRevenue(ctx context.Context, filter models.ProductRevenueFilter) ([]models.ProductRevenueRow, error)
+}
+
+// ReviewComments stores reviewer notes on post submissions
+type ReviewComments interface {
+    Create(ctx context.Context, comment *models.ReviewComment) error
+    ListByPost(ctx context.Context, postID string) ([]models.ReviewComment, error)
+    DeleteByPost(ctx context.Context, postID string) error
+}
+
+// Attribution stores post-to-product click-throughs and the orders they lead to
+type Attribution interface {
+    CreateClick(ctx context.Context, click *models.PostProductClick) error
+    // LatestClicks returns the most recent click on each product by the visitor or user since a time
+    LatestClicks(ctx context.Context, visitorID, userID string, productIDs []string, since time.Time) ([]models.PostProductClick, error)
+    CreateConversions(ctx context.Context, conversions []models.PostConversion) error
+    // PostStats totals clicks and conversions by post, leaving titles to the post store
+    PostStats(ctx context.Context, since time.Time, limit int) ([]models.PostAttributionStats, error)
+    PostProductStats(ctx context.Context, postID string, since time.Time) ([]models.ProductAttributionStats, error)
+}
+
+// Series reads the ordered series posts belong to
+type Series interface {
+    // ByPost returns the series a post is in with its posts in order, or nil if it
+    // isn't in one
+    ByPost(ctx context.Context, postID string) (*models.SeriesPosts, error)
+}
+
+// SlugRedirects stores the previous slugs of posts, products and categories
+type SlugRedirects interface {
+    // Record keeps oldSlug as a redirect to the entity when its slug changes to newSlug
+    Record(ctx context.Context, entityType, entityID, oldSlug, newSlug string) error
+}
+
+// Subscribers stores newsletter subscribers
+type Subscribers interface {
+    Create(ctx context.Context, subscriber *models.Subscriber) error
+    GetByEmail(ctx context.Context, email string) (*models.Subscriber, error)
+    GetByConfirmToken(ctx context.Context, token string) (*models.Subscriber, error)
+    GetByUnsubscribeToken(ctx context.Context, token string) (*models.Subscriber, error)
+    Update(ctx context.Context, subscriber *models.Subscriber) error
+    // ListDueForDigest returns active subscribers whose last digest went out before a time
+    ListDueForDigest(ctx context.Context, lastSentBefore time.Time) ([]models.Subscriber, error)
+    List(ctx context.Context) ([]models.Subscriber, error)
+    Stats(ctx context.Context) (*models.SubscriberStats, error)
+}
+
+// Promotions stores coupon codes and automatic promotions and tracks their use
+type Promotions interface {
+    Create(ctx context.Context, promotion *models.Promotion) error
+    GetByID(ctx context.Context, id string) (*models.Promotion, error)
+    // GetByCode looks a coupon up by its code, ignoring case
+    GetByCode(ctx context.Context, code string) (*models.Promotion, error)
+    List(ctx context.Context) ([]models.Promotion, error)
+    // ListAutomatic returns the active promotions without a code that run at a time, by priority
+    ListAutomatic(ctx context.Context, at time.Time) ([]models.Promotion, error)
+    Update(ctx context.Context, promotion *models.Promotion) error
+    Delete(ctx context.Context, id string) error
+    // Redeem counts one use of a promotion, returning false if its usage limit is reached
+    Redeem(ctx context.Context, id uuid.UUID) (bool, error)
+    // Release gives back a use counted by Redeem
+    Release(ctx context.Context, id uuid.UUID) error
+    CountUserRedemptions(ctx context.Context, promotionID uuid.UUID, userID string) (int64, error)
+    // ExpandCategories returns the categories with their subcategories
+    ExpandCategories(ctx context.Context, categoryIDs []string) ([]string, error)
+}
+
+// TaxZones stores the regions tax is charged in and their rates
+type TaxZones interface {
+    Create(ctx context.Context, zone *models.TaxZone) error
+    GetByID(ctx context.Context, id string) (*models.TaxZone, error)
+    List(ctx context.Context) ([]models.TaxZone, error)
+    // Update saves the zone and replaces its rates with zone.Rates
+    Update(ctx context.Context, zone *models.TaxZone) error
+    Delete(ctx context.Context, id string) error
+    // Match returns the most specific zone covering an address, or nil if none does
+    Match(ctx context.Context, country, region string) (*models.TaxZone, error)
+    // Report totals the tax on orders by zone and period, converted to the base currency
+    Report(ctx context.Context, filter models.TaxReportFilter) ([]models.TaxReportRow, error)
+}
+
+// Shipping stores the zones orders ship to and the methods available in each
+type Shipping interface {
+    CreateZone(ctx context.Context, zone *models.ShippingZone) error
+    GetZone(ctx context.Context, id string) (*models.ShippingZone, error)
+    // ListZones returns every zone with its methods in display order
+    ListZones(ctx context.Context) ([]models.ShippingZone, error)
+    UpdateZone(ctx context.Context, zone *models.ShippingZone) error
+    DeleteZone(ctx context.Context, id string) error
+    CreateMethod(ctx context.Context, method *models.ShippingMethod) error
+    GetMethod(ctx context.Context, id string) (*models.ShippingMethod, error)
+    UpdateMethod(ctx context.Context, method *models.ShippingMethod) error
+    DeleteMethod(ctx context.Context, id string) error
+}
+
+// Currencies stores the currencies the shop sells in and per-currency price overrides
+type Currencies interface {
+    Create(ctx context.Context, currency *models.Currency) error
+    GetByCode(ctx context.Context, code string) (*models.Currency, error)
+    List(ctx context.Context, activeOnly bool) ([]models.Currency, error)
+    Update(ctx context.Context, currency *models.Currency) error
+    // Delete removes a currency and its price overrides
+    Delete(ctx context.Context, code string) error
+    // SetProductPrice replaces a product's or variant's price override in a currency
+    SetProductPrice(ctx context.Context, price *models.ProductPrice) error
+    DeleteProductPrice(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, currency string) error
+    // ProductPrices returns the overrides in a currency for the products and their variants
+    ProductPrices(ctx context.Context, productIDs []uuid.UUID, currency string) ([]models.ProductPrice, error)
+}
+
+// StockReservations holds stock for orders between checkout and payment
+type StockReservations interface {
+    // Reserve saves the reservations if there's enough available stock for all of them,
+    // and returns false without saving any if there isn't
+    Reserve(ctx context.Context, reservations []models.StockReservation) (bool, error)
+    // Reserved totals the stock active reservations hold of the products and their variants
+    Reserved(ctx context.Context, productIDs []uuid.UUID) ([]models.ReservedStock, error)
+    // Convert takes an order's active reservations off stock on hand as sales in the stock
+    // ledger, whether or not they've expired
+    Convert(ctx context.Context, orderID uuid.UUID) error
+    // Release gives an order's active reservations back to available stock
+    Release(ctx context.Context, orderID uuid.UUID) error
+    // ExpiredOrders returns the IDs of the orders with active reservations that expired by now
+    ExpiredOrders(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+}
+
+// StockLedger changes stock on hand and keeps an immutable record of every change
+type StockLedger interface {
+    // Record applies the movements' changes and saves them to the ledger, or returns false
+    // without applying any if one would take stock below zero
+    Record(ctx context.Context, movements []models.StockMovement) (bool, error)
+    // Count sets stock on hand to a counted level, recording the difference as movement
+    Count(ctx context.Context, movement *models.StockMovement, counted int) error
+    // History lists a SKU's ledger entries, newest first, and returns the total count
+    History(ctx context.Context, filter models.StockLedgerFilter) ([]models.StockMovement, int64, error)
+}
+
+// Subscriptions stores customers' recurring orders
+type Subscriptions interface {
+    Create(ctx context.Context, subscription *models.Subscription) error
+    GetByID(ctx context.Context, id string) (*models.Subscription, error)
+    ListByUser(ctx context.Context, userID string) ([]models.Subscription, error)
+    Update(ctx context.Context, subscription *models.Subscription) error
+    // ListDue lists the active subscriptions due a renewal order, or a retry of one, by now
+    ListDue(ctx context.Context, now time.Time) ([]models.Subscription, error)
+    // ListAwaitingPayment lists the subscriptions whose renewal order hasn't been paid for yet
+    ListAwaitingPayment(ctx context.Context) ([]models.Subscription, error)
+    // ListToRemind lists the active subscriptions renewing by before whose customers
+    // haven't been reminded yet
+    ListToRemind(ctx context.Context, before time.Time) ([]models.Subscription, error)
+    // GetPaymentMethod returns one of the user's saved payment methods
+    GetPaymentMethod(ctx context.Context, userID, id uuid.UUID) (*models.PaymentMethod, error)
+}
+
+// Credits stores gift cards and store credit balances, and keeps an immutable ledger of
+// every change to them
+type Credits interface {
+    // CreateGiftCard saves a gift card with the ledger entry issuing its balance
+    CreateGiftCard(ctx context.Context, card *models.GiftCard, entry *models.CreditTransaction) error
+    GetGiftCardByCode(ctx context.Context, code string) (*models.GiftCard, error)
+    // GetStoreCredit returns a customer's store credit, with a zero balance if they have none
+    GetStoreCredit(ctx context.Context, userID uuid.UUID) (*models.StoreCredit, error)
+    // Record applies the entries' changes and saves them to the ledger, or returns false
+    // without applying any if one would take a balance below zero
+    Record(ctx context.Context, entries []models.CreditTransaction) (bool, error)
+    // Redeem adds applied to a pending order's credit and records the redemptions paying
+    // for it, or returns false without changing anything if the order is no longer pending,
+    // the credit would come to more than its total or a balance doesn't cover its entry
+    Redeem(ctx context.Context, orderID uuid.UUID, applied float64, entries []models.CreditTransaction) (bool, error)
+    // ActivateOrderGiftCards makes the gift cards bought in an order usable once it's paid for
+    ActivateOrderGiftCards(ctx context.Context, orderID uuid.UUID) error
+    // ReleaseOrder refunds the credit a canceled order took and voids the gift cards bought in it
+    ReleaseOrder(ctx context.Context, orderID uuid.UUID, actorID *uuid.UUID, reason string) error
+    // History lists a gift card's or customer's ledger entries, newest first, and returns
+    // the total count
+    History(ctx context.Context, filter models.CreditLedgerFilter) ([]models.CreditTransaction, int64, error)
 }
 
 type Repository struct {
-    Users     Users
-    Posts     Posts
-    Products  Products
-    CartItems CartItems
-    Orders    Orders
+    Users          Users
+    Posts          Posts
+    ReviewComments ReviewComments
+    Products       Products
+    CartItems      CartItems
+    Orders         Orders
+    Attribution    Attribution
+    SlugRedirects  SlugRedirects
+    Series         Series
+    Subscribers    Subscribers
+    Promotions     Promotions
+    TaxZones       TaxZones
+    Shipping       Shipping
+    Currencies     Currencies
+    Reservations   StockReservations
+    StockLedger    StockLedger
+    Subscriptions  Subscriptions
+    Credits        Credits
+    // PostStore overrides where posts are kept; nil uses the Posts table
+    PostStore PostRepository
 }
 
 // TokenRepository handles token data storage operations
@@ -147,9 +370,90 @@ func (r *PostsRepo) List(ctx context.Context, filter models.PostFilter) ([]model
     return posts, count, nil
 }
 
+// Find implements the Find method of the Posts interface
+func (r *PostsRepo) Find(ctx context.Context, query models.PostQuery) ([]models.Post, int64, error) {
+    db := r.db.WithContext(ctx).Model(&models.Post{}).Scopes(postQueryScope(query))
+    return pagePostQuery(db, query)
+}
+
+// FindLocalized implements the FindLocalized method of the Posts interface
+func (r *PostsRepo) FindLocalized(ctx context.Context, locale, fallback string, query models.PostQuery) ([]models.Post, int64, error) {
+    query.Locales = []string{locale, fallback}
+
+    // Keep the preferred version of each translation group; posts that were never
+    // translated are a group of their own
+    group := "COALESCE(NULLIF(translation_group_id, ''), id::text)"
+    localized := r.db.WithContext(ctx).Model(&models.Post{}).Scopes(postQueryScope(query)).
+        Select("DISTINCT ON (" + group + ") *").
+        Order(clause.OrderBy{Expression: clause.Expr{
+            SQL:  group + ", CASE WHEN locale = ? THEN 0 ELSE 1 END",
+            Vars: []interface{}{locale},
+        }})
+
+    return pagePostQuery(r.db.WithContext(ctx).Table("(?) AS posts", localized), query)
+}
+
+// postQueryScope applies the filters of a post query
+func postQueryScope(query models.PostQuery) func(*gorm.DB) *gorm.DB {
+    return func(db *gorm.DB) *gorm.DB {
+        if query.Category != "" {
+            db = db.Where("? = ANY(categories)", query.Category)
+        }
+        if query.Tag != "" {
+            db = db.Where("? = ANY(tags)", query.Tag)
+        }
+        if query.AuthorID != "" {
+            db = db.Where("author_id = ?", query.AuthorID)
+        }
+        if query.Search != "" {
+            db = db.Where("to_tsvector('english', title || ' ' || coalesce(excerpt, '') || ' ' || content) @@ plainto_tsquery('english', ?)", query.Search)
+        }
+        if query.Published != nil {
+            db = db.Where("published = ?", *query.Published)
+        }
+        if query.PublishedAfter != nil {
+            db = db.Where("published_at >= ?", *query.PublishedAfter)
+        }
+        if query.Status != "" {
+            db = db.Where("status = ?", query.Status)
+        }
+        if query.TranslationGroupID != "" {
+            db = db.Where("translation_group_id = ?", query.TranslationGroupID)
+        }
+        if len(query.IDs) > 0 {
+            db = db.Where("id IN ?", query.IDs)
+        }
+        if len(query.Locales) > 0 {
+            db = db.Where("locale IN ?", query.Locales)
+        }
+        return db
+    }
+}
+
+// pagePostQuery counts the posts a query selects and loads its page, newest first
+func pagePostQuery(db *gorm.DB, query models.PostQuery) ([]models.Post, int64, error) {
+    var total int64
+    if err := db.Count(&total).Error; err != nil {
+        return nil, 0, err
+    }
+
+    if query.Page > 0 && query.Limit > 0 {
+        db = db.Offset((query.Page - 1) * query.Limit).Limit(query.Limit)
+    }
+
+    var posts []models.Post
+    if err := db.Order("created_at DESC").Find(&posts).Error; err != nil {
+        return nil, 0, err
+    }
+
+    return posts, total, nil
+}
+
 // Update implements the Update method of the Posts interface
 func (r *PostsRepo) Update(ctx context.Context, id string, post *models.Post) error {
-    return r.db.WithContext(ctx).Model(&models.Post{}).Where("id = ?", id).Updates(post).Error
+    // Select all columns so zero values such as published = false are written too
+    return r.db.WithContext(ctx).Model(&models.Post{}).Where("id = ?", id).
+        Select("*").Omit("id", "created_at").Updates(post).Error
 }
 
 // Delete implements the Delete method of the Posts interface
@@ -159,11 +463,24 @@ func (r *PostsRepo) Delete(ctx context.Context, id string) error {
 
 func NewRepository(db *gorm.DB) *Repository {
     return &Repository{
-        Users:     NewUsersRepo(db),
-        Posts:     NewPostsRepo(db),
-        Products:  NewProductsRepo(db),
-        CartItems: NewCartItemsRepo(db),
-        Orders:    NewOrdersRepo(db),
+        Users:          NewUsersRepo(db),
+        Posts:          NewPostsRepo(db),
+        ReviewComments: NewReviewCommentsRepo(db),
+        Products:       NewProductsRepo(db),
+        CartItems:      NewCartItemsRepo(db),
+        Orders:         NewOrdersRepo(db),
+        Attribution:    NewAttributionRepo(db),
+        SlugRedirects:  NewSlugRedirectsRepo(db),
+        Series:         NewSeriesRepo(db),
+        Subscribers:    NewSubscribersRepo(db),
+        Promotions:     NewPromotionsRepo(db),
+        TaxZones:       NewTaxZonesRepo(db),
+        Shipping:       NewShippingRepo(db),
+        Currencies:     NewCurrenciesRepo(db),
+        Reservations:   NewStockReservationsRepo(db),
+        StockLedger:    NewStockLedgerRepo(db),
+        Subscriptions:  NewSubscriptionsRepo(db),
+        Credits:        NewCreditsRepo(db),
     }
 }
 
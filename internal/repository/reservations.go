@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StockReservationsRepo implements the StockReservations interface
+type StockReservationsRepo struct {
+	db *gorm.DB
+}
+
+// NewStockReservationsRepo creates a new StockReservationsRepo
+func NewStockReservationsRepo(db *gorm.DB) StockReservations {
+	return &StockReservationsRepo{
+		db: db,
+	}
+}
+
+// Reserve implements the Reserve method of the StockReservations interface
+func (r *StockReservationsRepo) Reserve(ctx context.Context, reservations []models.StockReservation) (bool, error) {
+	// Lock rows in a fixed order so two checkouts of the same products can't deadlock
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservationTarget(reservations[i]) < reservationTarget(reservations[j])
+	})
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for i := range reservations {
+			reservation := &reservations[i]
+
+			// Locking the product or variant row makes concurrent checkouts wait for
+			// this one's reservations before working out what's left
+			var onHand int
+			var err error
+			if reservation.VariantID == nil {
+				err = tx.Raw("SELECT stock_quantity FROM products WHERE id = ? FOR UPDATE", reservation.ProductID).Row().Scan(&onHand)
+			} else {
+				err = tx.Raw("SELECT stock_quantity FROM product_variants WHERE id = ? AND product_id = ? FOR UPDATE",
+					*reservation.VariantID, reservation.ProductID).Row().Scan(&onHand)
+			}
+			if err != nil {
+				return err
+			}
+
+			var held int
+			if err := activeReservations(tx.Model(&models.StockReservation{}), now).
+				Scopes(reservedItem(reservation.ProductID, reservation.VariantID)).
+				Select("COALESCE(SUM(quantity), 0)").Row().Scan(&held); err != nil {
+				return err
+			}
+			if onHand-held < reservation.Quantity {
+				return errStockShort
+			}
+
+			reservation.Status = models.ReservationActive
+			if err := tx.Create(reservation).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errStockShort) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Reserved implements the Reserved method of the StockReservations interface
+func (r *StockReservationsRepo) Reserved(ctx context.Context, productIDs []uuid.UUID) ([]models.ReservedStock, error) {
+	var reserved []models.ReservedStock
+	if len(productIDs) == 0 {
+		return reserved, nil
+	}
+	err := activeReservations(r.db.WithContext(ctx).Model(&models.StockReservation{}), time.Now()).
+		Select("product_id, variant_id, SUM(quantity) AS quantity").
+		Where("product_id IN ?", productIDs).
+		Group("product_id, variant_id").
+		Scan(&reserved).Error
+	return reserved, err
+}
+
+// Convert implements the Convert method of the StockReservations interface
+func (r *StockReservationsRepo) Convert(ctx context.Context, orderID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Claiming the reservations in the same statement keeps two payments of one
+		// order from taking the stock twice
+		var reservations []models.StockReservation
+		if err := tx.Raw(`
+			UPDATE stock_reservations SET status = ?, updated_at = ?
+			WHERE order_id = ? AND status = ?
+			RETURNING *
+		`, models.ReservationConverted, time.Now(), orderID, models.ReservationActive).
+			Scan(&reservations).Error; err != nil {
+			return err
+		}
+
+		for _, reservation := range reservations {
+			orderID := reservation.OrderID
+			if err := recordStockMovement(tx, &models.StockMovement{
+				ProductID: reservation.ProductID,
+				VariantID: reservation.VariantID,
+				Type:      models.StockSale,
+				Quantity:  -reservation.Quantity,
+				Reason:    "Order paid",
+				ActorID:   &reservation.UserID,
+				OrderID:   &orderID,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Release implements the Release method of the StockReservations interface
+func (r *StockReservationsRepo) Release(ctx context.Context, orderID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.StockReservation{}).
+		Where("order_id = ? AND status = ?", orderID, models.ReservationActive).
+		Update("status", models.ReservationReleased).Error
+}
+
+// ExpiredOrders implements the ExpiredOrders method of the StockReservations interface
+func (r *StockReservationsRepo) ExpiredOrders(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var orderIDs []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&models.StockReservation{}).
+		Distinct("order_id").
+		Where("status = ? AND expires_at <= ?", models.ReservationActive, now).
+		Pluck("order_id", &orderIDs).Error
+	return orderIDs, err
+}
+
+// errStockShort rolls back a change that would take more than the available stock
+var errStockShort = errors.New("not enough stock")
+
+// activeReservations selects the reservations still holding stock at a time
+func activeReservations(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("status = ? AND expires_at > ?", models.ReservationActive, now)
+}
+
+// reservedItem selects the reservations of a product, or of one of its variants
+func reservedItem(productID uuid.UUID, variantID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("product_id = ?", productID)
+		if variantID == nil {
+			return db.Where("variant_id IS NULL")
+		}
+		return db.Where("variant_id = ?", *variantID)
+	}
+}
+
+// reservationTarget identifies the product or variant row a reservation locks
+func reservationTarget(reservation models.StockReservation) string {
+	if reservation.VariantID == nil {
+		return "product/" + reservation.ProductID.String()
+	}
+	return "variant/" + reservation.VariantID.String()
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// ReviewCommentsRepo implements the ReviewComments interface
+type ReviewCommentsRepo struct {
+	db *gorm.DB
+}
+
+// NewReviewCommentsRepo creates a new ReviewCommentsRepo
+func NewReviewCommentsRepo(db *gorm.DB) ReviewComments {
+	return &ReviewCommentsRepo{
+		db: db,
+	}
+}
+
+// Create implements the Create method of the ReviewComments interface
+func (r *ReviewCommentsRepo) Create(ctx context.Context, comment *models.ReviewComment) error {
+	return r.db.WithContext(ctx).Create(comment).Error
+}
+
+// ListByPost implements the ListByPost method of the ReviewComments interface
+func (r *ReviewCommentsRepo) ListByPost(ctx context.Context, postID string) ([]models.ReviewComment, error) {
+	var comments []models.ReviewComment
+	err := r.db.WithContext(ctx).Where("post_id = ?", postID).Order("created_at ASC").Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// DeleteByPost implements the DeleteByPost method of the ReviewComments interface
+func (r *ReviewCommentsRepo) DeleteByPost(ctx context.Context, postID string) error {
+	return r.db.WithContext(ctx).Where("post_id = ?", postID).Delete(&models.ReviewComment{}).Error
+}
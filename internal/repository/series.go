@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// SeriesRepo implements the Series interface
+type SeriesRepo struct {
+	db *gorm.DB
+}
+
+// NewSeriesRepo creates a new SeriesRepo
+func NewSeriesRepo(db *gorm.DB) Series {
+	return &SeriesRepo{
+		db: db,
+	}
+}
+
+// ByPost implements the ByPost method of the Series interface
+func (r *SeriesRepo) ByPost(ctx context.Context, postID string) (*models.SeriesPosts, error) {
+	db := r.db.WithContext(ctx)
+
+	var series models.SeriesPosts
+	err := db.Table("series").
+		Select("series.id, series.slug, series.title").
+		Joins("JOIN series_posts ON series_posts.series_id = series.id").
+		Where("series_posts.post_id = ?", postID).
+		Take(&series).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Posts may live in another store, so only their IDs are read here
+	if err := db.Table("series_posts").
+		Where("series_id = ?", series.ID).
+		Order("position ASC").
+		Pluck("post_id::text", &series.PostIDs).Error; err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// ShippingRepo implements the Shipping interface
+type ShippingRepo struct {
+	db *gorm.DB
+}
+
+// NewShippingRepo creates a new ShippingRepo
+func NewShippingRepo(db *gorm.DB) Shipping {
+	return &ShippingRepo{
+		db: db,
+	}
+}
+
+// CreateZone implements the CreateZone method of the Shipping interface
+func (r *ShippingRepo) CreateZone(ctx context.Context, zone *models.ShippingZone) error {
+	return r.db.WithContext(ctx).Omit("Methods").Create(zone).Error
+}
+
+// GetZone implements the GetZone method of the Shipping interface
+func (r *ShippingRepo) GetZone(ctx context.Context, id string) (*models.ShippingZone, error) {
+	var zone models.ShippingZone
+	if err := r.db.WithContext(ctx).
+		Preload("Methods", func(db *gorm.DB) *gorm.DB { return db.Order("position, created_at") }).
+		First(&zone, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// ListZones implements the ListZones method of the Shipping interface
+func (r *ShippingRepo) ListZones(ctx context.Context) ([]models.ShippingZone, error) {
+	var zones []models.ShippingZone
+	err := r.db.WithContext(ctx).
+		Preload("Methods", func(db *gorm.DB) *gorm.DB { return db.Order("position, created_at") }).
+		Order("name").
+		Find(&zones).Error
+	return zones, err
+}
+
+// UpdateZone implements the UpdateZone method of the Shipping interface
+func (r *ShippingRepo) UpdateZone(ctx context.Context, zone *models.ShippingZone) error {
+	return r.db.WithContext(ctx).Omit("Methods").Save(zone).Error
+}
+
+// DeleteZone implements the DeleteZone method of the Shipping interface
+func (r *ShippingRepo) DeleteZone(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shipping_zone_id = ?", id).Delete(&models.ShippingMethod{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ShippingZone{}, "id = ?", id).Error
+	})
+}
+
+// CreateMethod implements the CreateMethod method of the Shipping interface
+func (r *ShippingRepo) CreateMethod(ctx context.Context, method *models.ShippingMethod) error {
+	return r.db.WithContext(ctx).Create(method).Error
+}
+
+// GetMethod implements the GetMethod method of the Shipping interface
+func (r *ShippingRepo) GetMethod(ctx context.Context, id string) (*models.ShippingMethod, error) {
+	var method models.ShippingMethod
+	if err := r.db.WithContext(ctx).First(&method, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+// UpdateMethod implements the UpdateMethod method of the Shipping interface
+func (r *ShippingRepo) UpdateMethod(ctx context.Context, method *models.ShippingMethod) error {
+	return r.db.WithContext(ctx).Save(method).Error
+}
+
+// DeleteMethod implements the DeleteMethod method of the Shipping interface
+func (r *ShippingRepo) DeleteMethod(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&models.ShippingMethod{}, "id = ?", id).Error
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SlugRedirectsRepo implements the SlugRedirects interface
+type SlugRedirectsRepo struct {
+	db *gorm.DB
+}
+
+// NewSlugRedirectsRepo creates a new SlugRedirectsRepo
+func NewSlugRedirectsRepo(db *gorm.DB) SlugRedirects {
+	return &SlugRedirectsRepo{
+		db: db,
+	}
+}
+
+// Record implements the Record method of the SlugRedirects interface
+func (r *SlugRedirectsRepo) Record(ctx context.Context, entityType, entityID, oldSlug, newSlug string) error {
+	if oldSlug == "" || oldSlug == newSlug {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A live slug always wins, so drop any redirect still pointing the new slug elsewhere
+		if err := tx.Where("entity_type = ? AND old_slug = ?", entityType, newSlug).
+			Delete(&models.SlugRedirect{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SlugRedirect{
+			EntityType: entityType,
+			EntityID:   entityID,
+			OldSlug:    oldSlug,
+		}).Error
+	})
+}
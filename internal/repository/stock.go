@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StockLedgerRepo implements the StockLedger interface
+type StockLedgerRepo struct {
+	db *gorm.DB
+}
+
+// NewStockLedgerRepo creates a new StockLedgerRepo
+func NewStockLedgerRepo(db *gorm.DB) StockLedger {
+	return &StockLedgerRepo{
+		db: db,
+	}
+}
+
+// Record implements the Record method of the StockLedger interface
+func (r *StockLedgerRepo) Record(ctx context.Context, movements []models.StockMovement) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range movements {
+			if err := recordStockMovement(tx, &movements[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errStockShort) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Count implements the Count method of the StockLedger interface
+func (r *StockLedgerRepo) Count(ctx context.Context, movement *models.StockMovement, counted int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the row so sales made during the count land before or after it, not in between
+		table, id := stockRow(movement)
+		var onHand int
+		if err := tx.Raw("SELECT stock_quantity FROM "+table+" WHERE id = ? FOR UPDATE", id).
+			Row().Scan(&onHand); err != nil {
+			return err
+		}
+
+		movement.Quantity = counted - onHand
+		return recordStockMovement(tx, movement)
+	})
+}
+
+// History implements the History method of the StockLedger interface
+func (r *StockLedgerRepo) History(ctx context.Context, filter models.StockLedgerFilter) ([]models.StockMovement, int64, error) {
+	db := r.db.WithContext(ctx)
+
+	// Look the SKU up rather than matching the SKU on entries, so the history of
+	// something whose SKU changed stays whole
+	query := db.Model(&models.StockMovement{})
+	var variant models.ProductVariant
+	err := db.Where("sku = ?", filter.SKU).Limit(1).Find(&variant).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if variant.ID != uuid.Nil {
+		query = query.Where("variant_id = ?", variant.ID)
+	} else {
+		var product models.Product
+		if err := db.Where("sku = ?", filter.SKU).First(&product).Error; err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("product_id = ? AND variant_id IS NULL", product.ID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page > 0 && filter.Limit > 0 {
+		query = query.Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit)
+	}
+
+	var movements []models.StockMovement
+	if err := query.Order("created_at DESC").Find(&movements).Error; err != nil {
+		return nil, 0, err
+	}
+	return movements, total, nil
+}
+
+// recordStockMovement applies a movement's change to stock on hand and saves it to the
+// ledger. The change is made in one conditional statement, so stock can't be taken
+// below zero however many checkouts run at once; errStockShort is returned if it would be.
+func recordStockMovement(tx *gorm.DB, movement *models.StockMovement) error {
+	table, id := stockRow(movement)
+	err := tx.Raw(`
+		UPDATE `+table+`
+		SET stock_quantity = stock_quantity + ?, updated_at = ?
+		WHERE id = ? AND stock_quantity + ? >= 0
+		RETURNING stock_quantity, COALESCE(sku, '')
+	`, movement.Quantity, time.Now(), id, movement.Quantity).Row().Scan(&movement.Balance, &movement.SKU)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errStockShort
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Create(movement).Error
+}
+
+// stockRow returns the table and ID of the row holding a movement's stock
+func stockRow(movement *models.StockMovement) (string, uuid.UUID) {
+	if movement.VariantID != nil {
+		return "product_variants", *movement.VariantID
+	}
+	return "products", movement.ProductID
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// SubscribersRepo implements the Subscribers interface
+type SubscribersRepo struct {
+	db *gorm.DB
+}
+
+// NewSubscribersRepo creates a new SubscribersRepo
+func NewSubscribersRepo(db *gorm.DB) Subscribers {
+	return &SubscribersRepo{
+		db: db,
+	}
+}
+
+// Create implements the Create method of the Subscribers interface
+func (r *SubscribersRepo) Create(ctx context.Context, subscriber *models.Subscriber) error {
+	return r.db.WithContext(ctx).Create(subscriber).Error
+}
+
+// GetByEmail implements the GetByEmail method of the Subscribers interface
+func (r *SubscribersRepo) GetByEmail(ctx context.Context, email string) (*models.Subscriber, error) {
+	return r.getBy(ctx, "email = ?", email)
+}
+
+// GetByConfirmToken implements the GetByConfirmToken method of the Subscribers interface
+func (r *SubscribersRepo) GetByConfirmToken(ctx context.Context, token string) (*models.Subscriber, error) {
+	return r.getBy(ctx, "confirm_token = ?", token)
+}
+
+// GetByUnsubscribeToken implements the GetByUnsubscribeToken method of the Subscribers interface
+func (r *SubscribersRepo) GetByUnsubscribeToken(ctx context.Context, token string) (*models.Subscriber, error) {
+	return r.getBy(ctx, "unsubscribe_token = ?", token)
+}
+
+// getBy loads the subscriber matching condition
+func (r *SubscribersRepo) getBy(ctx context.Context, condition, value string) (*models.Subscriber, error) {
+	var subscriber models.Subscriber
+	if err := r.db.WithContext(ctx).Where(condition, value).First(&subscriber).Error; err != nil {
+		return nil, err
+	}
+	return &subscriber, nil
+}
+
+// Update implements the Update method of the Subscribers interface
+func (r *SubscribersRepo) Update(ctx context.Context, subscriber *models.Subscriber) error {
+	return r.db.WithContext(ctx).Save(subscriber).Error
+}
+
+// ListDueForDigest implements the ListDueForDigest method of the Subscribers interface
+func (r *SubscribersRepo) ListDueForDigest(ctx context.Context, lastSentBefore time.Time) ([]models.Subscriber, error) {
+	var subscribers []models.Subscriber
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND (last_digest_at IS NULL OR last_digest_at < ?)", models.SubscriberActive, lastSentBefore).
+		Order("created_at ASC").Find(&subscribers).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+// List implements the List method of the Subscribers interface
+func (r *SubscribersRepo) List(ctx context.Context) ([]models.Subscriber, error) {
+	var subscribers []models.Subscriber
+	if err := r.db.WithContext(ctx).Order("created_at ASC").Find(&subscribers).Error; err != nil {
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+// Stats implements the Stats method of the Subscribers interface
+func (r *SubscribersRepo) Stats(ctx context.Context) (*models.SubscriberStats, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Subscriber{}).
+		Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.SubscriberStats{}
+	for _, row := range rows {
+		stats.Total += row.Count
+		switch row.Status {
+		case models.SubscriberPending:
+			stats.Pending = row.Count
+		case models.SubscriberActive:
+			stats.Active = row.Count
+		case models.SubscriberUnsubscribed:
+			stats.Unsubscribed = row.Count
+		}
+	}
+	return stats, nil
+}
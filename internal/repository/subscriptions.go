@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SubscriptionsRepo implements the Subscriptions interface
+type SubscriptionsRepo struct {
+	db *gorm.DB
+}
+
+// NewSubscriptionsRepo creates a new SubscriptionsRepo
+func NewSubscriptionsRepo(db *gorm.DB) Subscriptions {
+	return &SubscriptionsRepo{
+		db: db,
+	}
+}
+
+// Create implements the Create method of the Subscriptions interface
+func (r *SubscriptionsRepo) Create(ctx context.Context, subscription *models.Subscription) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(subscription).Error
+}
+
+// GetByID implements the GetByID method of the Subscriptions interface
+func (r *SubscriptionsRepo) GetByID(ctx context.Context, id string) (*models.Subscription, error) {
+	subscriptionID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscription models.Subscription
+	if err := r.withDetails(r.db.WithContext(ctx)).First(&subscription, "id = ?", subscriptionID).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ListByUser implements the ListByUser method of the Subscriptions interface
+func (r *SubscriptionsRepo) ListByUser(ctx context.Context, userID string) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.withDetails(r.db.WithContext(ctx)).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Update implements the Update method of the Subscriptions interface
+func (r *SubscriptionsRepo) Update(ctx context.Context, subscription *models.Subscription) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(subscription).Error
+}
+
+// ListDue implements the ListDue method of the Subscriptions interface
+func (r *SubscriptionsRepo) ListDue(ctx context.Context, now time.Time) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.withDetails(r.db.WithContext(ctx)).
+		Where("status = ? AND renewal_order_id IS NULL", models.SubscriptionActive).
+		Where("COALESCE(retry_at, next_renewal_at) <= ?", now).
+		Order("next_renewal_at").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ListAwaitingPayment implements the ListAwaitingPayment method of the Subscriptions interface
+func (r *SubscriptionsRepo) ListAwaitingPayment(ctx context.Context) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.withDetails(r.db.WithContext(ctx)).
+		Where("renewal_order_id IS NOT NULL AND status <> ?", models.SubscriptionCanceled).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ListToRemind implements the ListToRemind method of the Subscriptions interface
+func (r *SubscriptionsRepo) ListToRemind(ctx context.Context, before time.Time) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.withDetails(r.db.WithContext(ctx)).
+		Where("status = ? AND renewal_order_id IS NULL AND failed_attempts = 0", models.SubscriptionActive).
+		Where("reminder_sent_at IS NULL AND next_renewal_at <= ?", before).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetPaymentMethod implements the GetPaymentMethod method of the Subscriptions interface
+func (r *SubscriptionsRepo) GetPaymentMethod(ctx context.Context, userID, id uuid.UUID) (*models.PaymentMethod, error) {
+	var method models.PaymentMethod
+	if err := r.db.WithContext(ctx).First(&method, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+// withDetails loads what renewing a subscription and emailing its customer need
+func (r *SubscriptionsRepo) withDetails(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("User").
+		Preload("Product").
+		Preload("Product.Categories").
+		Preload("Product.Variants").
+		Preload("Product.Components").
+		Preload("Product.Components.Product").
+		Preload("Product.Components.Variant").
+		Preload("Variant").
+		Preload("PaymentMethod")
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// TaxZonesRepo implements the TaxZones interface
+type TaxZonesRepo struct {
+	db *gorm.DB
+}
+
+// NewTaxZonesRepo creates a new TaxZonesRepo
+func NewTaxZonesRepo(db *gorm.DB) TaxZones {
+	return &TaxZonesRepo{
+		db: db,
+	}
+}
+
+// Create implements the Create method of the TaxZones interface
+func (r *TaxZonesRepo) Create(ctx context.Context, zone *models.TaxZone) error {
+	return r.db.WithContext(ctx).Create(zone).Error
+}
+
+// GetByID implements the GetByID method of the TaxZones interface
+func (r *TaxZonesRepo) GetByID(ctx context.Context, id string) (*models.TaxZone, error) {
+	var zone models.TaxZone
+	if err := r.db.WithContext(ctx).Preload("Rates").First(&zone, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// List implements the List method of the TaxZones interface
+func (r *TaxZonesRepo) List(ctx context.Context) ([]models.TaxZone, error) {
+	var zones []models.TaxZone
+	err := r.db.WithContext(ctx).Preload("Rates").Order("country, region, name").Find(&zones).Error
+	return zones, err
+}
+
+// Update implements the Update method of the TaxZones interface
+func (r *TaxZonesRepo) Update(ctx context.Context, zone *models.TaxZone) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Rates").Save(zone).Error; err != nil {
+			return err
+		}
+
+		// The zone's rates are replaced by the ones it has now
+		if err := tx.Where("tax_zone_id = ?", zone.ID).Delete(&models.TaxRate{}).Error; err != nil {
+			return err
+		}
+		if len(zone.Rates) == 0 {
+			return nil
+		}
+		for i := range zone.Rates {
+			zone.Rates[i].TaxZoneID = zone.ID
+		}
+		return tx.Create(&zone.Rates).Error
+	})
+}
+
+// Delete implements the Delete method of the TaxZones interface
+func (r *TaxZonesRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tax_zone_id = ?", id).Delete(&models.TaxRate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.TaxZone{}, "id = ?", id).Error
+	})
+}
+
+// Match implements the Match method of the TaxZones interface
+func (r *TaxZonesRepo) Match(ctx context.Context, country, region string) (*models.TaxZone, error) {
+	var zones []models.TaxZone
+	err := r.db.WithContext(ctx).
+		Preload("Rates").
+		Where("UPPER(country) = ? OR country = ''", strings.ToUpper(strings.TrimSpace(country))).
+		Where("region = '' OR UPPER(region) = ?", strings.ToUpper(strings.TrimSpace(region))).
+		// The most specific zone wins: a region, then its country, then everywhere else
+		Order("region <> '' DESC, country <> '' DESC, created_at").
+		Limit(1).
+		Find(&zones).Error
+	if err != nil || len(zones) == 0 {
+		return nil, err
+	}
+	return &zones[0], nil
+}
+
+// Report implements the Report method of the TaxZones interface
+func (r *TaxZonesRepo) Report(ctx context.Context, filter models.TaxReportFilter) ([]models.TaxReportRow, error) {
+	var rows []models.TaxReportRow
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT l.tax_zone_id::text AS tax_zone_id, l.zone_name AS zone,
+			date_trunc(@period, o.created_at) AS period,
+			COUNT(DISTINCT o.id) AS orders,
+			SUM(l.taxable_amount / o.exchange_rate) AS taxable_amount,
+			SUM(l.amount / o.exchange_rate) AS tax_amount
+		FROM order_tax_lines l
+		JOIN orders o ON o.id = l.order_id
+		WHERE o.status <> 'canceled' AND o.created_at >= @from AND o.created_at < @to
+		GROUP BY l.tax_zone_id, l.zone_name, date_trunc(@period, o.created_at)
+		ORDER BY period, zone
+	`, map[string]interface{}{"period": filter.Period, "from": filter.From, "to": filter.To}).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/repository"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+)
+
+// AttributionWindow is how long after clicking through from a post an order still counts towards it
+const AttributionWindow = 30 * 24 * time.Hour
+
+// ErrProductNotFound is returned when a click references a product that doesn't exist
+var ErrProductNotFound = errors.New("product not found")
+
+// AttributionService tracks which posts drive product clicks and orders
+type AttributionService struct {
+	attributionRepo repository.Attribution
+	postRepo        repository.PostRepository
+	productsRepo    repository.Products
+}
+
+// NewAttributionService creates a new attribution service
+func NewAttributionService(attributionRepo repository.Attribution, postRepo repository.PostRepository, productsRepo repository.Products) *AttributionService {
+	return &AttributionService{
+		attributionRepo: attributionRepo,
+		postRepo:        postRepo,
+		productsRepo:    productsRepo,
+	}
+}
+
+// TrackClick records a click-through from a post to a product
+func (s *AttributionService) TrackClick(ctx context.Context, postID, productID, visitorID, userID string) error {
+	if _, err := s.postRepo.GetByID(ctx, postID); err != nil {
+		return ErrPostNotFound
+	}
+	if _, err := s.productsRepo.GetByID(ctx, productID); err != nil {
+		return ErrProductNotFound
+	}
+
+	return s.attributionRepo.CreateClick(ctx, &models.PostProductClick{
+		PostID:    postID,
+		ProductID: productID,
+		VisitorID: visitorID,
+		UserID:    userID,
+	})
+}
+
+// AttributeOrder credits each item in an order to the post the buyer most recently
+// clicked that product from, within the attribution window
+func (s *AttributionService) AttributeOrder(ctx context.Context, order *models.Order, visitorID string) error {
+	productIDs := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		productIDs = append(productIDs, item.ProductID.String())
+	}
+
+	clicks, err := s.attributionRepo.LatestClicks(ctx, visitorID, order.UserID.String(), productIDs, time.Now().Add(-AttributionWindow))
+	if err != nil {
+		return err
+	}
+
+	postByProduct := make(map[string]string, len(clicks))
+	for _, click := range clicks {
+		postByProduct[click.ProductID] = click.PostID
+	}
+
+	// Revenue is what each line sold for after discounts, in the base currency so
+	// orders placed in different currencies add up
+	rate := order.ExchangeRate
+	if rate <= 0 {
+		rate = 1
+	}
+
+	var conversions []models.PostConversion
+	for _, item := range order.Items {
+		postID, ok := postByProduct[item.ProductID.String()]
+		if !ok {
+			continue
+		}
+		conversions = append(conversions, models.PostConversion{
+			PostID:    postID,
+			ProductID: item.ProductID.String(),
+			OrderID:   order.ID.String(),
+			Quantity:  item.Quantity,
+			Revenue:   roundMoney(lineRemaining(&item, order.Currency)/rate, util.BaseCurrency()),
+		})
+	}
+
+	return s.attributionRepo.CreateConversions(ctx, conversions)
+}
+
+// TopPosts returns the posts that drove the most revenue over the last days
+func (s *AttributionService) TopPosts(ctx context.Context, days, limit int) ([]models.PostAttributionStats, error) {
+	stats, err := s.attributionRepo.PostStats(ctx, time.Now().AddDate(0, 0, -days), limit)
+	if err != nil || len(stats) == 0 {
+		return stats, err
+	}
+
+	// Titles come from the post store, which may not be the database the stats are in
+	ids := make([]string, len(stats))
+	for i, stat := range stats {
+		ids[i] = stat.PostID
+	}
+	posts, _, err := s.postRepo.Find(ctx, models.PostQuery{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]string, len(posts))
+	for _, post := range posts {
+		titles[post.ID.String()] = post.Title
+	}
+
+	// Posts deleted since don't show up
+	top := stats[:0]
+	for _, stat := range stats {
+		if title, ok := titles[stat.PostID]; ok {
+			stat.Title = title
+			top = append(top, stat)
+		}
+	}
+	return top, nil
+}
+
+// PostProducts returns a post's clicks and orders by product over the last days
+func (s *AttributionService) PostProducts(ctx context.Context, postID string, days int) ([]models.ProductAttributionStats, error) {
+	if _, err := s.postRepo.GetByID(ctx, postID); err != nil {
+		return nil, ErrPostNotFound
+	}
+	return s.attributionRepo.PostProductStats(ctx, postID, time.Now().AddDate(0, 0, -days))
+}
@@ -3,10 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/adrianmcmains/blog-ecommerce/internal/models"
 	"github.com/adrianmcmains/blog-ecommerce/internal/repository"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
 )
 
 // PostInput represents the input for creating or updating a post
@@ -18,25 +22,45 @@ type PostInput struct {
 	Categories    []string `json:"categories"`
 	Tags          []string `json:"tags"`
 	Published     bool     `json:"published"`
+	Locale        string   `json:"locale"`
+	TranslationOf string   `json:"translation_of"`
 }
 
 // Common errors
 var (
-	ErrPostNotFound = errors.New("post not found")
-	ErrNotAuthorized = errors.New("not authorized to perform this action")
+	ErrPostNotFound        = errors.New("post not found")
+	ErrNotAuthorized       = errors.New("not authorized to perform this action")
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrTranslationExists   = errors.New("a translation in this language already exists")
 )
 
+// UnknownProductsError is returned when post content embeds products that don't exist
+type UnknownProductsError struct {
+	Slugs []string
+}
+
+func (e *UnknownProductsError) Error() string {
+	return "unknown products embedded in content: " + strings.Join(e.Slugs, ", ")
+}
+
 // BlogService handles blog-related business logic
 type BlogService struct {
-	postRepo repository.PostRepository
-	userRepo repository.UserRepository
+	postRepo     repository.PostRepository
+	userRepo     repository.UserRepository
+	commentRepo  repository.ReviewComments
+	productsRepo repository.Products
+	seriesRepo   repository.Series
+	notifier     PostNotifier
 }
 
 // NewBlogService creates a new blog service
-func NewBlogService(postRepo repository.PostRepository, userRepo repository.UserRepository) *BlogService {
+func NewBlogService(postRepo repository.PostRepository, userRepo repository.UserRepository, commentRepo repository.ReviewComments, productsRepo repository.Products, seriesRepo repository.Series) *BlogService {
 	return &BlogService{
-		postRepo: postRepo,
-		userRepo: userRepo,
+		postRepo:     postRepo,
+		userRepo:     userRepo,
+		commentRepo:  commentRepo,
+		productsRepo: productsRepo,
+		seriesRepo:   seriesRepo,
 	}
 }
 
@@ -47,30 +71,64 @@ func (s *BlogService) CreatePost(ctx context.Context, input PostInput, authorID
 	if err != nil {
 		return nil, err
 	}
-	
+
 	authorName := author.FirstName + " " + author.LastName
 
+	if err := s.validateProductEmbeds(ctx, input.Content); err != nil {
+		return nil, err
+	}
+
+	locale := input.Locale
+	if locale == "" {
+		locale = util.DefaultLanguage()
+	}
+	if !util.IsSupportedLanguage(locale) {
+		return nil, ErrUnsupportedLanguage
+	}
+
+	// A translation joins the source post's group; otherwise the post starts a new one
+	groupID := uuid.New().String()
+	if input.TranslationOf != "" {
+		source, err := s.postRepo.GetByID(ctx, input.TranslationOf)
+		if err != nil {
+			return nil, ErrPostNotFound
+		}
+		groupID = source.TranslationGroupID
+		if groupID == "" {
+			// Posts created before translations existed get their own ID as group
+			groupID = source.ID.String()
+			source.TranslationGroupID = groupID
+			if err := s.postRepo.Update(ctx, source); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.checkTranslationFree(ctx, groupID, locale, ""); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create new post
 	post := &models.Post{
 		Base: models.Base{
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
-		Title:         input.Title,
-		Content:       input.Content,
-		Excerpt:       input.Excerpt,
-		FeaturedImage: input.FeaturedImage,
-		AuthorID:      authorID,
-		AuthorName:    authorName,
-		Categories:    input.Categories,
-		Tags:          input.Tags,
-		Published:     input.Published,
+		Title:              input.Title,
+		Content:            input.Content,
+		Excerpt:            input.Excerpt,
+		FeaturedImage:      input.FeaturedImage,
+		AuthorID:           authorID,
+		AuthorName:         authorName,
+		Categories:         input.Categories,
+		Tags:               input.Tags,
+		Status:             models.PostStatusDraft,
+		Locale:             locale,
+		TranslationGroupID: groupID,
 	}
 
-	// Handle published status
-	if input.Published {
-		now := time.Now()
-		post.PublishedAt = &now
+	// Only admins and editors can publish directly; everyone else starts with a draft
+	if input.Published && models.IsEditorRole(author.Role) {
+		setPostStatus(post, models.PostStatusPublished)
 	}
 
 	// Save post to repository
@@ -90,31 +148,45 @@ func (s *BlogService) GetPost(ctx context.Context, id string) (*models.Post, err
 	return post, nil
 }
 
-// ListPosts retrieves all posts with filtering and pagination
-func (s *BlogService) ListPosts(ctx context.Context, page, limit int, category, authorID string) ([]*models.Post, int64, error) {
-	var posts []*models.Post
-	var err error
+// ListPosts retrieves posts matching query with pagination
+func (s *BlogService) ListPosts(ctx context.Context, query models.PostQuery) ([]*models.Post, int64, error) {
+	return s.postRepo.Find(ctx, query)
+}
 
-	// Apply filters if provided
-	if category != "" {
-		posts, err = s.postRepo.GetByCategory(ctx, category, page, limit)
-	} else if authorID != "" {
-		posts, err = s.postRepo.GetByAuthor(ctx, authorID, page, limit)
-	} else {
-		posts, err = s.postRepo.GetAll(ctx, page, limit)
+// ListPostsInLanguage retrieves posts in lang matching query with pagination, showing
+// the default language version of any post that has not been translated
+func (s *BlogService) ListPostsInLanguage(ctx context.Context, query models.PostQuery, lang string) ([]*models.Post, int64, error) {
+	if lang == "" {
+		lang = util.DefaultLanguage()
 	}
+	return s.postRepo.GetByLocale(ctx, lang, util.DefaultLanguage(), query)
+}
 
+// GetTranslations retrieves every language version of a post, including the post itself
+func (s *BlogService) GetTranslations(ctx context.Context, id string) ([]*models.Post, error) {
+	post, err := s.postRepo.GetByID(ctx, id)
 	if err != nil {
-		return nil, 0, err
+		return nil, ErrPostNotFound
 	}
+	if post.TranslationGroupID == "" {
+		return []*models.Post{post}, nil
+	}
+	return s.postRepo.GetTranslations(ctx, post.TranslationGroupID)
+}
 
-	// Get total count for pagination
-	count, err := s.postRepo.Count(ctx)
+// checkTranslationFree returns ErrTranslationExists if a post other than exceptID
+// in the group is already written in locale
+func (s *BlogService) checkTranslationFree(ctx context.Context, groupID, locale, exceptID string) error {
+	translations, err := s.postRepo.GetTranslations(ctx, groupID)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
-
-	return posts, count, nil
+	for _, translation := range translations {
+		if translation.Locale == locale && translation.ID.String() != exceptID {
+			return ErrTranslationExists
+		}
+	}
+	return nil
 }
 
 // UpdatePost updates an existing post
@@ -125,11 +197,20 @@ func (s *BlogService) UpdatePost(ctx context.Context, id string, input PostInput
 		return nil, ErrPostNotFound
 	}
 
-	// Check if user is the author of the post
-	if post.AuthorID != userID {
+	// Check if user is the author of the post or an editor
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrNotAuthorized
+	}
+	isEditor := models.IsEditorRole(user.Role)
+	if post.AuthorID != userID && !isEditor {
 		return nil, ErrNotAuthorized
 	}
 
+	if err := s.validateProductEmbeds(ctx, input.Content); err != nil {
+		return nil, err
+	}
+
 	// Update fields
 	post.Title = input.Title
 	post.Content = input.Content
@@ -148,14 +229,25 @@ func (s *BlogService) UpdatePost(ctx context.Context, id string, input PostInput
 	if len(input.Tags) > 0 {
 		post.Tags = input.Tags
 	}
-
-	// Handle publishing status change
-	if post.Published != input.Published {
-		post.Published = input.Published
-		if input.Published {
-			now := time.Now()
-			post.PublishedAt = &now
+	if input.Locale != "" && input.Locale != post.Locale {
+		if !util.IsSupportedLanguage(input.Locale) {
+			return nil, ErrUnsupportedLanguage
 		}
+		if post.TranslationGroupID != "" {
+			if err := s.checkTranslationFree(ctx, post.TranslationGroupID, input.Locale, post.ID.String()); err != nil {
+				return nil, err
+			}
+		}
+		post.Locale = input.Locale
+	}
+
+	// Publishing goes through TransitionPost; an approval only covers the content
+	// that was reviewed, so an author's edit of an approved or published post sends
+	// it back for review, taking it down until an editor publishes it again
+	resubmitted := false
+	if (post.Status == models.PostStatusApproved || post.Status == models.PostStatusPublished) && !isEditor {
+		setPostStatus(post, models.PostStatusInReview)
+		resubmitted = true
 	}
 
 	// Save updated post
@@ -163,9 +255,126 @@ func (s *BlogService) UpdatePost(ctx context.Context, id string, input PostInput
 		return nil, err
 	}
 
+	if resubmitted {
+		s.notifyStatusChange(ctx, post, user, "")
+	}
+
 	return post, nil
 }
 
+// GetSeriesNavigation returns where a post sits in its series, with the published posts
+// before and after it, or nil if it isn't in a series
+func (s *BlogService) GetSeriesNavigation(ctx context.Context, post *models.Post) (*models.SeriesNavigation, error) {
+	series, err := s.seriesRepo.ByPost(ctx, post.ID.String())
+	if err != nil || series == nil {
+		return nil, err
+	}
+
+	posts, _, err := s.postRepo.Find(ctx, models.PostQuery{IDs: series.PostIDs})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID.String()] = p
+	}
+
+	// Published posts in the series, in order, plus the current post even if it is a draft
+	var items []*models.Post
+	for _, id := range series.PostIDs {
+		if p, ok := byID[id]; ok && (p.Published || p.ID == post.ID) {
+			items = append(items, p)
+		}
+	}
+
+	nav := &models.SeriesNavigation{
+		ID:    series.ID,
+		Slug:  series.Slug,
+		Title: series.Title,
+		Total: len(items),
+	}
+	for i, item := range items {
+		if item.ID != post.ID {
+			continue
+		}
+		nav.Position = i + 1
+		if i > 0 {
+			nav.Prev = &models.SeriesNavItem{ID: items[i-1].ID.String(), Title: items[i-1].Title, Position: i}
+		}
+		if i < len(items)-1 {
+			nav.Next = &models.SeriesNavItem{ID: items[i+1].ID.String(), Title: items[i+1].Title, Position: i + 2}
+		}
+		break
+	}
+	return nav, nil
+}
+
+// GetProductCards returns the products embedded in a post, in order of appearance,
+// with their current price and stock. Products that have since been removed are skipped.
+func (s *BlogService) GetProductCards(ctx context.Context, post *models.Post) ([]models.ProductCard, error) {
+	slugs := util.ExtractProductEmbeds(post.Content)
+	cards := []models.ProductCard{}
+	if len(slugs) == 0 {
+		return cards, nil
+	}
+
+	products, err := s.productsRepo.GetBySlugs(ctx, slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	bySlug := make(map[string]models.Product, len(products))
+	for _, product := range products {
+		bySlug[product.Slug] = product
+	}
+
+	for _, slug := range slugs {
+		product, ok := bySlug[slug]
+		if !ok {
+			continue
+		}
+		cards = append(cards, models.ProductCard{
+			ID:            product.ID.String(),
+			Slug:          product.Slug,
+			Name:          product.Name,
+			Price:         product.Price,
+			Image:         product.Image,
+			StockQuantity: product.StockQuantity,
+			InStock:       product.Status == "active" && product.StockQuantity > 0,
+		})
+	}
+	return cards, nil
+}
+
+// validateProductEmbeds checks that every product embedded in content exists
+func (s *BlogService) validateProductEmbeds(ctx context.Context, content string) error {
+	slugs := util.ExtractProductEmbeds(content)
+	if len(slugs) == 0 {
+		return nil
+	}
+
+	products, err := s.productsRepo.GetBySlugs(ctx, slugs)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]bool, len(products))
+	for _, product := range products {
+		found[product.Slug] = true
+	}
+
+	var unknown []string
+	for _, slug := range slugs {
+		if !found[slug] {
+			unknown = append(unknown, slug)
+		}
+	}
+	if len(unknown) > 0 {
+		return &UnknownProductsError{Slugs: unknown}
+	}
+	return nil
+}
+
 // DeletePost deletes a post
 func (s *BlogService) DeletePost(ctx context.Context, id string, userID string) error {
 	// Get the post to check authorization
@@ -179,6 +388,9 @@ func (s *BlogService) DeletePost(ctx context.Context, id string, userID string)
 		return ErrNotAuthorized
 	}
 
-	// Delete the post
-	return s.postRepo.Delete(ctx, id)
-}
\ No newline at end of file
+	// Delete the post and its review history
+	if err := s.postRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return s.commentRepo.DeleteByPost(ctx, id)
+}
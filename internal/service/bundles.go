@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidBundle is returned when a bundle's components don't make sense
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+// ProductRevenue totals what each product and variant sold for, on its own and as
+// part of bundles, in the base currency
+func (s *ShopService) ProductRevenue(ctx context.Context, filter models.ProductRevenueFilter) ([]models.ProductRevenueRow, error) {
+	rows, err := s.ordersRepo.ProductRevenue(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Revenue = roundMoney(rows[i].Revenue, util.BaseCurrency())
+		rows[i].BundleRevenue = roundMoney(rows[i].BundleRevenue, util.BaseCurrency())
+	}
+	return rows, nil
+}
+
+// BundleBreakdown splits a bundle order line into its components for fulfilment,
+// allocating net, what the line sold for after discounts, across them in proportion
+// to their unit price times how many each bundle holds. unitPrice gives a component's
+// price in the line's currency; nil uses the base prices.
+func BundleBreakdown(bundle *models.Product, quantity int, net float64, currency string, unitPrice func(component *models.BundleComponent) float64) []models.OrderItemComponent {
+	if unitPrice == nil {
+		unitPrice = componentBasePrice
+	}
+
+	breakdown := make([]models.OrderItemComponent, len(bundle.Components))
+	weights := make([]float64, len(bundle.Components))
+	total := 0.0
+	for i := range bundle.Components {
+		component := &bundle.Components[i]
+		breakdown[i] = models.OrderItemComponent{
+			ProductID: component.ProductID,
+			VariantID: component.VariantID,
+			Quantity:  component.Quantity * quantity,
+		}
+		if component.Product != nil {
+			breakdown[i].Name = component.Product.Name
+			breakdown[i].SKU = component.Product.SKU
+		}
+		if component.Variant != nil {
+			breakdown[i].Name += " - " + component.Variant.Name
+			breakdown[i].SKU = component.Variant.SKU
+		}
+
+		weights[i] = unitPrice(component) * float64(component.Quantity)
+		total += weights[i]
+	}
+
+	// Components with no price split the line evenly; the last one takes whatever
+	// rounding leaves over so the shares add up exactly
+	left := roundMoney(net, currency)
+	for i := range breakdown {
+		if i == len(breakdown)-1 {
+			breakdown[i].Revenue = left
+			break
+		}
+		share := 1 / float64(len(breakdown))
+		if total > 0 {
+			share = weights[i] / total
+		}
+		breakdown[i].Revenue = roundMoney(net*share, currency)
+		left = roundMoney(left-breakdown[i].Revenue, currency)
+	}
+	return breakdown
+}
+
+// buildComponents validates a bundle's component inputs and turns them into its
+// components, with their products and variants loaded
+func (s *ShopService) buildComponents(ctx context.Context, bundleID uuid.UUID, inputs []models.BundleComponentInput) ([]models.BundleComponent, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: a bundle needs at least one component", ErrInvalidBundle)
+	}
+
+	components := make([]models.BundleComponent, 0, len(inputs))
+	seen := make(map[string]bool, len(inputs))
+	for _, input := range inputs {
+		if input.ProductID == bundleID.String() {
+			return nil, fmt.Errorf("%w: a bundle can't contain itself", ErrInvalidBundle)
+		}
+		product, err := s.productsRepo.GetByID(ctx, input.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: product %s not found", ErrInvalidBundle, input.ProductID)
+		}
+		if product.Type == models.ProductBundle {
+			return nil, fmt.Errorf("%w: %s is a bundle itself", ErrInvalidBundle, product.Name)
+		}
+		if product.Type == models.ProductGiftCard {
+			return nil, fmt.Errorf("%w: %s is a gift card", ErrInvalidBundle, product.Name)
+		}
+
+		variant, err := resolveVariant(product, input.VariantID, nil)
+		if errors.Is(err, ErrVariantRequired) {
+			return nil, fmt.Errorf("%w: choose a variant of %s", ErrInvalidBundle, product.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s has no variant %s", ErrInvalidBundle, product.Name, input.VariantID)
+		}
+
+		component := models.BundleComponent{
+			BundleID:  bundleID,
+			ProductID: product.ID,
+			Product:   product,
+			Quantity:  input.Quantity,
+		}
+		if variant != nil {
+			component.VariantID = &variant.ID
+			component.Variant = variant
+		}
+
+		key := priceKey(component.ProductID, component.VariantID)
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidBundle, product.Name)
+		}
+		seen[key] = true
+		components = append(components, component)
+	}
+	return components, nil
+}
+
+// checkBundleInput makes sure a product being saved as a bundle has nothing a bundle
+// can't have, and that a product being saved as a simple one isn't given components
+func (s *ShopService) checkBundleInput(ctx context.Context, productID uuid.UUID, input models.CreateProductInput) error {
+	if input.Type != models.ProductBundle {
+		if len(input.Components) > 0 {
+			return fmt.Errorf("%w: only bundles have components", ErrInvalidBundle)
+		}
+		return nil
+	}
+
+	if len(input.Options) > 0 || len(input.Variants) > 0 {
+		return fmt.Errorf("%w: bundles can't have options or variants", ErrInvalidBundle)
+	}
+	if productID == uuid.Nil {
+		return nil
+	}
+
+	// A product another bundle is made of can't become a bundle itself
+	bundles, err := s.productsRepo.CountBundlesWith(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if bundles > 0 {
+		return fmt.Errorf("%w: the product is part of %d bundles", ErrInvalidBundle, bundles)
+	}
+	return nil
+}
+
+// bundleAvailable is how many of a bundle its components' available stock makes up
+func bundleAvailable(bundle *models.Product, held map[string]int) int {
+	available := -1
+	for _, component := range bundle.Components {
+		if component.Quantity <= 0 || component.Product == nil {
+			return 0
+		}
+		onHand := component.Product.StockQuantity
+		if component.Variant != nil {
+			onHand = component.Variant.StockQuantity
+		}
+		count := max(onHand-held[priceKey(component.ProductID, component.VariantID)], 0) / component.Quantity
+		if available < 0 || count < available {
+			available = count
+		}
+	}
+	return max(available, 0)
+}
+
+// bundleWeight is a bundle's own weight, or its components' when it has none
+func bundleWeight(bundle *models.Product) float64 {
+	if bundle.Weight > 0 {
+		return bundle.Weight
+	}
+	weight := 0.0
+	for _, component := range bundle.Components {
+		if component.Product == nil {
+			continue
+		}
+		itemWeight := component.Product.Weight
+		if component.Variant != nil && component.Variant.Weight > 0 {
+			itemWeight = component.Variant.Weight
+		}
+		weight += itemWeight * float64(component.Quantity)
+	}
+	return weight
+}
+
+// componentBasePrice is a component's unit price in the base currency
+func componentBasePrice(component *models.BundleComponent) float64 {
+	if component.Variant != nil {
+		return component.Variant.Price
+	}
+	if component.Product != nil {
+		return component.Product.Price
+	}
+	return 0
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrGiftCardNotFound is returned for a gift card code that doesn't exist
+	ErrGiftCardNotFound = errors.New("gift card not found")
+	// ErrGiftCardUnusable is returned for a gift card that hasn't been paid for, was
+	// voided, has expired or has nothing left on it
+	ErrGiftCardUnusable = errors.New("gift card can't be used")
+	// ErrInvalidCredit is returned when credit can't be issued or applied as asked
+	ErrInvalidCredit = errors.New("invalid credit")
+	// ErrOrderNotFound is returned for an order that doesn't exist or isn't the user's
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrGiftCardQuantity is returned for a cart line of more gift cards than can be
+	// bought at once
+	ErrGiftCardQuantity = errors.New("too many gift cards")
+)
+
+// giftCardAlphabet leaves out letters and digits that are easily mistaken for each other
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// MaxGiftCardsPerLine is how many of a gift card can be bought in one cart line; each
+// one is issued as its own card, and gift cards have no stock to limit them otherwise
+const MaxGiftCardsPerLine = 20
+
+// GiftCardBalance looks up what's left on a gift card by its code
+func (s *ShopService) GiftCardBalance(ctx context.Context, code string) (*models.GiftCardBalance, error) {
+	card, err := s.creditsRepo.GetGiftCardByCode(ctx, normalizeGiftCardCode(code))
+	if err != nil {
+		return nil, ErrGiftCardNotFound
+	}
+
+	return &models.GiftCardBalance{
+		Code:      maskGiftCardCode(card.Code),
+		Balance:   card.Balance,
+		Currency:  card.Currency,
+		Status:    card.Status,
+		ExpiresAt: card.ExpiresAt,
+	}, nil
+}
+
+// StoreCredit returns a customer's store credit and a page of its ledger
+func (s *ShopService) StoreCredit(ctx context.Context, userID string, page, limit int) (*models.StoreCredit, []models.CreditTransaction, int64, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	credit, err := s.creditsRepo.GetStoreCredit(ctx, id)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	entries, total, err := s.creditsRepo.History(ctx, models.CreditLedgerFilter{UserID: &id, Page: page, Limit: limit})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return credit, entries, total, nil
+}
+
+// GiftCardLedger returns a gift card and a page of its ledger for the admin
+func (s *ShopService) GiftCardLedger(ctx context.Context, code string, page, limit int) (*models.GiftCard, []models.CreditTransaction, int64, error) {
+	card, err := s.creditsRepo.GetGiftCardByCode(ctx, normalizeGiftCardCode(code))
+	if err != nil {
+		return nil, nil, 0, ErrGiftCardNotFound
+	}
+	entries, total, err := s.creditsRepo.History(ctx, models.CreditLedgerFilter{GiftCardID: &card.ID, Page: page, Limit: limit})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return card, entries, total, nil
+}
+
+// IssueStoreCredit adds store credit to a customer's balance, e.g. for a refund or goodwill
+func (s *ShopService) IssueStoreCredit(ctx context.Context, input models.StoreCreditInput) (*models.CreditTransaction, error) {
+	userID, err := uuid.Parse(input.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user not found", ErrInvalidCredit)
+	}
+	amount := roundMoney(input.Amount, util.BaseCurrency())
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: the amount has to be more than zero", ErrInvalidCredit)
+	}
+
+	entries := []models.CreditTransaction{{
+		UserID:  &userID,
+		Type:    models.CreditIssue,
+		Amount:  amount,
+		Reason:  input.Reason,
+		ActorID: parseActorID(input.ActorID),
+	}}
+	if _, err := s.creditsRepo.Record(ctx, entries); err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// IssueGiftCard creates an active gift card from the admin, e.g. as a goodwill gesture
+func (s *ShopService) IssueGiftCard(ctx context.Context, input models.GiftCardInput) (*models.GiftCard, error) {
+	amount := roundMoney(input.Amount, util.BaseCurrency())
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: the amount has to be more than zero", ErrInvalidCredit)
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: the expiry date has to be in the future", ErrInvalidCredit)
+	}
+
+	return s.createGiftCard(ctx, &models.GiftCard{
+		InitialValue: amount,
+		Balance:      amount,
+		Status:       models.GiftCardActive,
+		ExpiresAt:    input.ExpiresAt,
+	}, &models.CreditTransaction{
+		Reason:  input.Reason,
+		ActorID: parseActorID(input.ActorID),
+	})
+}
+
+// ApplyCredit pays part or all of a customer's pending order with gift cards, in the
+// order given, and then their store credit. Balances are in the base currency and
+// converted at the order's exchange rate. An order paid for in full is completed
+// straight away; otherwise what's left is charged through InitiatePayment.
+func (s *ShopService) ApplyCredit(ctx context.Context, input models.ApplyCreditInput) (*models.Order, error) {
+	order, err := s.GetOrder(ctx, input.OrderID, input.UserID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.Status != "pending" {
+		return nil, fmt.Errorf("%w: the order isn't awaiting payment", ErrInvalidCredit)
+	}
+	if len(input.GiftCardCodes) == 0 && !input.UseStoreCredit {
+		return nil, fmt.Errorf("%w: give a gift card code or use store credit", ErrInvalidCredit)
+	}
+
+	due := roundMoney(order.TotalAmount-order.CreditApplied, order.Currency)
+	if due <= 0 {
+		return nil, fmt.Errorf("%w: the order is already paid for", ErrInvalidCredit)
+	}
+	rate := order.ExchangeRate
+	if rate <= 0 {
+		rate = 1
+	}
+
+	applied := 0.0
+	var entries []models.CreditTransaction
+	take := func(entry models.CreditTransaction, balance float64) {
+		left := roundMoney(due-applied, order.Currency)
+		needed := roundMoney(left/rate, util.BaseCurrency())
+		amount := min(balance, needed)
+		if left <= 0 || amount <= 0 {
+			return
+		}
+
+		// Taking all that's needed settles what's left exactly, whatever the rounding
+		local := left
+		if amount < needed {
+			local = min(roundMoney(amount*rate, order.Currency), left)
+		}
+		entry.Type = models.CreditRedemption
+		entry.Amount = -amount
+		entry.Reason = "Order " + order.ID.String()
+		entry.ActorID = &order.UserID
+		entries = append(entries, entry)
+		applied += local
+	}
+
+	seen := make(map[string]bool, len(input.GiftCardCodes))
+	for _, code := range input.GiftCardCodes {
+		code = normalizeGiftCardCode(code)
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		card, err := s.creditsRepo.GetGiftCardByCode(ctx, code)
+		if err != nil {
+			return nil, ErrGiftCardNotFound
+		}
+		if err := checkGiftCardUsable(card); err != nil {
+			return nil, err
+		}
+		take(models.CreditTransaction{GiftCardID: &card.ID}, card.Balance)
+	}
+	if input.UseStoreCredit {
+		credit, err := s.creditsRepo.GetStoreCredit(ctx, order.UserID)
+		if err != nil {
+			return nil, err
+		}
+		take(models.CreditTransaction{UserID: &order.UserID}, credit.Balance)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: there's no credit to apply", ErrInvalidCredit)
+	}
+
+	ok, err := s.creditsRepo.Redeem(ctx, order.ID, roundMoney(applied, order.Currency), entries)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: the order or a balance changed while applying credit, try again", ErrInvalidCredit)
+	}
+
+	if applied >= due {
+		if err := s.completePaidOrder(ctx, order.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.ordersRepo.GetByID(ctx, order.ID.String())
+}
+
+// completePaidOrder moves an order that's been paid for on to processing, taking its
+// reserved stock off stock on hand and making the gift cards bought in it usable
+func (s *ShopService) completePaidOrder(ctx context.Context, orderID uuid.UUID) error {
+	if _, err := s.ordersRepo.MarkPaid(ctx, orderID); err != nil {
+		return err
+	}
+	if err := s.reservationsRepo.Convert(ctx, orderID); err != nil {
+		return err
+	}
+	return s.creditsRepo.ActivateOrderGiftCards(ctx, orderID)
+}
+
+// issueOrderGiftCards creates a pending gift card for each gift card bought in an
+// order, worth its base price; the cards become usable once the order is paid for
+func (s *ShopService) issueOrderGiftCards(ctx context.Context, order *models.Order, items []models.CartItem) error {
+	for _, item := range items {
+		if item.Product.Type != models.ProductGiftCard {
+			continue
+		}
+		value := item.Product.Price
+		if item.Variant != nil {
+			value = item.Variant.Price
+		}
+
+		for i := 0; i < item.Quantity; i++ {
+			card, err := s.createGiftCard(ctx, &models.GiftCard{
+				InitialValue: value,
+				Balance:      value,
+				Status:       models.GiftCardPending,
+				PurchaserID:  &order.UserID,
+				OrderID:      &order.ID,
+			}, &models.CreditTransaction{
+				Reason:  "Bought in order " + order.ID.String(),
+				ActorID: &order.UserID,
+			})
+			if err != nil {
+				return err
+			}
+			order.GiftCards = append(order.GiftCards, *card)
+		}
+	}
+	return nil
+}
+
+// createGiftCard gives a gift card a new code and saves it with its issue entry
+func (s *ShopService) createGiftCard(ctx context.Context, card *models.GiftCard, entry *models.CreditTransaction) (*models.GiftCard, error) {
+	code, err := generateGiftCardCode()
+	if err != nil {
+		return nil, err
+	}
+	card.Code = code
+	card.Currency = util.BaseCurrency()
+
+	if err := s.creditsRepo.CreateGiftCard(ctx, card, entry); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// isGiftCardLine reports whether an order line sells gift cards, which are stored money
+// rather than goods: promotions, tax and shipping don't apply to them
+func isGiftCardLine(item *models.OrderItem) bool {
+	return item.Product.Type == models.ProductGiftCard
+}
+
+// checkGiftCardQuantity makes sure a cart line doesn't buy more of a gift card than
+// MaxGiftCardsPerLine
+func checkGiftCardQuantity(product *models.Product, quantity int) error {
+	if product.Type == models.ProductGiftCard && quantity > MaxGiftCardsPerLine {
+		return fmt.Errorf("%w: at most %d of a gift card can be bought at once", ErrGiftCardQuantity, MaxGiftCardsPerLine)
+	}
+	return nil
+}
+
+// checkGiftCardUsable makes sure a gift card can pay for an order
+func checkGiftCardUsable(card *models.GiftCard) error {
+	switch {
+	case card.Status == models.GiftCardPending:
+		return fmt.Errorf("%w: the order it was bought in hasn't been paid for", ErrGiftCardUnusable)
+	case card.Status != models.GiftCardActive:
+		return fmt.Errorf("%w: it was voided", ErrGiftCardUnusable)
+	case card.ExpiresAt != nil && !card.ExpiresAt.After(time.Now()):
+		return fmt.Errorf("%w: it has expired", ErrGiftCardUnusable)
+	case card.Balance <= 0:
+		return fmt.Errorf("%w: there's nothing left on it", ErrGiftCardUnusable)
+	}
+	return nil
+}
+
+// generateGiftCardCode returns a random code like GC-7KQ2-M9XD-4TRH
+func generateGiftCardCode() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	// The alphabet has 32 letters, so every byte maps onto it evenly
+	for i, b := range bytes {
+		bytes[i] = giftCardAlphabet[int(b)%len(giftCardAlphabet)]
+	}
+	return "GC-" + string(bytes[0:4]) + "-" + string(bytes[4:8]) + "-" + string(bytes[8:12]), nil
+}
+
+// normalizeGiftCardCode formats a code as customers might type it, in any case and
+// with or without its dashes, the way codes are saved
+func normalizeGiftCardCode(code string) string {
+	code = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return -1
+	}, code)
+	if len(code) != 14 || !strings.HasPrefix(code, "GC") {
+		return code
+	}
+	return "GC-" + code[2:6] + "-" + code[6:10] + "-" + code[10:14]
+}
+
+// maskGiftCardCode hides all but the last group of a code
+func maskGiftCardCode(code string) string {
+	if len(code) < 4 {
+		return code
+	}
+	return "GC-****-****-" + code[len(code)-4:]
+}
+
+// parseActorID parses the ID of the admin making a change, if there is one
+func parseActorID(id string) *uuid.UUID {
+	actorID, err := uuid.Parse(id)
+	if err != nil {
+		return nil
+	}
+	return &actorID
+}
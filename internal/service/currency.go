@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnsupportedCurrency is returned when asked for a currency the shop doesn't sell in
+	ErrUnsupportedCurrency = errors.New("currency is not supported")
+	// ErrInvalidCurrency is returned when a currency's settings don't make sense
+	ErrInvalidCurrency = errors.New("invalid currency")
+)
+
+// exchangeRate is a currency and how much one unit of the base currency is worth in it
+type exchangeRate struct {
+	Currency string
+	Rate     float64
+}
+
+// CreateCurrency adds a currency customers can shop in
+func (s *ShopService) CreateCurrency(ctx context.Context, input models.CurrencyInput) (*models.Currency, error) {
+	currency := &models.Currency{}
+	if err := applyCurrencyInput(currency, input); err != nil {
+		return nil, err
+	}
+	if err := s.currencyRepo.Create(ctx, currency); err != nil {
+		return nil, err
+	}
+	return currency, nil
+}
+
+// ListCurrencies returns the currencies customers can shop in, or all of them for admins
+func (s *ShopService) ListCurrencies(ctx context.Context, activeOnly bool) ([]models.Currency, error) {
+	return s.currencyRepo.List(ctx, activeOnly)
+}
+
+// UpdateCurrency changes a currency's exchange rate or details; orders already placed
+// keep the rate they were placed at
+func (s *ShopService) UpdateCurrency(ctx context.Context, code string, input models.CurrencyInput) (*models.Currency, error) {
+	currency, err := s.currencyRepo.GetByCode(ctx, util.NormalizeCurrency(code))
+	if err != nil {
+		return nil, err
+	}
+	if util.NormalizeCurrency(input.Code) != currency.Code {
+		return nil, fmt.Errorf("%w: a currency's code can't be changed", ErrInvalidCurrency)
+	}
+	if err := applyCurrencyInput(currency, input); err != nil {
+		return nil, err
+	}
+	if err := s.currencyRepo.Update(ctx, currency); err != nil {
+		return nil, err
+	}
+	return currency, nil
+}
+
+// DeleteCurrency removes a currency and its price overrides
+func (s *ShopService) DeleteCurrency(ctx context.Context, code string) error {
+	return s.currencyRepo.Delete(ctx, util.NormalizeCurrency(code))
+}
+
+// SetProductPrice sets a product's or variant's price in a currency instead of converting it
+func (s *ShopService) SetProductPrice(ctx context.Context, productID string, input models.ProductPriceInput) (*models.ProductPrice, error) {
+	product, variantID, err := s.priceTarget(ctx, productID, input.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := s.resolveCurrency(ctx, input.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if rate.Currency == util.BaseCurrency() {
+		return nil, fmt.Errorf("%w: prices in the base currency are set on the product", ErrInvalidCurrency)
+	}
+
+	price := &models.ProductPrice{
+		ProductID: product.ID,
+		VariantID: variantID,
+		Currency:  rate.Currency,
+		Price:     roundMoney(input.Price, rate.Currency),
+	}
+	if err := s.currencyRepo.SetProductPrice(ctx, price); err != nil {
+		return nil, err
+	}
+	return price, nil
+}
+
+// DeleteProductPrice removes a price override so the product's price is converted again
+func (s *ShopService) DeleteProductPrice(ctx context.Context, productID, variantID, currency string) error {
+	product, variant, err := s.priceTarget(ctx, productID, variantID)
+	if err != nil {
+		return err
+	}
+	return s.currencyRepo.DeleteProductPrice(ctx, product.ID, variant, util.NormalizeCurrency(currency))
+}
+
+// LocalizeProducts converts the products' and their variants' prices into a currency,
+// using their price overrides where they have them
+func (s *ShopService) LocalizeProducts(ctx context.Context, products []models.Product, currency string) error {
+	rate, err := s.resolveCurrency(ctx, currency)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+	}
+	prices, err := s.productPrices(ctx, ids, rate)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		product := &products[i]
+		product.Price = prices.price(product.ID, nil, product.Price)
+		for j := range product.Variants {
+			variant := &product.Variants[j]
+			variant.Price = prices.price(product.ID, &variant.ID, variant.Price)
+		}
+		product.Currency = rate.Currency
+	}
+	return nil
+}
+
+// LocalizeCart converts the prices of the products in cart items into a currency
+func (s *ShopService) LocalizeCart(ctx context.Context, items []models.CartItem, currency string) error {
+	products := make([]models.Product, len(items))
+	for i := range items {
+		products[i] = items[i].Product
+		if items[i].Variant != nil {
+			products[i].Variants = []models.ProductVariant{*items[i].Variant}
+		}
+	}
+	if err := s.LocalizeProducts(ctx, products, currency); err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].Product.Price = products[i].Price
+		items[i].Product.Currency = products[i].Currency
+		if items[i].Variant != nil {
+			items[i].Variant.Price = products[i].Variants[0].Price
+		}
+	}
+	return nil
+}
+
+// CheckCurrency returns the normalized code of a currency the shop sells in
+func (s *ShopService) CheckCurrency(ctx context.Context, code string) (string, error) {
+	rate, err := s.resolveCurrency(ctx, code)
+	if err != nil {
+		return "", err
+	}
+	return rate.Currency, nil
+}
+
+// resolveCurrency returns the exchange rate of a currency the shop sells in; an empty
+// code means the base currency
+func (s *ShopService) resolveCurrency(ctx context.Context, code string) (exchangeRate, error) {
+	code = util.NormalizeCurrency(code)
+	base := util.BaseCurrency()
+	if code == "" || code == base {
+		return exchangeRate{Currency: base, Rate: 1}, nil
+	}
+
+	currency, err := s.currencyRepo.GetByCode(ctx, code)
+	if err != nil || !currency.Active {
+		return exchangeRate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, code)
+	}
+	return exchangeRate{Currency: currency.Code, Rate: currency.Rate}, nil
+}
+
+// priceTarget looks up the product, and the variant if one is given, a price is for
+func (s *ShopService) priceTarget(ctx context.Context, productID, variantID string) (*models.Product, *uuid.UUID, error) {
+	product, err := s.productsRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if variantID == "" {
+		return product, nil, nil
+	}
+	for _, variant := range product.Variants {
+		if variant.ID.String() == variantID {
+			id := variant.ID
+			return product, &id, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: variant not found", ErrInvalidVariant)
+}
+
+// localPrices converts base prices into a currency, preferring price overrides
+type localPrices struct {
+	rate      exchangeRate
+	overrides map[string]float64
+}
+
+// productPrices loads the price overrides in a currency for products
+func (s *ShopService) productPrices(ctx context.Context, productIDs []uuid.UUID, rate exchangeRate) (*localPrices, error) {
+	prices := &localPrices{rate: rate, overrides: make(map[string]float64)}
+	if rate.Currency == util.BaseCurrency() {
+		return prices, nil
+	}
+
+	overrides, err := s.currencyRepo.ProductPrices(ctx, productIDs, rate.Currency)
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range overrides {
+		prices.overrides[priceKey(override.ProductID, override.VariantID)] = override.Price
+	}
+	return prices, nil
+}
+
+// price returns a product's or variant's price in the currency given its base price
+func (p *localPrices) price(productID uuid.UUID, variantID *uuid.UUID, basePrice float64) float64 {
+	if price, ok := p.overrides[priceKey(productID, variantID)]; ok {
+		return price
+	}
+	return roundMoney(basePrice*p.rate.Rate, p.rate.Currency)
+}
+
+// priceKey identifies the product or variant a price override is for
+func priceKey(productID uuid.UUID, variantID *uuid.UUID) string {
+	if variantID == nil {
+		return productID.String()
+	}
+	return productID.String() + "/" + variantID.String()
+}
+
+// applyCurrencyInput validates input and copies it onto currency
+func applyCurrencyInput(currency *models.Currency, input models.CurrencyInput) error {
+	code := util.NormalizeCurrency(input.Code)
+	if code == util.BaseCurrency() {
+		return fmt.Errorf("%w: %s is the base currency", ErrInvalidCurrency, code)
+	}
+
+	currency.Code = code
+	currency.Name = input.Name
+	currency.Symbol = input.Symbol
+	currency.Rate = input.Rate
+	currency.Active = input.Active == nil || *input.Active
+	return nil
+}
+
+// toQuoteCurrency converts an amount set in the base currency, like a fixed discount
+// or a shipping cost, into the quote's currency
+func toQuoteCurrency(amount float64, quote *models.OrderQuote) float64 {
+	return roundMoney(amount*quote.ExchangeRate, quote.Currency)
+}
+
+// roundMoney rounds an amount to the smallest unit of its currency
+func roundMoney(amount float64, currency string) float64 {
+	return util.RoundCurrency(amount, currency)
+}
+
+// formatAmount formats an amount with its currency's decimals, e.g. "UGX 50000"
+func formatAmount(amount float64, currency string) string {
+	return currency + " " + strconv.FormatFloat(amount, 'f', util.CurrencyDecimals(currency), 64)
+}
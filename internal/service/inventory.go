@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInsufficientStock is returned when there isn't enough available stock for a cart
+// item or order line
+var ErrInsufficientStock = errors.New("not enough stock")
+
+// DefaultReservationTTL is how long checkout holds stock for an unpaid order when
+// STOCK_RESERVATION_MINUTES isn't set
+const DefaultReservationTTL = 15 * time.Minute
+
+// reservationTTL returns how long checkout holds stock for an unpaid order
+func reservationTTL() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("STOCK_RESERVATION_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultReservationTTL
+}
+
+// ExpireReservations cancels the orders whose stock reservations expired before they
+// were paid for, giving back what they held, and returns how many were canceled. An order
+// paid for in the meantime keeps its reservations, which the payment takes off stock.
+func (s *ShopService) ExpireReservations(ctx context.Context) (int, error) {
+	orderIDs, err := s.reservationsRepo.ExpiredOrders(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	canceled := 0
+	for _, id := range orderIDs {
+		ok, err := s.cancelUnpaidOrder(ctx, id)
+		if err != nil {
+			log.Printf("Failed to cancel order %s after its stock reservation expired: %v", id, err)
+			continue
+		}
+		if ok {
+			canceled++
+			continue
+		}
+
+		// The order wasn't awaiting payment any more: a canceled one gives its stock back,
+		// and one that was paid for takes it, if the payment hasn't already. Reservations
+		// left behind by an order that no longer exists just go back to stock.
+		order, err := s.ordersRepo.GetByID(ctx, id.String())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load order %s with expired stock reservations: %v", id, err)
+			continue
+		}
+		if order == nil || order.Status == "canceled" {
+			err = s.reservationsRepo.Release(ctx, id)
+		} else {
+			err = s.reservationsRepo.Convert(ctx, id)
+		}
+		if err != nil {
+			log.Printf("Failed to settle expired stock reservations of order %s: %v", id, err)
+		}
+	}
+	return canceled, nil
+}
+
+// cancelUnpaidOrder cancels an order still awaiting payment, giving back the stock,
+// promotion uses and credit it held and voiding the gift cards bought in it, and
+// returns false if it was no longer awaiting payment
+func (s *ShopService) cancelUnpaidOrder(ctx context.Context, id uuid.UUID) (bool, error) {
+	// Canceling first keeps a payment landing now from losing the stock it's paying for
+	ok, err := s.ordersRepo.CancelPending(ctx, id)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := s.reservationsRepo.Release(ctx, id); err != nil {
+		return true, err
+	}
+
+	order, err := s.ordersRepo.GetByID(ctx, id.String())
+	if err != nil {
+		return true, err
+	}
+	for _, redemption := range order.Promotions {
+		if err := s.promotionsRepo.Release(ctx, redemption.PromotionID); err != nil {
+			return true, err
+		}
+	}
+	if err := s.creditsRepo.ReleaseOrder(ctx, id, nil, "Order "+id.String()+" canceled"); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// RunReservationExpiry expires reservations every interval until ctx is cancelled
+func (s *ShopService) RunReservationExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if canceled, err := s.ExpireReservations(ctx); err != nil {
+			log.Printf("Failed to expire stock reservations: %v", err)
+		} else if canceled > 0 {
+			log.Printf("Canceled %d unpaid orders whose stock reservations expired", canceled)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reserveStock holds the stock of an order's cart items for ttl. A bundle holds its
+// components' stock, so paying for it takes them off stock on hand; gift cards have none.
+func (s *ShopService) reserveStock(ctx context.Context, order *models.Order, items []models.CartItem, ttl time.Duration) error {
+	expiresAt := time.Now().Add(ttl)
+	reservations := make([]models.StockReservation, 0, len(items))
+	for _, item := range items {
+		if item.Product.Type == models.ProductGiftCard {
+			continue
+		}
+		if item.Product.Type == models.ProductBundle {
+			for _, component := range item.Product.Components {
+				reservations = append(reservations, models.StockReservation{
+					ProductID: component.ProductID,
+					VariantID: component.VariantID,
+					UserID:    order.UserID,
+					OrderID:   order.ID,
+					Quantity:  item.Quantity * component.Quantity,
+					ExpiresAt: expiresAt,
+				})
+			}
+			continue
+		}
+		reservations = append(reservations, models.StockReservation{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			UserID:    order.UserID,
+			OrderID:   order.ID,
+			Quantity:  item.Quantity,
+			ExpiresAt: expiresAt,
+		})
+	}
+
+	ok, err := s.reservationsRepo.Reserve(ctx, reservations)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// setAvailableStock sets the products' and their variants' available stock from
+// their stock on hand and active reservations, and bundles' from their components'
+func (s *ShopService) setAvailableStock(ctx context.Context, products []models.Product) error {
+	ids := make([]uuid.UUID, 0, len(products))
+	for i := range products {
+		ids = append(ids, products[i].ID)
+		for _, component := range products[i].Components {
+			ids = append(ids, component.ProductID)
+		}
+	}
+	reserved, err := s.reservationsRepo.Reserved(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	held := make(map[string]int, len(reserved))
+	for _, r := range reserved {
+		held[priceKey(r.ProductID, r.VariantID)] = r.Quantity
+	}
+
+	for i := range products {
+		product := &products[i]
+		if product.Type == models.ProductBundle {
+			product.AvailableStock = bundleAvailable(product, held)
+			continue
+		}
+		product.AvailableStock = max(product.StockQuantity-held[priceKey(product.ID, nil)], 0)
+		for j := range product.Variants {
+			variant := &product.Variants[j]
+			variant.AvailableStock = max(variant.StockQuantity-held[priceKey(product.ID, &variant.ID)], 0)
+		}
+	}
+	return nil
+}
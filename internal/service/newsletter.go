@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/repository"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+)
+
+// DigestInterval is how often each subscriber receives the new-post digest
+const DigestInterval = 7 * 24 * time.Hour
+
+var (
+	// ErrInvalidSubscriptionToken is returned for an unknown confirm or unsubscribe token
+	ErrInvalidSubscriptionToken = errors.New("invalid or expired subscription token")
+	// ErrEmailRequired is returned when an anonymous visitor subscribes without an email
+	ErrEmailRequired = errors.New("email is required")
+)
+
+// TemplateMailer sends templated emails
+type TemplateMailer interface {
+	SendTemplateEmail(to string, template util.EmailTemplate, data interface{}) error
+}
+
+// DigestPost is a post listed in a newsletter digest
+type DigestPost struct {
+	Title   string
+	Excerpt string
+	URL     string
+}
+
+// NewsletterService manages newsletter subscriptions and the weekly digest
+type NewsletterService struct {
+	subscribersRepo repository.Subscribers
+	postRepo        repository.PostRepository
+	mailer          TemplateMailer
+	siteURL         string
+	apiURL          string
+}
+
+// NewNewsletterService creates a new newsletter service. Post links point at SITE_URL
+// and confirm/unsubscribe links at API_URL.
+func NewNewsletterService(subscribersRepo repository.Subscribers, postRepo repository.PostRepository) *NewsletterService {
+	siteURL := os.Getenv("SITE_URL")
+	if siteURL == "" {
+		siteURL = "http://localhost:1313"
+	}
+	apiURL := os.Getenv("API_URL")
+	if apiURL == "" {
+		apiURL = "http://localhost:8080"
+	}
+
+	return &NewsletterService{
+		subscribersRepo: subscribersRepo,
+		postRepo:        postRepo,
+		siteURL:         strings.TrimRight(siteURL, "/"),
+		apiURL:          strings.TrimRight(apiURL, "/"),
+	}
+}
+
+// SetMailer sets the mailer used for confirmation and digest emails; without one
+// subscribers are stored but no emails are sent
+func (s *NewsletterService) SetMailer(mailer TemplateMailer) {
+	s.mailer = mailer
+}
+
+// Subscribe signs an email address up for the newsletter and sends a confirmation
+// email. Signing up again updates the chosen categories, and re-sends the
+// confirmation if the address was never confirmed or had unsubscribed. Anyone can
+// enter an address, so a change to an active subscription only takes effect once it
+// is confirmed by email, unless it comes from the account the subscription belongs to.
+func (s *NewsletterService) Subscribe(ctx context.Context, email, userID string, categories []string) (*models.Subscriber, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, ErrEmailRequired
+	}
+	categories = util.RemoveDuplicates(categories)
+
+	subscriber, err := s.subscribersRepo.GetByEmail(ctx, email)
+	if err != nil {
+		unsubscribeToken, err := util.GenerateRandomString(32)
+		if err != nil {
+			return nil, err
+		}
+		subscriber = &models.Subscriber{
+			Email:            email,
+			UserID:           userID,
+			Categories:       categories,
+			Status:           models.SubscriberPending,
+			UnsubscribeToken: unsubscribeToken,
+		}
+		if err := s.requestConfirmation(ctx, subscriber, true); err != nil {
+			return nil, err
+		}
+		return subscriber, nil
+	}
+
+	if subscriber.Status == models.SubscriberActive {
+		if userID != "" && subscriber.UserID == userID {
+			subscriber.Categories = categories
+			if err := s.subscribersRepo.Update(ctx, subscriber); err != nil {
+				return nil, err
+			}
+			return subscriber, nil
+		}
+
+		subscriber.PendingCategories = categories
+		subscriber.PendingUserID = userID
+		if err := s.requestConfirmation(ctx, subscriber, false); err != nil {
+			return nil, err
+		}
+		return subscriber, nil
+	}
+
+	subscriber.Categories = categories
+	if userID != "" {
+		subscriber.UserID = userID
+	}
+	subscriber.Status = models.SubscriberPending
+	subscriber.UnsubscribedAt = nil
+	if err := s.requestConfirmation(ctx, subscriber, false); err != nil {
+		return nil, err
+	}
+	return subscriber, nil
+}
+
+// Confirm activates the subscription with a confirmation token, or applies the change
+// waiting on an active one
+func (s *NewsletterService) Confirm(ctx context.Context, token string) (*models.Subscriber, error) {
+	if token == "" {
+		return nil, ErrInvalidSubscriptionToken
+	}
+	subscriber, err := s.subscribersRepo.GetByConfirmToken(ctx, token)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionToken
+	}
+
+	if subscriber.ChangePending() {
+		subscriber.Categories = subscriber.PendingCategories
+		if subscriber.PendingUserID != "" {
+			subscriber.UserID = subscriber.PendingUserID
+		}
+		subscriber.PendingCategories = nil
+		subscriber.PendingUserID = ""
+		subscriber.ConfirmToken = ""
+		if err := s.subscribersRepo.Update(ctx, subscriber); err != nil {
+			return nil, err
+		}
+		return subscriber, nil
+	}
+	if subscriber.Status != models.SubscriberPending {
+		return nil, ErrInvalidSubscriptionToken
+	}
+
+	now := time.Now()
+	subscriber.Status = models.SubscriberActive
+	subscriber.ConfirmToken = ""
+	subscriber.ConfirmedAt = &now
+	// The first digest only covers posts published after confirming
+	subscriber.LastDigestAt = &now
+	if err := s.subscribersRepo.Update(ctx, subscriber); err != nil {
+		return nil, err
+	}
+	return subscriber, nil
+}
+
+// Unsubscribe stops all newsletter emails to the subscriber with an unsubscribe token
+func (s *NewsletterService) Unsubscribe(ctx context.Context, token string) (*models.Subscriber, error) {
+	if token == "" {
+		return nil, ErrInvalidSubscriptionToken
+	}
+	subscriber, err := s.subscribersRepo.GetByUnsubscribeToken(ctx, token)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionToken
+	}
+	if subscriber.Status == models.SubscriberUnsubscribed {
+		return subscriber, nil
+	}
+
+	now := time.Now()
+	subscriber.Status = models.SubscriberUnsubscribed
+	subscriber.ConfirmToken = ""
+	subscriber.UnsubscribedAt = &now
+	if err := s.subscribersRepo.Update(ctx, subscriber); err != nil {
+		return nil, err
+	}
+	return subscriber, nil
+}
+
+// Stats counts subscribers by status
+func (s *NewsletterService) Stats(ctx context.Context) (*models.SubscriberStats, error) {
+	return s.subscribersRepo.Stats(ctx)
+}
+
+// ListSubscribers returns every subscriber, oldest first
+func (s *NewsletterService) ListSubscribers(ctx context.Context) ([]models.Subscriber, error) {
+	return s.subscribersRepo.List(ctx)
+}
+
+// SendDueDigests emails each active subscriber whose last digest is at least a
+// DigestInterval old the posts published since then, and returns how many were sent
+func (s *NewsletterService) SendDueDigests(ctx context.Context) (int, error) {
+	if s.mailer == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	subscribers, err := s.subscribersRepo.ListDueForDigest(ctx, now.Add(-DigestInterval))
+	if err != nil {
+		return 0, err
+	}
+	if len(subscribers) == 0 {
+		return 0, nil
+	}
+
+	// Fetch the posts once for the longest window any subscriber needs
+	since := now.Add(-DigestInterval)
+	for _, subscriber := range subscribers {
+		if subscriber.LastDigestAt != nil && subscriber.LastDigestAt.Before(since) {
+			since = *subscriber.LastDigestAt
+		}
+	}
+	published := true
+	posts, _, err := s.postRepo.Find(ctx, models.PostQuery{
+		Published:      &published,
+		PublishedAfter: &since,
+		Locales:        []string{util.DefaultLanguage()},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range subscribers {
+		subscriber := &subscribers[i]
+		digest := s.digestPosts(subscriber, posts, now)
+		if len(digest) > 0 {
+			err := s.mailer.SendTemplateEmail(subscriber.Email, util.NewsletterDigestTemplate, map[string]interface{}{
+				"Email":          subscriber.Email,
+				"Posts":          digest,
+				"UnsubscribeURL": s.unsubscribeURL(subscriber),
+				"Year":           now.Year(),
+			})
+			if err != nil {
+				log.Printf("Failed to send newsletter digest to %s: %v", subscriber.Email, err)
+				continue
+			}
+			sent++
+		}
+
+		subscriber.LastDigestAt = &now
+		if err := s.subscribersRepo.Update(ctx, subscriber); err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+// RunDigests sends due digests every interval until ctx is cancelled
+func (s *NewsletterService) RunDigests(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if sent, err := s.SendDueDigests(ctx); err != nil {
+			log.Printf("Failed to send newsletter digests: %v", err)
+		} else if sent > 0 {
+			log.Printf("Sent %d newsletter digests", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// digestPosts picks the posts published since the subscriber's last digest in the
+// categories they follow
+func (s *NewsletterService) digestPosts(subscriber *models.Subscriber, posts []*models.Post, now time.Time) []DigestPost {
+	since := now.Add(-DigestInterval)
+	if subscriber.LastDigestAt != nil {
+		since = *subscriber.LastDigestAt
+	}
+
+	var digest []DigestPost
+	for _, post := range posts {
+		if post.PublishedAt == nil || post.PublishedAt.Before(since) {
+			continue
+		}
+		if len(subscriber.Categories) > 0 && !sharesCategory(post.Categories, subscriber.Categories) {
+			continue
+		}
+		digest = append(digest, DigestPost{
+			Title:   post.Title,
+			Excerpt: post.Excerpt,
+			URL:     s.siteURL + "/blog/" + post.ID.String() + "/",
+		})
+	}
+	return digest
+}
+
+// requestConfirmation saves the subscriber with a fresh confirmation token and emails it
+func (s *NewsletterService) requestConfirmation(ctx context.Context, subscriber *models.Subscriber, isNew bool) error {
+	token, err := util.GenerateRandomString(32)
+	if err != nil {
+		return err
+	}
+	subscriber.ConfirmToken = token
+
+	if isNew {
+		err = s.subscribersRepo.Create(ctx, subscriber)
+	} else {
+		err = s.subscribersRepo.Update(ctx, subscriber)
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.mailer == nil {
+		return nil
+	}
+
+	email := subscriber.Email
+	confirmURL := s.apiURL + "/api/newsletter/confirm?token=" + url.QueryEscape(token)
+	// Send in the background so a slow mail server doesn't hold up the request
+	go func() {
+		err := s.mailer.SendTemplateEmail(email, util.NewsletterConfirmTemplate, map[string]interface{}{
+			"Email":      email,
+			"ConfirmURL": confirmURL,
+			"Year":       time.Now().Year(),
+		})
+		if err != nil {
+			log.Printf("Failed to send newsletter confirmation to %s: %v", email, err)
+		}
+	}()
+	return nil
+}
+
+// unsubscribeURL returns the one-click unsubscribe link for a subscriber
+func (s *NewsletterService) unsubscribeURL(subscriber *models.Subscriber) string {
+	return s.apiURL + "/api/newsletter/unsubscribe?token=" + url.QueryEscape(subscriber.UnsubscribeToken)
+}
+
+// sharesCategory reports whether any of a post's categories is in categories
+func sharesCategory(postCategories, categories []string) bool {
+	for _, category := range postCategories {
+		if util.Contains(categories, category) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidPromotion is returned when a promotion's settings don't make sense
+	ErrInvalidPromotion = errors.New("invalid promotion")
+	// ErrInvalidCoupon is returned when a coupon code can't be applied to the cart
+	ErrInvalidCoupon = errors.New("coupon cannot be applied")
+	// ErrPromotionUnavailable is returned when a promotion runs out while placing the order
+	ErrPromotionUnavailable = errors.New("promotion is no longer available")
+)
+
+// CreatePromotion creates a coupon or automatic promotion
+func (s *ShopService) CreatePromotion(ctx context.Context, input models.PromotionInput) (*models.Promotion, error) {
+	promotion := &models.Promotion{}
+	if err := applyPromotionInput(promotion, input); err != nil {
+		return nil, err
+	}
+	if err := s.promotionsRepo.Create(ctx, promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// ListPromotions returns every promotion, highest priority first
+func (s *ShopService) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	return s.promotionsRepo.List(ctx)
+}
+
+// GetPromotion returns a promotion by ID
+func (s *ShopService) GetPromotion(ctx context.Context, id string) (*models.Promotion, error) {
+	return s.promotionsRepo.GetByID(ctx, id)
+}
+
+// UpdatePromotion changes a promotion's settings; its usage count is kept
+func (s *ShopService) UpdatePromotion(ctx context.Context, id string, input models.PromotionInput) (*models.Promotion, error) {
+	promotion, err := s.promotionsRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPromotionInput(promotion, input); err != nil {
+		return nil, err
+	}
+	if err := s.promotionsRepo.Update(ctx, promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// DeletePromotion deletes a promotion; orders that used it keep their discounts
+func (s *ShopService) DeletePromotion(ctx context.Context, id string) error {
+	return s.promotionsRepo.Delete(ctx, id)
+}
+
+// applyPromotionInput validates input and copies it onto promotion
+func applyPromotionInput(promotion *models.Promotion, input models.PromotionInput) error {
+	switch input.Type {
+	case models.PromotionPercentage:
+		if input.Value <= 0 || input.Value > 100 {
+			return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidPromotion)
+		}
+	case models.PromotionFixedAmount:
+		if input.Value <= 0 {
+			return fmt.Errorf("%w: amount must be greater than 0", ErrInvalidPromotion)
+		}
+	case models.PromotionBuyXGetY:
+		if input.BuyQuantity < 1 || input.GetQuantity < 1 {
+			return fmt.Errorf("%w: buy and get quantities must be at least 1", ErrInvalidPromotion)
+		}
+		if input.GetPercent == 0 {
+			input.GetPercent = 100
+		}
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+	for _, id := range input.CategoryIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("%w: invalid category ID %q", ErrInvalidPromotion, id)
+		}
+	}
+
+	var code *string
+	if trimmed := strings.ToUpper(strings.TrimSpace(input.Code)); trimmed != "" {
+		code = &trimmed
+	}
+
+	promotion.Name = input.Name
+	promotion.Description = input.Description
+	promotion.Code = code
+	promotion.Type = input.Type
+	promotion.Value = input.Value
+	promotion.BuyQuantity = input.BuyQuantity
+	promotion.GetQuantity = input.GetQuantity
+	promotion.GetPercent = input.GetPercent
+	promotion.CategoryIDs = util.RemoveDuplicates(input.CategoryIDs)
+	promotion.MinSubtotal = input.MinSubtotal
+	promotion.FirstOrderOnly = input.FirstOrderOnly
+	promotion.UsageLimit = input.UsageLimit
+	promotion.PerUserLimit = input.PerUserLimit
+	promotion.StartsAt = input.StartsAt
+	promotion.EndsAt = input.EndsAt
+	promotion.Stackable = input.Stackable
+	promotion.Priority = input.Priority
+	promotion.Active = input.Active == nil || *input.Active
+	return nil
+}
+
+// promotionCandidate is a promotion being considered for a cart
+type promotionCandidate struct {
+	promotion *models.Promotion
+	coupon    bool
+}
+
+// applyPromotions applies the automatic promotions and the customer's coupons to the
+// quote's items, highest priority first. Stackable promotions combine with each other;
+// a promotion that isn't stackable is only applied when nothing else is. Automatic
+// promotions that don't apply are skipped, while a coupon that doesn't apply is an
+// error so the customer knows why.
+func (s *ShopService) applyPromotions(ctx context.Context, userID string, quote *models.OrderQuote, categories [][]string, couponCodes []string) error {
+	now := time.Now()
+
+	automatic, err := s.promotionsRepo.ListAutomatic(ctx, now)
+	if err != nil {
+		return err
+	}
+	candidates := make([]promotionCandidate, 0, len(automatic)+len(couponCodes))
+	for i := range automatic {
+		candidates = append(candidates, promotionCandidate{promotion: &automatic[i]})
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range couponCodes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		promotion, err := s.promotionsRepo.GetByCode(ctx, code)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not a valid code", ErrInvalidCoupon, code)
+		}
+		candidates = append(candidates, promotionCandidate{promotion: promotion, coupon: true})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].promotion.Priority > candidates[j].promotion.Priority
+	})
+
+	check := &eligibilityCheck{s: s, ctx: ctx, userID: userID, now: now}
+	exclusive := false
+	for _, candidate := range candidates {
+		promotion := candidate.promotion
+		reject := func(reason string) error {
+			if candidate.coupon {
+				return fmt.Errorf("%w: %s %s", ErrInvalidCoupon, *promotion.Code, reason)
+			}
+			return nil
+		}
+
+		if exclusive || (!promotion.Stackable && len(quote.Promotions) > 0) {
+			if err := reject("cannot be combined with other promotions"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		reason, err := check.ineligible(promotion, quote)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			if err := reject(reason); err != nil {
+				return err
+			}
+			continue
+		}
+
+		eligible, err := check.eligibleLines(promotion, quote, categories)
+		if err != nil {
+			return err
+		}
+		amount, applied := applyPromotion(promotion, quote, eligible)
+		if !applied {
+			if err := reject("does not apply to the items in your cart"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		redemption := models.PromotionRedemption{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			Type:        promotion.Type,
+			Amount:      amount,
+		}
+		if promotion.Code != nil {
+			redemption.Code = *promotion.Code
+		}
+		quote.Promotions = append(quote.Promotions, redemption)
+		exclusive = !promotion.Stackable
+	}
+
+	return nil
+}
+
+// eligibilityCheck checks promotions against the customer placing an order, looking
+// up their order history only when a promotion needs it
+type eligibilityCheck struct {
+	s          *ShopService
+	ctx        context.Context
+	userID     string
+	now        time.Time
+	orderCount *int64
+}
+
+// ineligible returns why the promotion can't be used on this order, or "" if it can
+func (c *eligibilityCheck) ineligible(promotion *models.Promotion, quote *models.OrderQuote) (string, error) {
+	minSubtotal := toQuoteCurrency(promotion.MinSubtotal, quote)
+	switch {
+	case !promotion.Active:
+		return "is not active", nil
+	case promotion.StartsAt != nil && c.now.Before(*promotion.StartsAt):
+		return "is not valid yet", nil
+	case promotion.EndsAt != nil && !c.now.Before(*promotion.EndsAt):
+		return "has expired", nil
+	case promotion.UsageLimit > 0 && promotion.UsageCount >= promotion.UsageLimit:
+		return "has been fully redeemed", nil
+	case goodsSubtotal(quote) < minSubtotal:
+		return "requires a subtotal of at least " + formatAmount(minSubtotal, quote.Currency), nil
+	}
+
+	if promotion.FirstOrderOnly {
+		if c.orderCount == nil {
+			count, err := c.s.ordersRepo.CountByUser(c.ctx, c.userID)
+			if err != nil {
+				return "", err
+			}
+			c.orderCount = &count
+		}
+		if *c.orderCount > 0 {
+			return "is only valid on your first order", nil
+		}
+	}
+
+	if promotion.PerUserLimit > 0 {
+		used, err := c.s.promotionsRepo.CountUserRedemptions(c.ctx, promotion.ID, c.userID)
+		if err != nil {
+			return "", err
+		}
+		if used >= int64(promotion.PerUserLimit) {
+			return "has already been used the maximum number of times", nil
+		}
+	}
+
+	return "", nil
+}
+
+// eligibleLines reports which order lines the promotion applies to; it never applies
+// to gift cards
+func (c *eligibilityCheck) eligibleLines(promotion *models.Promotion, quote *models.OrderQuote, categories [][]string) ([]bool, error) {
+	eligible := make([]bool, len(categories))
+	if len(promotion.CategoryIDs) == 0 {
+		for i := range eligible {
+			eligible[i] = !isGiftCardLine(&quote.Items[i])
+		}
+		return eligible, nil
+	}
+
+	tree, err := c.s.promotionsRepo.ExpandCategories(c.ctx, promotion.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i, lineCategories := range categories {
+		if isGiftCardLine(&quote.Items[i]) {
+			continue
+		}
+		for _, category := range lineCategories {
+			if util.Contains(tree, category) {
+				eligible[i] = true
+				break
+			}
+		}
+	}
+	return eligible, nil
+}
+
+// goodsSubtotal is the order subtotal without the gift cards in it, which don't count
+// towards a promotion's minimum or free shipping
+func goodsSubtotal(quote *models.OrderQuote) float64 {
+	subtotal := quote.Subtotal
+	for i := range quote.Items {
+		if isGiftCardLine(&quote.Items[i]) {
+			subtotal -= quote.Items[i].LineTotal()
+		}
+	}
+	return roundMoney(subtotal, quote.Currency)
+}
+
+// applyPromotion takes the promotion off the eligible lines of the quote, recording
+// each line's share, and returns the total taken off and whether it applied at all
+func applyPromotion(promotion *models.Promotion, quote *models.OrderQuote, eligible []bool) (float64, bool) {
+	var shares []float64
+	switch promotion.Type {
+	case models.PromotionFreeShipping:
+		quote.FreeShipping = true
+		return 0, true
+	case models.PromotionPercentage:
+		shares = make([]float64, len(quote.Items))
+		for i := range quote.Items {
+			if eligible[i] {
+				shares[i] = roundMoney(lineRemaining(&quote.Items[i], quote.Currency)*promotion.Value/100, quote.Currency)
+			}
+		}
+	case models.PromotionFixedAmount:
+		shares = spreadAmount(quote.Items, eligible, toQuoteCurrency(promotion.Value, quote), quote.Currency)
+	case models.PromotionBuyXGetY:
+		shares = buyXGetYShares(promotion, quote.Items, eligible, quote.Currency)
+	}
+
+	total := 0.0
+	for i, share := range shares {
+		if share <= 0 {
+			continue
+		}
+		item := &quote.Items[i]
+		discount := models.OrderItemDiscount{PromotionID: promotion.ID, Amount: share}
+		if promotion.Code != nil {
+			discount.Code = *promotion.Code
+		}
+		item.Discounts = append(item.Discounts, discount)
+		item.DiscountAmount = roundMoney(item.DiscountAmount+share, quote.Currency)
+		total += share
+	}
+
+	total = roundMoney(total, quote.Currency)
+	return total, total > 0
+}
+
+// spreadAmount splits a fixed discount across the eligible lines in proportion to what
+// is left to pay on each, never taking off more than that
+func spreadAmount(items []models.OrderItem, eligible []bool, amount float64, currency string) []float64 {
+	shares := make([]float64, len(items))
+	base := 0.0
+	last := -1
+	for i := range items {
+		if eligible[i] && lineRemaining(&items[i], currency) > 0 {
+			base += lineRemaining(&items[i], currency)
+			last = i
+		}
+	}
+	if last < 0 {
+		return shares
+	}
+	if amount > base {
+		amount = base
+	}
+
+	// The last line takes whatever rounding leaves over so the shares add up exactly
+	left := roundMoney(amount, currency)
+	for i := range items {
+		if !eligible[i] || lineRemaining(&items[i], currency) <= 0 {
+			continue
+		}
+		if i == last {
+			shares[i] = left
+			break
+		}
+		shares[i] = roundMoney(amount*lineRemaining(&items[i], currency)/base, currency)
+		left = roundMoney(left-shares[i], currency)
+	}
+	return shares
+}
+
+// buyXGetYShares discounts GetQuantity units for every BuyQuantity + GetQuantity eligible
+// units in the cart, picking the cheapest units as the discounted ones
+func buyXGetYShares(promotion *models.Promotion, items []models.OrderItem, eligible []bool, currency string) []float64 {
+	shares := make([]float64, len(items))
+
+	type unit struct {
+		line  int
+		price float64
+	}
+	var units []unit
+	for i := range items {
+		if !eligible[i] || items[i].Quantity == 0 {
+			continue
+		}
+		// Discount what is left of each unit after earlier promotions
+		price := lineRemaining(&items[i], currency) / float64(items[i].Quantity)
+		for q := 0; q < items[i].Quantity; q++ {
+			units = append(units, unit{line: i, price: price})
+		}
+	}
+
+	group := promotion.BuyQuantity + promotion.GetQuantity
+	if group <= 0 {
+		return shares
+	}
+	free := len(units) / group * promotion.GetQuantity
+	if free == 0 {
+		return shares
+	}
+
+	sort.SliceStable(units, func(i, j int) bool { return units[i].price < units[j].price })
+	for _, u := range units[:free] {
+		shares[u.line] += u.price * promotion.GetPercent / 100
+	}
+	for i := range shares {
+		shares[i] = roundMoney(shares[i], currency)
+	}
+	return shares
+}
+
+// lineRemaining is what is left to pay on a line after the discounts applied so far
+func lineRemaining(item *models.OrderItem, currency string) float64 {
+	return roundMoney(item.LineTotal()-item.DiscountAmount, currency)
+}
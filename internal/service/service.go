@@ -9,9 +9,11 @@ import (
 
 // Service struct that combines all services
 type Service struct {
-    Auth *AuthService
-    Blog *BlogService
-    Shop *ShopService
+    Auth        *AuthService
+    Blog        *BlogService
+    Shop        *ShopService
+    Attribution *AttributionService
+    Newsletter  *NewsletterService
 }
 
 // NewService creates a new Service with all required dependencies
@@ -25,13 +27,18 @@ func NewService(
     // Create UserRepository adapter
     userRepo := &UserRepoAdapter{repos.Users}
     
-    // Create PostRepository adapter
-    postRepo := &PostRepoAdapter{repos.Posts}
+    // Posts live in Postgres unless another post store is configured
+    var postRepo repository.PostRepository = &PostRepoAdapter{repos.Posts}
+    if repos.PostStore != nil {
+        postRepo = repos.PostStore
+    }
     
     return &Service{
-        Auth: NewAuthService(userRepo, tokenRepo, jwtSecret, jwtTTL, refreshTTL),
-        Blog: NewBlogService(postRepo, userRepo),
-        Shop: NewShopService(repos.Products, repos.CartItems, repos.Orders),
+        Auth:        NewAuthService(userRepo, tokenRepo, jwtSecret, jwtTTL, refreshTTL),
+        Blog:        NewBlogService(postRepo, userRepo, repos.ReviewComments, repos.Products, repos.Series),
+        Shop:        NewShopService(repos.Products, repos.CartItems, repos.Orders, repos.SlugRedirects, repos.Promotions, repos.TaxZones, repos.Shipping, repos.Currencies, repos.Reservations, repos.StockLedger, repos.Subscriptions, repos.Credits),
+        Attribution: NewAttributionService(repos.Attribution, postRepo, repos.Products),
+        Newsletter:  NewNewsletterService(repos.Subscribers, postRepo),
     }
 }
 
@@ -189,6 +196,41 @@ func (a *PostRepoAdapter) GetByAuthor(ctx context.Context, authorID string, page
     return result, nil
 }
 
+func (a *PostRepoAdapter) GetByLocale(ctx context.Context, locale, fallback string, query models.PostQuery) ([]*models.Post, int64, error) {
+    posts, total, err := a.Posts.FindLocalized(ctx, locale, fallback, query)
+    if err != nil {
+        return nil, 0, err
+    }
+    return models.PagePosts(posts, 0, 0), total, nil
+}
+
+func (a *PostRepoAdapter) Find(ctx context.Context, query models.PostQuery) ([]*models.Post, int64, error) {
+    posts, total, err := a.Posts.Find(ctx, query)
+    if err != nil {
+        return nil, 0, err
+    }
+    return models.PagePosts(posts, 0, 0), total, nil
+}
+
+func (a *PostRepoAdapter) GetTranslations(ctx context.Context, groupID string) ([]*models.Post, error) {
+    if groupID == "" {
+        return []*models.Post{}, nil
+    }
+    posts, _, err := a.Posts.Find(ctx, models.PostQuery{TranslationGroupID: groupID})
+    if err != nil {
+        return nil, err
+    }
+    return models.PagePosts(posts, 0, 0), nil
+}
+
+func (a *PostRepoAdapter) GetByStatus(ctx context.Context, status string, page, limit int) ([]*models.Post, int64, error) {
+    posts, total, err := a.Posts.Find(ctx, models.PostQuery{Status: status, Page: page, Limit: limit})
+    if err != nil {
+        return nil, 0, err
+    }
+    return models.PagePosts(posts, 0, 0), total, nil
+}
+
 func (a *PostRepoAdapter) Update(ctx context.Context, post *models.Post) error {
     return a.Posts.Update(ctx, post.ID.String(), post)
 }
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+)
+
+var (
+	// ErrInvalidShipping is returned when a shipping zone or method's settings don't make sense
+	ErrInvalidShipping = errors.New("invalid shipping settings")
+	// ErrShippingMethodRequired is returned when an order that can be shipped doesn't pick a method
+	ErrShippingMethodRequired = errors.New("a shipping method must be chosen")
+	// ErrShippingMethodUnavailable is returned when the chosen method can't ship the order
+	ErrShippingMethodUnavailable = errors.New("shipping method is not available for this address")
+)
+
+// CreateShippingZone creates a shipping zone
+func (s *ShopService) CreateShippingZone(ctx context.Context, input models.ShippingZoneInput) (*models.ShippingZone, error) {
+	zone := &models.ShippingZone{}
+	if err := applyShippingZoneInput(zone, input); err != nil {
+		return nil, err
+	}
+	if err := s.shippingRepo.CreateZone(ctx, zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// ListShippingZones returns every shipping zone with its methods
+func (s *ShopService) ListShippingZones(ctx context.Context) ([]models.ShippingZone, error) {
+	return s.shippingRepo.ListZones(ctx)
+}
+
+// GetShippingZone returns a shipping zone with its methods
+func (s *ShopService) GetShippingZone(ctx context.Context, id string) (*models.ShippingZone, error) {
+	return s.shippingRepo.GetZone(ctx, id)
+}
+
+// UpdateShippingZone changes where a shipping zone covers
+func (s *ShopService) UpdateShippingZone(ctx context.Context, id string, input models.ShippingZoneInput) (*models.ShippingZone, error) {
+	zone, err := s.shippingRepo.GetZone(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyShippingZoneInput(zone, input); err != nil {
+		return nil, err
+	}
+	if err := s.shippingRepo.UpdateZone(ctx, zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// DeleteShippingZone deletes a shipping zone and its methods
+func (s *ShopService) DeleteShippingZone(ctx context.Context, id string) error {
+	return s.shippingRepo.DeleteZone(ctx, id)
+}
+
+// CreateShippingMethod adds a shipping method to a zone
+func (s *ShopService) CreateShippingMethod(ctx context.Context, zoneID string, input models.ShippingMethodInput) (*models.ShippingMethod, error) {
+	zone, err := s.shippingRepo.GetZone(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	method := &models.ShippingMethod{ShippingZoneID: zone.ID}
+	if err := applyShippingMethodInput(method, input); err != nil {
+		return nil, err
+	}
+	if err := s.shippingRepo.CreateMethod(ctx, method); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+// UpdateShippingMethod changes a shipping method; orders already placed keep what they were charged
+func (s *ShopService) UpdateShippingMethod(ctx context.Context, id string, input models.ShippingMethodInput) (*models.ShippingMethod, error) {
+	method, err := s.shippingRepo.GetMethod(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyShippingMethodInput(method, input); err != nil {
+		return nil, err
+	}
+	if err := s.shippingRepo.UpdateMethod(ctx, method); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+// DeleteShippingMethod deletes a shipping method
+func (s *ShopService) DeleteShippingMethod(ctx context.Context, id string) error {
+	return s.shippingRepo.DeleteMethod(ctx, id)
+}
+
+// QuoteShipping returns the shipping methods that can deliver cart items to an
+// address, in the zone's display order, with what each would cost
+func (s *ShopService) QuoteShipping(ctx context.Context, input models.ShippingQuoteInput) ([]models.ShippingOption, error) {
+	quote, _, err := s.priceCart(ctx, cartRequest{
+		UserID:      input.UserID,
+		CartIDs:     input.CartIDs,
+		CouponCodes: input.CouponCodes,
+		Country:     input.Country,
+		Region:      input.State,
+		Currency:    input.Currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quote.ShippingOptions, nil
+}
+
+// applyShippingZoneInput validates input and copies it onto zone
+func applyShippingZoneInput(zone *models.ShippingZone, input models.ShippingZoneInput) error {
+	countries := make([]string, 0, len(input.Countries))
+	for _, country := range input.Countries {
+		if country = strings.ToUpper(strings.TrimSpace(country)); country != "" {
+			countries = append(countries, country)
+		}
+	}
+	regions := make([]string, 0, len(input.Regions))
+	for _, region := range input.Regions {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	if len(regions) > 0 && len(countries) == 0 {
+		return fmt.Errorf("%w: regions need a country", ErrInvalidShipping)
+	}
+
+	zone.Name = input.Name
+	zone.Countries = util.RemoveDuplicates(countries)
+	zone.Regions = util.RemoveDuplicates(regions)
+	return nil
+}
+
+// applyShippingMethodInput validates input and copies it onto method
+func applyShippingMethodInput(method *models.ShippingMethod, input models.ShippingMethodInput) error {
+	switch input.Type {
+	case models.ShippingWeightBased, models.ShippingPriceTiered:
+		if len(input.Tiers) == 0 {
+			return fmt.Errorf("%w: %s methods need at least one tier", ErrInvalidShipping, input.Type)
+		}
+	case models.ShippingFreeOver:
+		if input.FreeOver <= 0 {
+			return fmt.Errorf("%w: free_over must be greater than 0", ErrInvalidShipping)
+		}
+	}
+
+	tiers := append([]models.ShippingTier(nil), input.Tiers...)
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].Min < tiers[j].Min })
+
+	method.Name = input.Name
+	method.Description = input.Description
+	method.Type = input.Type
+	method.Cost = input.Cost
+	method.Tiers = tiers
+	method.FreeOver = input.FreeOver
+	method.Position = input.Position
+	method.Active = input.Active == nil || *input.Active
+	return nil
+}
+
+// applyShipping lists the shipping methods available for the quote's address and
+// charges the chosen one. Addresses no zone covers have no methods and ship free.
+func (s *ShopService) applyShipping(ctx context.Context, quote *models.OrderQuote, weight float64, req cartRequest) error {
+	quote.ShippingOptions = []models.ShippingOption{}
+	if strings.TrimSpace(req.Country) == "" {
+		return nil
+	}
+
+	zones, err := s.shippingRepo.ListZones(ctx)
+	if err != nil {
+		return err
+	}
+	zone := matchShippingZone(zones, req.Country, req.Region)
+	if zone == nil {
+		return nil
+	}
+
+	subtotal := roundMoney(goodsSubtotal(quote)-quote.DiscountTotal, quote.Currency)
+	for _, method := range zone.Methods {
+		if !method.Active {
+			continue
+		}
+		cost := shippingCost(&method, weight, subtotal, quote)
+		if quote.FreeShipping {
+			cost = 0
+		}
+		quote.ShippingOptions = append(quote.ShippingOptions, models.ShippingOption{
+			MethodID:    method.ID,
+			Name:        method.Name,
+			Description: method.Description,
+			Type:        method.Type,
+			Zone:        zone.Name,
+			Cost:        cost,
+		})
+	}
+
+	if req.ShippingMethodID == "" {
+		if req.RequireShipping && len(quote.ShippingOptions) > 0 {
+			return ErrShippingMethodRequired
+		}
+		return nil
+	}
+	for _, option := range quote.ShippingOptions {
+		if option.MethodID.String() == req.ShippingMethodID {
+			id := option.MethodID
+			quote.ShippingMethodID = &id
+			quote.ShippingMethod = option.Name
+			quote.ShippingCost = option.Cost
+			return nil
+		}
+	}
+	return ErrShippingMethodUnavailable
+}
+
+// matchShippingZone returns the most specific zone covering an address: one listing
+// its region, then one covering its whole country, then one covering everywhere else
+func matchShippingZone(zones []models.ShippingZone, country, region string) *models.ShippingZone {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	region = strings.TrimSpace(region)
+
+	var best *models.ShippingZone
+	bestScore := 0
+	for i := range zones {
+		zone := &zones[i]
+		score := 0
+		switch {
+		case len(zone.Countries) == 0:
+			score = 1
+		case !util.Contains(zone.Countries, country):
+			continue
+		case len(zone.Regions) == 0:
+			score = 2
+		case containsFold(zone.Regions, region):
+			score = 3
+		default:
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = zone, score
+		}
+	}
+	return best
+}
+
+// volumetricDivisor turns a parcel's volume in cm³ into the weight in kg carriers
+// charge for it
+const volumetricDivisor = 5000
+
+// shippingWeight is the weight a product is charged for when shipped: weight, or its
+// volumetric weight if it's bulkier than it is heavy
+func shippingWeight(product *models.Product, weight float64) float64 {
+	return max(weight, product.Length*product.Width*product.Height/volumetricDivisor)
+}
+
+// shippingCost is what a method charges for an order of the given weight and subtotal,
+// in the quote's currency. Methods' costs and thresholds are set in the base currency.
+func shippingCost(method *models.ShippingMethod, weight, subtotal float64, quote *models.OrderQuote) float64 {
+	if method.FreeOver > 0 && subtotal >= toQuoteCurrency(method.FreeOver, quote) {
+		return 0
+	}
+
+	cost := method.Cost
+	switch method.Type {
+	case models.ShippingWeightBased:
+		cost = tierCost(method.Tiers, weight, method.Cost, 1)
+	case models.ShippingPriceTiered:
+		cost = tierCost(method.Tiers, subtotal, method.Cost, quote.ExchangeRate)
+	}
+	return toQuoteCurrency(cost, quote)
+}
+
+// tierCost returns the cost of the highest tier value reaches, or fallback if it
+// reaches none; tiers are sorted by Min, and their Min is scaled by scale
+func tierCost(tiers []models.ShippingTier, value, fallback, scale float64) float64 {
+	cost := fallback
+	for _, tier := range tiers {
+		if value < tier.Min*scale {
+			break
+		}
+		cost = tier.Cost
+	}
+	return cost
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
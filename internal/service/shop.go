@@ -3,7 +3,11 @@ package service
 import (
     "context"
     "errors"
-    //"time"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+    "time"
     
     "github.com/adrianmcmains/blog-ecommerce/internal/models"
     "github.com/adrianmcmains/blog-ecommerce/internal/repository"
@@ -16,31 +20,74 @@ type ShopService struct {
     productsRepo  repository.Products
     cartItemsRepo repository.CartItems
     ordersRepo    repository.Orders
+    slugRepo      repository.SlugRedirects
+    promotionsRepo repository.Promotions
+    taxRepo       repository.TaxZones
+    shippingRepo  repository.Shipping
+    currencyRepo  repository.Currencies
+    reservationsRepo repository.StockReservations
+    ledgerRepo    repository.StockLedger
+    subscriptionsRepo repository.Subscriptions
+    creditsRepo   repository.Credits
+    mailer        TemplateMailer
+    charger       RenewalCharger
+    siteURL       string
 }
 
 func NewShopService(
     productsRepo repository.Products,
     cartItemsRepo repository.CartItems,
     ordersRepo repository.Orders,
+    slugRepo repository.SlugRedirects,
+    promotionsRepo repository.Promotions,
+    taxRepo repository.TaxZones,
+    shippingRepo repository.Shipping,
+    currencyRepo repository.Currencies,
+    reservationsRepo repository.StockReservations,
+    ledgerRepo repository.StockLedger,
+    subscriptionsRepo repository.Subscriptions,
+    creditsRepo repository.Credits,
 ) *ShopService {
+    // Links in subscription emails point at SITE_URL
+    siteURL := os.Getenv("SITE_URL")
+    if siteURL == "" {
+        siteURL = "http://localhost:1313"
+    }
+    
     return &ShopService{
         productsRepo:  productsRepo,
         cartItemsRepo: cartItemsRepo,
         ordersRepo:    ordersRepo,
+        slugRepo:      slugRepo,
+        promotionsRepo: promotionsRepo,
+        taxRepo:       taxRepo,
+        shippingRepo:  shippingRepo,
+        currencyRepo:  currencyRepo,
+        reservationsRepo: reservationsRepo,
+        ledgerRepo:    ledgerRepo,
+        subscriptionsRepo: subscriptionsRepo,
+        creditsRepo:   creditsRepo,
+        siteURL:       strings.TrimRight(siteURL, "/"),
     }
 }
 
 // Product methods
 func (s *ShopService) CreateProduct(ctx context.Context, input models.CreateProductInput) (*models.Product, error) {
-    // Create product
+    // Create product; its opening stock is recorded in the stock ledger once it's saved
     product := &models.Product{
         Name:          input.Name,
         Slug:          util.GenerateSlug(input.Name),
         Description:   input.Description,
         Price:         input.Price,
-        StockQuantity: input.StockQuantity,
+        SKU:           input.SKU,
         Image:         input.Image,
         Status:        "active",
+        Type:          productTypeOrDefault(input.Type),
+        TaxClass:      taxClassOrDefault(input.TaxClass),
+        Weight:        input.Weight,
+        Length:        input.Length,
+        Width:         input.Width,
+        Height:        input.Height,
     }
     
     // Add categories
@@ -53,32 +100,81 @@ func (s *ShopService) CreateProduct(ctx context.Context, input models.CreateProd
         }
     }
     
-    // Add variants
-    if len(input.Variants) > 0 {
-        for _, variantInput := range input.Variants {
-            product.Variants = append(product.Variants, models.ProductVariant{
-                Name:          variantInput.Name,
-                Price:         variantInput.Price,
-                StockQuantity: variantInput.StockQuantity,
-                SKU:           variantInput.SKU,
-            })
+    // Bundles are made of other products and have no options, variants or stock of their own
+    if err := s.checkBundleInput(ctx, uuid.Nil, input); err != nil {
+        return nil, err
+    }
+    var components []models.BundleComponent
+    if product.Type == models.ProductBundle {
+        var err error
+        if components, err = s.buildComponents(ctx, uuid.Nil, input.Components); err != nil {
+            return nil, err
         }
     }
     
+    // Add options and variants; the variants are the combinations of the options' values
+    options, planned, err := planVariants(product, input.Options, input.Variants)
+    if err != nil {
+        return nil, err
+    }
+    product.Options = options
+    for _, plan := range planned {
+        product.Variants = append(product.Variants, plan.Variant)
+    }
+    
     // Save product
     if err := s.productsRepo.Create(ctx, product); err != nil {
         return nil, err
     }
     
+    if product.Type == models.ProductBundle {
+        if err := s.productsRepo.ReplaceComponents(ctx, product.ID, components); err != nil {
+            return nil, err
+        }
+        product.Components = components
+        return product, nil
+    }
+    
+    // Gift cards have no stock
+    if product.Type == models.ProductGiftCard {
+        return product, nil
+    }
+    
+    if err := s.countStock(ctx, product, nil, input.StockQuantity, "Opening stock"); err != nil {
+        return nil, err
+    }
+    for i, plan := range planned {
+        if err := s.countStock(ctx, product, &product.Variants[i], plan.Stock, "Opening stock"); err != nil {
+            return nil, err
+        }
+    }
+    
     return product, nil
 }
 
 func (s *ShopService) GetProduct(ctx context.Context, id string) (*models.Product, error) {
-    return s.productsRepo.GetByID(ctx, id)
+    product, err := s.productsRepo.GetByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    
+    products := []models.Product{*product}
+    if err := s.setAvailableStock(ctx, products); err != nil {
+        return nil, err
+    }
+    return &products[0], nil
 }
 
 func (s *ShopService) ListProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, int64, error) {
-    return s.productsRepo.List(ctx, filter)
+    products, total, err := s.productsRepo.List(ctx, filter)
+    if err != nil {
+        return nil, 0, err
+    }
+    
+    if err := s.setAvailableStock(ctx, products); err != nil {
+        return nil, 0, err
+    }
+    return products, total, nil
 }
 
 func (s *ShopService) UpdateProduct(ctx context.Context, id string, input models.CreateProductInput) (*models.Product, error) {
@@ -88,19 +184,60 @@ func (s *ShopService) UpdateProduct(ctx context.Context, id string, input models
         return nil, err
     }
     
-    // Update fields
+    if err := s.checkBundleInput(ctx, product.ID, input); err != nil {
+        return nil, err
+    }
+    newType := productTypeOrDefault(input.Type)
+    if newType == models.ProductBundle && len(product.Variants) > 0 {
+        return nil, fmt.Errorf("%w: remove the product's variants before making it a bundle", ErrInvalidBundle)
+    }
+    var components []models.BundleComponent
+    if newType == models.ProductBundle {
+        if components, err = s.buildComponents(ctx, product.ID, input.Components); err != nil {
+            return nil, err
+        }
+    }
+    
+    // Update fields, keeping the old slug so existing links redirect
+    oldSlug := product.Slug
     product.Name = input.Name
     product.Slug = util.GenerateSlug(input.Name)
     product.Description = input.Description
     product.Price = input.Price
-    product.StockQuantity = input.StockQuantity
+    product.SKU = input.SKU
     product.Image = input.Image
+    product.Type = newType
+    product.TaxClass = taxClassOrDefault(input.TaxClass)
+    product.Weight = input.Weight
+    product.Length = input.Length
+    product.Width = input.Width
+    product.Height = input.Height
     
     // Save product
     if err := s.productsRepo.Update(ctx, id, product); err != nil {
         return nil, err
     }
     
+    if err := s.slugRepo.Record(ctx, models.SlugEntityProduct, id, oldSlug, product.Slug); err != nil {
+        return nil, err
+    }
+    
+    // A bundle's components are replaced as a whole; a simple product has none
+    if err := s.productsRepo.ReplaceComponents(ctx, product.ID, components); err != nil {
+        return nil, err
+    }
+    product.Components = components
+    if product.Type == models.ProductBundle || product.Type == models.ProductGiftCard {
+        return product, nil
+    }
+    
+    // A new stock level is a count of what's on hand, recorded in the stock ledger
+    if input.StockQuantity != product.StockQuantity {
+        if err := s.countStock(ctx, product, nil, input.StockQuantity, "Product edited"); err != nil {
+            return nil, err
+        }
+    }
+    
     return product, nil
 }
 
@@ -116,14 +253,27 @@ func (s *ShopService) AddToCart(ctx context.Context, input models.AddToCartInput
         return nil, err
     }
     
-    product, err := s.productsRepo.GetByID(ctx, input.ProductID)
+    product, err := s.GetProduct(ctx, input.ProductID)
     if err != nil {
         return nil, errors.New("product not found")
     }
     
-    // Check stock
-    if product.StockQuantity < input.Quantity {
-        return nil, errors.New("not enough stock")
+    // Pick the variant by ID or by its option values
+    variant, err := resolveVariant(product, input.VariantID, input.Options)
+    if err != nil {
+        return nil, err
+    }
+    
+    // Check stock, leaving out what checkouts in progress have reserved; gift cards have none
+    available := product.AvailableStock
+    if variant != nil {
+        available = variant.AvailableStock
+    }
+    if available < input.Quantity && product.Type != models.ProductGiftCard {
+        return nil, ErrInsufficientStock
+    }
+    if err := checkGiftCardQuantity(product, input.Quantity); err != nil {
+        return nil, err
     }
     
     // Parse user ID
@@ -139,13 +289,9 @@ func (s *ShopService) AddToCart(ctx context.Context, input models.AddToCartInput
         Quantity:  input.Quantity,
     }
     
-    // Add variant if specified
-    if input.VariantID != "" {
-        variantID, err := uuid.Parse(input.VariantID)
-        if err != nil {
-            return nil, err
-        }
-        cartItem.VariantID = &variantID
+    // Add variant if the product has them
+    if variant != nil {
+        cartItem.VariantID = &variant.ID
     }
     
     // Save cart item
@@ -167,6 +313,10 @@ func (s *ShopService) UpdateCartItem(ctx context.Context, id string, quantity in
         return nil, err
     }
     
+    if err := checkGiftCardQuantity(&cartItem.Product, quantity); err != nil {
+        return nil, err
+    }
+    
     // Update quantity
     cartItem.Quantity = quantity
     
@@ -183,85 +333,325 @@ func (s *ShopService) RemoveFromCart(ctx context.Context, id string) error {
 }
 
 // Order methods
-func (s *ShopService) CreateOrder(ctx context.Context, input models.CreateOrderInput) (*models.Order, error) {
-    // Parse user ID
-    userID, err := uuid.Parse(input.UserID)
+
+// QuoteCart prices cart items with the promotions and coupons that would apply if
+// they were ordered now, and the tax if a country is given
+func (s *ShopService) QuoteCart(ctx context.Context, input models.QuoteInput) (*models.OrderQuote, error) {
+    quote, _, err := s.priceCart(ctx, cartRequest{
+        UserID:           input.UserID,
+        CartIDs:          input.CartIDs,
+        CouponCodes:      input.CouponCodes,
+        Country:          input.Country,
+        Region:           input.State,
+        ShippingMethodID: input.ShippingMethodID,
+        Currency:         input.Currency,
+    })
+    return quote, err
+}
+
+// cartRequest is what priceCart needs to price a user's cart items
+type cartRequest struct {
+    UserID           string
+    CartIDs          []string
+    CouponCodes      []string
+    Country          string
+    Region           string
+    ShippingMethodID string
+    Currency         string
+    // RequireShipping makes choosing a shipping method mandatory when any are available
+    RequireShipping  bool
+    // Items are priced as given instead of loading CartIDs, for orders not placed from the cart
+    Items            []models.CartItem
+    // DiscountPercent is taken off every line before promotions, e.g. for a subscription
+    DiscountPercent  float64
+}
+
+// priceCart builds order lines from the user's cart items in the requested currency,
+// applies promotions to them, works out the tax for the country and region they ship
+// to and charges shipping
+func (s *ShopService) priceCart(ctx context.Context, req cartRequest) (*models.OrderQuote, []models.CartItem, error) {
+    rate, err := s.resolveCurrency(ctx, req.Currency)
     if err != nil {
-        return nil, err
+        return nil, nil, err
     }
     
-    // Get cart items
-    var cartItems []models.CartItem
-    var totalAmount float64
+    cartItems := req.Items
+    userID := req.UserID
     
-    for _, cartID := range input.CartIDs {
-        cartItem, err := s.cartItemsRepo.GetByID(ctx, cartID)
-        if err != nil {
-            return nil, err
+    if cartItems == nil {
+        for _, cartID := range req.CartIDs {
+            cartItem, err := s.cartItemsRepo.GetByID(ctx, cartID)
+            if err != nil {
+                return nil, nil, err
+            }
+            if cartItem.UserID.String() != userID {
+                return nil, nil, errors.New("cart item not found")
+            }
+            
+            cartItems = append(cartItems, *cartItem)
         }
-        
-        cartItems = append(cartItems, *cartItem)
-        
-        // Calculate price
-        price := cartItem.Product.Price
-        if cartItem.Variant != nil {
-            price = cartItem.Variant.Price
+    }
+    
+    var productIDs []uuid.UUID
+    for _, cartItem := range cartItems {
+        productIDs = append(productIDs, cartItem.ProductID)
+        for _, component := range cartItem.Product.Components {
+            productIDs = append(productIDs, component.ProductID)
         }
-        
-        totalAmount += price * float64(cartItem.Quantity)
     }
     
-    // Create order
-    order := &models.Order{
-        UserID:      userID,
-        Status:      "pending",
-        TotalAmount: totalAmount,
-        Address: models.Address{
-            Street:     input.Address.Street,
-            City:       input.Address.City,
-            State:      input.Address.State,
-            Country:    input.Address.Country,
-            PostalCode: input.Address.PostalCode,
-        },
-    }
-    
-    // Add order items
+    prices, err := s.productPrices(ctx, productIDs, rate)
+    if err != nil {
+        return nil, nil, err
+    }
+    
+    quote := &models.OrderQuote{
+        Currency:     rate.Currency,
+        ExchangeRate: rate.Rate,
+        Promotions:   []models.PromotionRedemption{},
+        TaxLines:     []models.OrderTaxLine{},
+    }
+    categories := make([][]string, 0, len(cartItems))
+    taxClasses := make([]string, 0, len(cartItems))
+    weight := 0.0
+    shippable := false
+    
     for _, cartItem := range cartItems {
-        price := cartItem.Product.Price
+        if err := checkGiftCardQuantity(&cartItem.Product, cartItem.Quantity); err != nil {
+            return nil, nil, err
+        }
+        
+        // Calculate price
+        price := prices.price(cartItem.ProductID, nil, cartItem.Product.Price)
         if cartItem.Variant != nil {
-            price = cartItem.Variant.Price
+            price = prices.price(cartItem.ProductID, cartItem.VariantID, cartItem.Variant.Price)
         }
         
         orderItem := models.OrderItem{
             ProductID:   cartItem.ProductID,
+            Product:     cartItem.Product,
+            VariantID:   cartItem.VariantID,
             Quantity:    cartItem.Quantity,
             PriceAtTime: price,
         }
+        quote.Items = append(quote.Items, orderItem)
+        quote.Subtotal += orderItem.LineTotal()
         
-        if cartItem.VariantID != nil {
-            orderItem.VariantID = cartItem.VariantID
+        var lineCategories []string
+        for _, category := range cartItem.Product.Categories {
+            lineCategories = append(lineCategories, category.ID.String())
         }
-        
-        order.Items = append(order.Items, orderItem)
+        categories = append(categories, lineCategories)
+        taxClasses = append(taxClasses, taxClassOrDefault(cartItem.Product.TaxClass))
+        if isGiftCardLine(&orderItem) {
+            continue
+        }
+        shippable = true
+        itemWeight := cartItem.Product.Weight
+        if cartItem.Product.Type == models.ProductBundle {
+            itemWeight = bundleWeight(&cartItem.Product)
+        }
+        if cartItem.Variant != nil && cartItem.Variant.Weight > 0 {
+            itemWeight = cartItem.Variant.Weight
+        }
+        weight += shippingWeight(&cartItem.Product, itemWeight) * float64(cartItem.Quantity)
     }
+    quote.Subtotal = roundMoney(quote.Subtotal, quote.Currency)
     
-    // Save order
-    if err := s.ordersRepo.Create(ctx, order); err != nil {
+    if req.DiscountPercent > 0 {
+        for i := range quote.Items {
+            item := &quote.Items[i]
+            if isGiftCardLine(item) {
+                continue
+            }
+            item.DiscountAmount = roundMoney(item.LineTotal()*req.DiscountPercent/100, quote.Currency)
+            quote.SubscriptionDiscount += item.DiscountAmount
+        }
+        quote.SubscriptionDiscount = roundMoney(quote.SubscriptionDiscount, quote.Currency)
+    }
+    
+    if err := s.applyPromotions(ctx, userID, quote, categories, req.CouponCodes); err != nil {
+        return nil, nil, err
+    }
+    
+    // Bundle lines break down into their components, each with its share of what the
+    // line sells for after discounts
+    for i, cartItem := range cartItems {
+        if cartItem.Product.Type != models.ProductBundle {
+            continue
+        }
+        item := &quote.Items[i]
+        item.Components = BundleBreakdown(&cartItem.Product, item.Quantity, lineRemaining(item, quote.Currency), quote.Currency,
+            func(component *models.BundleComponent) float64 {
+                return prices.price(component.ProductID, component.VariantID, componentBasePrice(component))
+            })
+    }
+    
+    quote.DiscountTotal = quote.SubscriptionDiscount
+    for _, redemption := range quote.Promotions {
+        quote.DiscountTotal += redemption.Amount
+    }
+    quote.DiscountTotal = roundMoney(quote.DiscountTotal, quote.Currency)
+    
+    if err := s.applyTax(ctx, quote, taxClasses, req.Country, req.Region); err != nil {
+        return nil, nil, err
+    }
+    
+    // Gift cards aren't shipped, so an order of nothing else has no shipping to pay
+    if shippable {
+        if err := s.applyShipping(ctx, quote, weight, req); err != nil {
+            return nil, nil, err
+        }
+    } else {
+        quote.ShippingOptions = []models.ShippingOption{}
+    }
+    
+    quote.Total = roundMoney(quote.Subtotal - quote.DiscountTotal + quote.ShippingCost, quote.Currency)
+    if !quote.PricesIncludeTax {
+        quote.Total = roundMoney(quote.Total + quote.TaxTotal, quote.Currency)
+    }
+    
+    return quote, cartItems, nil
+}
+
+func (s *ShopService) CreateOrder(ctx context.Context, input models.CreateOrderInput) (*models.Order, error) {
+    // Parse user ID
+    userID, err := uuid.Parse(input.UserID)
+    if err != nil {
+        return nil, err
+    }
+    
+    quote, cartItems, err := s.priceCart(ctx, cartRequest{
+        UserID:           input.UserID,
+        CartIDs:          input.CartIDs,
+        CouponCodes:      input.CouponCodes,
+        Country:          input.Address.Country,
+        Region:           input.Address.State,
+        ShippingMethodID: input.ShippingMethodID,
+        Currency:         input.Currency,
+        RequireShipping:  true,
+    })
+    if err != nil {
+        return nil, err
+    }
+    
+    order, err := s.placeOrder(ctx, quote, cartItems, userID, models.Address{
+        Street:     input.Address.Street,
+        City:       input.Address.City,
+        State:      input.Address.State,
+        Country:    input.Address.Country,
+        PostalCode: input.Address.PostalCode,
+    }, nil, reservationTTL())
+    if err != nil {
         return nil, err
     }
     
+    // Pay what gift cards and store credit cover up front; if they can't be used the
+    // order is canceled, so the customer can check out again with the cart as it was
+    if len(input.GiftCardCodes) > 0 || input.UseStoreCredit {
+        paid, err := s.ApplyCredit(ctx, models.ApplyCreditInput{
+            OrderID:        order.ID.String(),
+            UserID:         input.UserID,
+            GiftCardCodes:  input.GiftCardCodes,
+            UseStoreCredit: input.UseStoreCredit,
+        })
+        if err != nil {
+            // Cancel the order so its stock and any credit it took are released
+            if _, cancelErr := s.cancelUnpaidOrder(ctx, order.ID); cancelErr != nil {
+                log.Printf("Failed to cancel order %s after its credit couldn't be applied: %v", order.ID, cancelErr)
+            }
+            return nil, err
+        }
+        order = paid
+    }
+    
     // Clear cart
     for _, cartItem := range cartItems {
         if err := s.cartItemsRepo.Delete(ctx, cartItem.ID.String()); err != nil {
             // Log error but continue
             continue
         }
-        
-        // Update product stock
-        if err := s.productsRepo.UpdateStock(ctx, cartItem.ProductID.String(), cartItem.Quantity); err != nil {
-            // Log error but continue
-            continue
+    }
+    
+    return order, nil
+}
+
+// placeOrder saves a priced order for its items, redeeming its promotions and holding
+// its stock for ttl until it's paid for
+func (s *ShopService) placeOrder(ctx context.Context, quote *models.OrderQuote, cartItems []models.CartItem, userID uuid.UUID, address models.Address, subscriptionID *uuid.UUID, ttl time.Duration) (*models.Order, error) {
+    // Count the promotions' uses up front so two orders can't both take the last one
+    var redeemed []uuid.UUID
+    release := func() {
+        for _, id := range redeemed {
+            if err := s.promotionsRepo.Release(ctx, id); err != nil {
+                log.Printf("Failed to release promotion %s after its order couldn't be placed: %v", id, err)
+            }
+        }
+    }
+    for _, redemption := range quote.Promotions {
+        ok, err := s.promotionsRepo.Redeem(ctx, redemption.PromotionID)
+        if err != nil || !ok {
+            release()
+            if err != nil {
+                return nil, err
+            }
+            return nil, ErrPromotionUnavailable
+        }
+        redeemed = append(redeemed, redemption.PromotionID)
+    }
+    
+    // Create order; its ID is set up front so stock can be reserved for it first
+    order := &models.Order{
+        Base:          models.Base{ID: uuid.New()},
+        UserID:        userID,
+        Status:        "pending",
+        Subtotal:      quote.Subtotal,
+        DiscountTotal: quote.DiscountTotal,
+        FreeShipping:  quote.FreeShipping,
+        TaxTotal:      quote.TaxTotal,
+        PricesIncludeTax: quote.PricesIncludeTax,
+        TaxLines:      quote.TaxLines,
+        ShippingMethodID: quote.ShippingMethodID,
+        ShippingMethod: quote.ShippingMethod,
+        ShippingCost:  quote.ShippingCost,
+        TotalAmount:   quote.Total,
+        Currency:      quote.Currency,
+        ExchangeRate:  quote.ExchangeRate,
+        Address:       address,
+        SubscriptionID: subscriptionID,
+    }
+    
+    // Add order items, leaving the products out so saving the order doesn't save them too
+    for _, orderItem := range quote.Items {
+        orderItem.Product = models.Product{}
+        order.Items = append(order.Items, orderItem)
+    }
+    for _, redemption := range quote.Promotions {
+        redemption.UserID = userID
+        order.Promotions = append(order.Promotions, redemption)
+    }
+    
+    // Hold the stock until the order is paid for or the reservation expires
+    if err := s.reserveStock(ctx, order, cartItems, ttl); err != nil {
+        release()
+        return nil, err
+    }
+    
+    // Save order
+    if err := s.ordersRepo.Create(ctx, order); err != nil {
+        release()
+        if releaseErr := s.reservationsRepo.Release(ctx, order.ID); releaseErr != nil {
+            log.Printf("Failed to release stock reserved for unsaved order %s: %v", order.ID, releaseErr)
+        }
+        return nil, err
+    }
+    
+    // Issue the gift cards bought in the order, usable once it's paid for
+    if err := s.issueOrderGiftCards(ctx, order, cartItems); err != nil {
+        if _, cancelErr := s.cancelUnpaidOrder(ctx, order.ID); cancelErr != nil {
+            log.Printf("Failed to cancel order %s after its gift cards couldn't be issued: %v", order.ID, cancelErr)
         }
+        return nil, err
     }
     
     return order, nil
@@ -283,4 +673,20 @@ func (s *ShopService) GetOrder(ctx context.Context, id string, userID string) (*
 
 func (s *ShopService) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, int64, error) {
     return s.ordersRepo.List(ctx, filter)
-}
\ No newline at end of file
+}
+
+// productTypeOrDefault returns the product type, or simple if it isn't set
+func productTypeOrDefault(productType string) string {
+    if productType == "" {
+        return models.ProductSimple
+    }
+    return productType
+}
+
+// taxClassOrDefault returns taxClass, or the default class if it is empty
+func taxClassOrDefault(taxClass string) string {
+    if taxClass == "" {
+        return models.DefaultTaxClass
+    }
+    return taxClass
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidStockAdjustment is returned when a manual stock change doesn't make sense
+var ErrInvalidStockAdjustment = errors.New("invalid stock adjustment")
+
+// AdjustStock records a manual change to a product's or variant's stock: an
+// adjustment by some amount, a return to stock, or a stock-take of what's on hand
+func (s *ShopService) AdjustStock(ctx context.Context, input models.StockAdjustmentInput) (*models.StockMovement, error) {
+	switch {
+	case input.Type == models.StockTake && input.Quantity < 0:
+		return nil, fmt.Errorf("%w: counted stock can't be negative", ErrInvalidStockAdjustment)
+	case input.Type == models.StockReturn && input.Quantity <= 0:
+		return nil, fmt.Errorf("%w: a return must add stock", ErrInvalidStockAdjustment)
+	case input.Type == models.StockAdjustment && input.Quantity == 0:
+		return nil, fmt.Errorf("%w: an adjustment must change stock", ErrInvalidStockAdjustment)
+	}
+
+	product, variantID, err := s.priceTarget(ctx, input.ProductID, input.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	movement := &models.StockMovement{
+		ProductID: product.ID,
+		VariantID: variantID,
+		Type:      input.Type,
+		Quantity:  input.Quantity,
+		Reason:    input.Reason,
+	}
+	if actorID, err := uuid.Parse(input.ActorID); err == nil {
+		movement.ActorID = &actorID
+	}
+
+	if input.Type == models.StockTake {
+		if err := s.ledgerRepo.Count(ctx, movement, input.Quantity); err != nil {
+			return nil, err
+		}
+		return movement, nil
+	}
+
+	movements := []models.StockMovement{*movement}
+	ok, err := s.ledgerRepo.Record(ctx, movements)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInsufficientStock
+	}
+	return &movements[0], nil
+}
+
+// StockLedger lists the stock ledger entries of a product or variant by its SKU
+func (s *ShopService) StockLedger(ctx context.Context, filter models.StockLedgerFilter) ([]models.StockMovement, int64, error) {
+	return s.ledgerRepo.History(ctx, filter)
+}
+
+// countStock sets a product's or variant's stock on hand to a counted level through
+// the stock ledger
+func (s *ShopService) countStock(ctx context.Context, product *models.Product, variant *models.ProductVariant, counted int, reason string) error {
+	movement := &models.StockMovement{
+		ProductID: product.ID,
+		Type:      models.StockTake,
+		Reason:    reason,
+	}
+	if variant != nil {
+		movement.VariantID = &variant.ID
+	}
+	if err := s.ledgerRepo.Count(ctx, movement, counted); err != nil {
+		return err
+	}
+
+	if variant != nil {
+		variant.StockQuantity = movement.Balance
+	} else {
+		product.StockQuantity = movement.Balance
+	}
+	return nil
+}
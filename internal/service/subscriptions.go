@@ -0,0 +1,529 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidSubscription is returned when a subscription can't be set up as asked
+	ErrInvalidSubscription = errors.New("invalid subscription")
+	// ErrSubscriptionNotFound is returned for a subscription that doesn't exist or isn't the user's
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrSubscriptionState is returned when a subscription can't be skipped, paused,
+	// resumed or canceled in its current state
+	ErrSubscriptionState = errors.New("subscription can't be changed")
+	// ErrRenewalDeclined is returned by a RenewalCharger when the provider turned the
+	// charge down, so nothing was taken and the renewal can be tried again
+	ErrRenewalDeclined = errors.New("payment declined")
+)
+
+// RenewalPaymentWindow is how long a customer has to pay for a renewal order, and its
+// stock is held, when it can't be charged to a saved payment method
+const RenewalPaymentWindow = 3 * 24 * time.Hour
+
+// DefaultSubscriptionReminderDays is how many days before a renewal the customer is
+// reminded when SUBSCRIPTION_REMINDER_DAYS isn't set
+const DefaultSubscriptionReminderDays = 3
+
+// renewalRetryDelays are how long after each failed renewal in a row it is tried again;
+// the subscription is paused when the last retry fails too
+var renewalRetryDelays = []time.Duration{24 * time.Hour, 3 * 24 * time.Hour, 7 * 24 * time.Hour}
+
+// RenewalCharger charges a saved payment method for a subscription's renewal order;
+// any error other than ErrRenewalDeclined means the charge may have gone through
+type RenewalCharger interface {
+	ChargeRenewal(ctx context.Context, order *models.Order, method *models.PaymentMethod) error
+}
+
+// SetMailer sets the mailer used for subscription emails; without one no emails are sent
+func (s *ShopService) SetMailer(mailer TemplateMailer) {
+	s.mailer = mailer
+}
+
+// SetRenewalCharger sets what charges renewals to saved payment methods; without one
+// customers are emailed to pay for each renewal order themselves
+func (s *ShopService) SetRenewalCharger(charger RenewalCharger) {
+	s.charger = charger
+}
+
+// CreateSubscription subscribes a customer to a product and places the first order
+func (s *ShopService) CreateSubscription(ctx context.Context, input models.SubscriptionInput) (*models.Subscription, error) {
+	userID, err := uuid.Parse(input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := s.productsRepo.GetByID(ctx, input.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: product not found", ErrInvalidSubscription)
+	}
+	if product.Type == models.ProductGiftCard {
+		return nil, fmt.Errorf("%w: gift cards can't be subscribed to", ErrInvalidSubscription)
+	}
+	variant, err := resolveVariant(product, input.VariantID, input.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := s.CheckCurrency(ctx, input.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	subscription := &models.Subscription{
+		Base:            models.Base{ID: uuid.New()},
+		UserID:          userID,
+		ProductID:       product.ID,
+		Product:         product,
+		Quantity:        input.Quantity,
+		IntervalDays:    input.IntervalDays,
+		DiscountPercent: subscriptionDiscount(),
+		Status:          models.SubscriptionActive,
+		Address: models.Address{
+			Street:     input.Address.Street,
+			City:       input.Address.City,
+			State:      input.Address.State,
+			Country:    input.Address.Country,
+			PostalCode: input.Address.PostalCode,
+		},
+		ShippingMethodID: input.ShippingMethodID,
+		Currency:         currency,
+		NextRenewalAt:    now,
+	}
+	if variant != nil {
+		subscription.VariantID = &variant.ID
+		subscription.Variant = variant
+	}
+	if input.PaymentMethodID != "" {
+		methodID, err := uuid.Parse(input.PaymentMethodID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: payment method not found", ErrInvalidSubscription)
+		}
+		method, err := s.subscriptionsRepo.GetPaymentMethod(ctx, userID, methodID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: payment method not found", ErrInvalidSubscription)
+		}
+		subscription.PaymentMethodID = &method.ID
+		subscription.PaymentMethod = method
+	}
+
+	// The first order is placed straight away, so problems with it come back to the
+	// customer instead of going into retries
+	order, err := s.placeRenewal(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	subscription.RenewalOrderID = &order.ID
+	if err := s.subscriptionsRepo.Create(ctx, subscription); err != nil {
+		if _, cancelErr := s.cancelUnpaidOrder(ctx, order.ID); cancelErr != nil {
+			log.Printf("Failed to cancel order %s after its subscription couldn't be saved: %v", order.ID, cancelErr)
+		}
+		return nil, err
+	}
+
+	if err := s.collectRenewal(ctx, subscription, order, now); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// ListSubscriptions lists a customer's subscriptions, newest first
+func (s *ShopService) ListSubscriptions(ctx context.Context, userID string) ([]models.Subscription, error) {
+	return s.subscriptionsRepo.ListByUser(ctx, userID)
+}
+
+// GetSubscription returns one of a customer's subscriptions
+func (s *ShopService) GetSubscription(ctx context.Context, id, userID string) (*models.Subscription, error) {
+	subscription, err := s.subscriptionsRepo.GetByID(ctx, id)
+	if err != nil || subscription.UserID.String() != userID {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// SkipSubscription skips a subscription's next renewal, moving it on by one interval
+func (s *ShopService) SkipSubscription(ctx context.Context, id, userID string) (*models.Subscription, error) {
+	subscription, err := s.GetSubscription(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case subscription.Status == models.SubscriptionCanceled:
+		return nil, fmt.Errorf("%w: the subscription is canceled", ErrSubscriptionState)
+	case subscription.RenewalOrderID != nil:
+		return nil, fmt.Errorf("%w: this renewal has already been ordered", ErrSubscriptionState)
+	}
+
+	subscription.NextRenewalAt = subscription.NextRenewalAt.AddDate(0, 0, subscription.IntervalDays)
+	subscription.ReminderSentAt = nil
+	subscription.FailedAttempts = 0
+	subscription.RetryAt = nil
+	subscription.LastError = ""
+	if err := s.subscriptionsRepo.Update(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// PauseSubscription stops a subscription renewing until it is resumed
+func (s *ShopService) PauseSubscription(ctx context.Context, id, userID string) (*models.Subscription, error) {
+	subscription, err := s.GetSubscription(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Status != models.SubscriptionActive {
+		return nil, fmt.Errorf("%w: the subscription is %s", ErrSubscriptionState, subscription.Status)
+	}
+
+	subscription.Status = models.SubscriptionPaused
+	subscription.RetryAt = nil
+	if err := s.subscriptionsRepo.Update(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// ResumeSubscription starts a paused subscription renewing again, straight away if
+// its renewal date passed while it was paused
+func (s *ShopService) ResumeSubscription(ctx context.Context, id, userID string) (*models.Subscription, error) {
+	subscription, err := s.GetSubscription(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Status != models.SubscriptionPaused {
+		return nil, fmt.Errorf("%w: the subscription is %s", ErrSubscriptionState, subscription.Status)
+	}
+
+	now := time.Now()
+	subscription.Status = models.SubscriptionActive
+	subscription.FailedAttempts = 0
+	subscription.RetryAt = nil
+	subscription.LastError = ""
+	if subscription.NextRenewalAt.Before(now) {
+		subscription.NextRenewalAt = now
+		subscription.ReminderSentAt = nil
+	}
+	if err := s.subscriptionsRepo.Update(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// CancelSubscription ends a subscription, canceling its renewal order if it hasn't
+// been paid for yet
+func (s *ShopService) CancelSubscription(ctx context.Context, id, userID string) (*models.Subscription, error) {
+	subscription, err := s.GetSubscription(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Status == models.SubscriptionCanceled {
+		return nil, fmt.Errorf("%w: the subscription is already canceled", ErrSubscriptionState)
+	}
+
+	if subscription.RenewalOrderID != nil {
+		if _, err := s.cancelUnpaidOrder(ctx, *subscription.RenewalOrderID); err != nil {
+			return nil, err
+		}
+		subscription.RenewalOrderID = nil
+	}
+
+	now := time.Now()
+	subscription.Status = models.SubscriptionCanceled
+	subscription.CanceledAt = &now
+	subscription.RetryAt = nil
+	if err := s.subscriptionsRepo.Update(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// ProcessSubscriptions settles renewal orders that were paid for or expired, places
+// the renewals that are due and reminds customers of upcoming ones. It returns how
+// many renewal orders it placed.
+func (s *ShopService) ProcessSubscriptions(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	awaiting, err := s.subscriptionsRepo.ListAwaitingPayment(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := range awaiting {
+		// One order that can't be settled mustn't hold up every other subscription
+		if err := s.settleRenewal(ctx, &awaiting[i], now); err != nil {
+			log.Printf("Failed to settle renewal of subscription %s: %v", awaiting[i].ID, err)
+		}
+	}
+
+	due, err := s.subscriptionsRepo.ListDue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	placed := 0
+	for i := range due {
+		subscription := &due[i]
+		if subscription.Product == nil {
+			if err := s.renewalFailed(ctx, subscription, now, "the product is no longer available"); err != nil {
+				log.Printf("Failed to record failed renewal of subscription %s: %v", subscription.ID, err)
+			}
+			continue
+		}
+
+		order, err := s.placeRenewal(ctx, subscription)
+		if err != nil {
+			log.Printf("Failed to renew subscription %s: %v", subscription.ID, err)
+			if err := s.renewalFailed(ctx, subscription, now, err.Error()); err != nil {
+				log.Printf("Failed to record failed renewal of subscription %s: %v", subscription.ID, err)
+			}
+			continue
+		}
+		placed++
+		if err := s.collectRenewal(ctx, subscription, order, now); err != nil {
+			log.Printf("Failed to collect renewal of subscription %s: %v", subscription.ID, err)
+		}
+	}
+
+	return placed, s.sendRenewalReminders(ctx, now)
+}
+
+// RunSubscriptions processes subscriptions every interval until ctx is cancelled
+func (s *ShopService) RunSubscriptions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if placed, err := s.ProcessSubscriptions(ctx); err != nil {
+			log.Printf("Failed to process subscriptions: %v", err)
+		} else if placed > 0 {
+			log.Printf("Placed %d subscription renewal orders", placed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// placeRenewal prices and places a subscription's order, holding its stock for as
+// long as it takes to pay for it
+func (s *ShopService) placeRenewal(ctx context.Context, subscription *models.Subscription) (*models.Order, error) {
+	quote, items, err := s.priceCart(ctx, cartRequest{
+		UserID:           subscription.UserID.String(),
+		Country:          subscription.Address.Country,
+		Region:           subscription.Address.State,
+		ShippingMethodID: subscription.ShippingMethodID,
+		Currency:         subscription.Currency,
+		RequireShipping:  true,
+		Items: []models.CartItem{{
+			UserID:    subscription.UserID,
+			ProductID: subscription.ProductID,
+			Product:   *subscription.Product,
+			VariantID: subscription.VariantID,
+			Variant:   subscription.Variant,
+			Quantity:  subscription.Quantity,
+		}},
+		DiscountPercent: subscription.DiscountPercent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := RenewalPaymentWindow
+	if s.charger != nil && subscription.PaymentMethod != nil {
+		ttl = reservationTTL()
+	}
+	return s.placeOrder(ctx, quote, items, subscription.UserID, subscription.Address, &subscription.ID, ttl)
+}
+
+// collectRenewal charges a renewal order to the subscription's saved payment method,
+// or emails the customer to pay for it when it can't be charged
+func (s *ShopService) collectRenewal(ctx context.Context, subscription *models.Subscription, order *models.Order, now time.Time) error {
+	if s.charger == nil || subscription.PaymentMethod == nil {
+		subscription.RenewalOrderID = &order.ID
+		if err := s.subscriptionsRepo.Update(ctx, subscription); err != nil {
+			return err
+		}
+		s.emailSubscriber(subscription, util.SubscriptionPaymentTemplate, map[string]interface{}{
+			"Total":  formatAmount(order.TotalAmount, order.Currency),
+			"PayBy":  now.Add(RenewalPaymentWindow).Format("January 2"),
+			"PayURL": s.siteURL + "/account/orders/" + order.ID.String(),
+		})
+		return nil
+	}
+
+	if err := s.charger.ChargeRenewal(ctx, order, subscription.PaymentMethod); err != nil {
+		if !errors.Is(err, ErrRenewalDeclined) {
+			return s.renewalNeedsReview(ctx, subscription, order, err)
+		}
+		if _, err := s.cancelUnpaidOrder(ctx, order.ID); err != nil {
+			return err
+		}
+		return s.renewalFailed(ctx, subscription, now, "the payment was declined")
+	}
+
+	if err := s.completePaidOrder(ctx, order.ID); err != nil {
+		return err
+	}
+	return s.renewalSucceeded(ctx, subscription, order.ID, now)
+}
+
+// settleRenewal checks on a renewal order the customer was asked to pay for
+func (s *ShopService) settleRenewal(ctx context.Context, subscription *models.Subscription, now time.Time) error {
+	order, err := s.ordersRepo.GetByID(ctx, subscription.RenewalOrderID.String())
+	if err != nil {
+		return err
+	}
+
+	switch order.Status {
+	case "pending", "payment_pending":
+		return nil
+	case "canceled":
+		return s.renewalFailed(ctx, subscription, now, "the order wasn't paid for in time")
+	default:
+		return s.renewalSucceeded(ctx, subscription, order.ID, now)
+	}
+}
+
+// renewalNeedsReview holds a renewal order whose charge may have gone through for an
+// admin to check with the payment provider. Canceling it and retrying could charge
+// the customer twice, so the subscription waits on the order like one the customer
+// was asked to pay for, until it's marked paid or canceled
+func (s *ShopService) renewalNeedsReview(ctx context.Context, subscription *models.Subscription, order *models.Order, cause error) error {
+	log.Printf("Renewal order %s of subscription %s needs checking with the payment provider: %v", order.ID, subscription.ID, cause)
+
+	subscription.RenewalOrderID = &order.ID
+	subscription.LastError = cause.Error()
+	if err := s.subscriptionsRepo.Update(ctx, subscription); err != nil {
+		return err
+	}
+	// Taking the order out of pending keeps its reservation expiring from canceling it
+	return s.ordersRepo.Update(ctx, order.ID.String(), &models.Order{Status: "payment_pending"})
+}
+
+// renewalSucceeded moves a subscription on to its next renewal
+func (s *ShopService) renewalSucceeded(ctx context.Context, subscription *models.Subscription, orderID uuid.UUID, now time.Time) error {
+	next := subscription.NextRenewalAt.AddDate(0, 0, subscription.IntervalDays)
+	if next.Before(now) {
+		next = now.AddDate(0, 0, subscription.IntervalDays)
+	}
+
+	subscription.NextRenewalAt = next
+	subscription.LastOrderID = &orderID
+	subscription.RenewalOrderID = nil
+	subscription.ReminderSentAt = nil
+	subscription.FailedAttempts = 0
+	subscription.RetryAt = nil
+	subscription.LastError = ""
+	return s.subscriptionsRepo.Update(ctx, subscription)
+}
+
+// renewalFailed schedules a retry of a renewal that didn't go through, pausing the
+// subscription once the retries run out, and lets the customer know
+func (s *ShopService) renewalFailed(ctx context.Context, subscription *models.Subscription, now time.Time, reason string) error {
+	subscription.RenewalOrderID = nil
+	subscription.FailedAttempts++
+	subscription.LastError = reason
+	subscription.RetryAt = nil
+
+	paused := subscription.FailedAttempts > len(renewalRetryDelays)
+	retryDate := ""
+	if paused {
+		subscription.Status = models.SubscriptionPaused
+	} else {
+		retryAt := now.Add(renewalRetryDelays[subscription.FailedAttempts-1])
+		subscription.RetryAt = &retryAt
+		retryDate = retryAt.Format("January 2")
+	}
+	if err := s.subscriptionsRepo.Update(ctx, subscription); err != nil {
+		return err
+	}
+
+	s.emailSubscriber(subscription, util.SubscriptionFailedTemplate, map[string]interface{}{
+		"Reason":    reason,
+		"RetryDate": retryDate,
+		"Paused":    paused,
+	})
+	return nil
+}
+
+// sendRenewalReminders emails the customers whose subscriptions renew within the
+// reminder period
+func (s *ShopService) sendRenewalReminders(ctx context.Context, now time.Time) error {
+	if s.mailer == nil {
+		return nil
+	}
+
+	subscriptions, err := s.subscriptionsRepo.ListToRemind(ctx, now.AddDate(0, 0, subscriptionReminderDays()))
+	if err != nil {
+		return err
+	}
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		s.emailSubscriber(subscription, util.SubscriptionReminderTemplate, map[string]interface{}{
+			"RenewalDate": subscription.NextRenewalAt.Format("January 2"),
+		})
+		subscription.ReminderSentAt = &now
+		if err := s.subscriptionsRepo.Update(ctx, subscription); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emailSubscriber sends a subscription email to its customer, logging rather than
+// returning failures so they don't hold up renewals
+func (s *ShopService) emailSubscriber(subscription *models.Subscription, template util.EmailTemplate, data map[string]interface{}) {
+	if s.mailer == nil || subscription.User == nil {
+		return
+	}
+
+	name := subscription.User.FirstName
+	if name == "" {
+		name = subscription.User.Email
+	}
+	product := "your product"
+	if subscription.Product != nil {
+		product = subscription.Product.Name
+		if subscription.Variant != nil {
+			product += " - " + subscription.Variant.Name
+		}
+	}
+
+	data["Name"] = name
+	data["Email"] = subscription.User.Email
+	data["Product"] = product
+	data["Quantity"] = subscription.Quantity
+	data["ManageURL"] = s.siteURL + "/account/subscriptions"
+	data["Year"] = time.Now().Year()
+	if err := s.mailer.SendTemplateEmail(subscription.User.Email, template, data); err != nil {
+		log.Printf("Failed to send subscription email to %s: %v", subscription.User.Email, err)
+	}
+}
+
+// subscriptionDiscount returns the percentage taken off subscription orders
+func subscriptionDiscount() float64 {
+	if percent, err := strconv.ParseFloat(os.Getenv("SUBSCRIPTION_DISCOUNT_PERCENT"), 64); err == nil && percent > 0 && percent < 100 {
+		return percent
+	}
+	return 0
+}
+
+// subscriptionReminderDays returns how many days before a renewal customers are reminded
+func subscriptionReminderDays() int {
+	if days, err := strconv.Atoi(os.Getenv("SUBSCRIPTION_REMINDER_DAYS")); err == nil && days > 0 {
+		return days
+	}
+	return DefaultSubscriptionReminderDays
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidTaxZone is returned when a tax zone's settings don't make sense
+	ErrInvalidTaxZone = errors.New("invalid tax zone")
+	// ErrInvalidTaxPeriod is returned when a tax report is grouped by an unknown period
+	ErrInvalidTaxPeriod = errors.New("period must be day, week, month, quarter or year")
+)
+
+// taxReportPeriods are the periods a tax report can be grouped by
+var taxReportPeriods = map[string]bool{"day": true, "week": true, "month": true, "quarter": true, "year": true}
+
+// CreateTaxZone creates a tax zone with its rates
+func (s *ShopService) CreateTaxZone(ctx context.Context, input models.TaxZoneInput) (*models.TaxZone, error) {
+	zone := &models.TaxZone{}
+	if err := applyTaxZoneInput(zone, input); err != nil {
+		return nil, err
+	}
+	if err := s.taxRepo.Create(ctx, zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// ListTaxZones returns every tax zone with its rates
+func (s *ShopService) ListTaxZones(ctx context.Context) ([]models.TaxZone, error) {
+	return s.taxRepo.List(ctx)
+}
+
+// GetTaxZone returns a tax zone by ID
+func (s *ShopService) GetTaxZone(ctx context.Context, id string) (*models.TaxZone, error) {
+	return s.taxRepo.GetByID(ctx, id)
+}
+
+// UpdateTaxZone changes a tax zone and replaces its rates; orders already placed keep
+// the tax they were charged
+func (s *ShopService) UpdateTaxZone(ctx context.Context, id string, input models.TaxZoneInput) (*models.TaxZone, error) {
+	zone, err := s.taxRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTaxZoneInput(zone, input); err != nil {
+		return nil, err
+	}
+	if err := s.taxRepo.Update(ctx, zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// DeleteTaxZone deletes a tax zone and its rates
+func (s *ShopService) DeleteTaxZone(ctx context.Context, id string) error {
+	return s.taxRepo.Delete(ctx, id)
+}
+
+// TaxReport totals the tax charged on orders by zone and period, in the base currency
+func (s *ShopService) TaxReport(ctx context.Context, filter models.TaxReportFilter) ([]models.TaxReportRow, error) {
+	if !taxReportPeriods[filter.Period] {
+		return nil, ErrInvalidTaxPeriod
+	}
+	rows, err := s.taxRepo.Report(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].TaxableAmount = roundMoney(rows[i].TaxableAmount, util.BaseCurrency())
+		rows[i].TaxAmount = roundMoney(rows[i].TaxAmount, util.BaseCurrency())
+	}
+	return rows, nil
+}
+
+// applyTaxZoneInput validates input and copies it onto zone
+func applyTaxZoneInput(zone *models.TaxZone, input models.TaxZoneInput) error {
+	country := strings.ToUpper(strings.TrimSpace(input.Country))
+	region := strings.TrimSpace(input.Region)
+	if region != "" && country == "" {
+		return fmt.Errorf("%w: a region needs a country", ErrInvalidTaxZone)
+	}
+
+	zone.Name = input.Name
+	zone.Country = country
+	zone.Region = region
+	zone.PricesIncludeTax = input.PricesIncludeTax
+	zone.Rates = nil
+	for _, rate := range input.Rates {
+		taxClass := strings.TrimSpace(rate.TaxClass)
+		if taxClass == "" {
+			taxClass = models.DefaultTaxClass
+		}
+		zone.Rates = append(zone.Rates, models.TaxRate{
+			Name:     rate.Name,
+			TaxClass: taxClass,
+			Rate:     rate.Rate,
+		})
+	}
+	return nil
+}
+
+// applyTax works out the tax on the quote's lines, after discounts, for the zone
+// covering the country and region. Where the zone's prices include tax it is taken
+// out of the line totals; otherwise it is added to the quote's total.
+func (s *ShopService) applyTax(ctx context.Context, quote *models.OrderQuote, taxClasses []string, country, region string) error {
+	if strings.TrimSpace(country) == "" {
+		return nil
+	}
+	zone, err := s.taxRepo.Match(ctx, country, region)
+	if err != nil || zone == nil {
+		return err
+	}
+	quote.PricesIncludeTax = zone.PricesIncludeTax
+
+	lines := make(map[uuid.UUID]*models.OrderTaxLine)
+	for i := range quote.Items {
+		item := &quote.Items[i]
+		if isGiftCardLine(item) {
+			continue
+		}
+
+		var rates []models.TaxRate
+		totalRate := 0.0
+		for _, rate := range zone.Rates {
+			if rate.TaxClass == taxClasses[i] {
+				rates = append(rates, rate)
+				totalRate += rate.Rate
+			}
+		}
+		if len(rates) == 0 {
+			continue
+		}
+
+		taxable := lineRemaining(item, quote.Currency)
+		if zone.PricesIncludeTax {
+			taxable = taxable / (1 + totalRate/100)
+		}
+		taxable = roundMoney(taxable, quote.Currency)
+
+		for _, rate := range rates {
+			amount := roundMoney(taxable*rate.Rate/100, quote.Currency)
+			item.TaxAmount = roundMoney(item.TaxAmount+amount, quote.Currency)
+
+			line, ok := lines[rate.ID]
+			if !ok {
+				line = &models.OrderTaxLine{
+					TaxZoneID: zone.ID,
+					TaxRateID: rate.ID,
+					ZoneName:  zone.Name,
+					Name:      rate.Name,
+					TaxClass:  rate.TaxClass,
+					Rate:      rate.Rate,
+					Inclusive: zone.PricesIncludeTax,
+				}
+				lines[rate.ID] = line
+			}
+			line.TaxableAmount = roundMoney(line.TaxableAmount+taxable, quote.Currency)
+			line.Amount = roundMoney(line.Amount+amount, quote.Currency)
+		}
+		item.TaxIncluded = zone.PricesIncludeTax
+	}
+
+	// Keep the lines in the order the zone lists its rates
+	for _, rate := range zone.Rates {
+		if line, ok := lines[rate.ID]; ok {
+			quote.TaxLines = append(quote.TaxLines, *line)
+			quote.TaxTotal += line.Amount
+		}
+	}
+	quote.TaxTotal = roundMoney(quote.TaxTotal, quote.Currency)
+	return nil
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/internal/repository"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidVariant is returned when a product's options or variants don't make sense
+	ErrInvalidVariant = errors.New("invalid variant")
+	// ErrVariantRequired is returned when adding a product with variants to the cart
+	// without saying which one
+	ErrVariantRequired = errors.New("choose a variant of this product")
+	// ErrVariantOrdered is returned when new options would remove a variant that has been ordered
+	ErrVariantOrdered = repository.ErrVariantOrdered
+)
+
+// maxVariantCombinations caps how many variants a product's options can generate
+const maxVariantCombinations = 200
+
+// plannedVariant is a variant a product should have and the stock it should open with
+type plannedVariant struct {
+	Variant models.ProductVariant
+	Stock   int
+	// Given is true when the variant's details came from a VariantInput
+	Given bool
+}
+
+// SetProductOptions replaces a product's option axes and regenerates its variants as
+// the combinations of their values. Variants whose combination survives keep their
+// ID, stock and details unless the input sets new ones; the rest are removed.
+func (s *ShopService) SetProductOptions(ctx context.Context, productID string, input models.ProductOptionsInput) (*models.Product, error) {
+	product, err := s.productsRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	options, planned, err := planVariants(product, input.Options, input.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	// Opening stock is only set for new variants; -1 marks the ones kept
+	variants := make([]models.ProductVariant, len(planned))
+	opening := make([]int, len(planned))
+	for i, plan := range planned {
+		variants[i] = plan.Variant
+		opening[i] = -1
+		if existing := matchVariant(product.Variants, plan.Variant.Options, len(options) == 0, plan.Variant.Name); existing != nil {
+			variants[i].ID = existing.ID
+			variants[i].StockQuantity = existing.StockQuantity
+			if !plan.Given {
+				variants[i].SKU = existing.SKU
+				variants[i].Price = existing.Price
+				variants[i].Weight = existing.Weight
+				variants[i].Image = existing.Image
+			}
+			continue
+		}
+		opening[i] = plan.Stock
+	}
+
+	if err := s.productsRepo.ReplaceVariants(ctx, product.ID, options, variants); err != nil {
+		return nil, err
+	}
+	product.Options = options
+	product.Variants = variants
+
+	// New variants open with their stock through the stock ledger
+	for i := range product.Variants {
+		if opening[i] < 0 {
+			continue
+		}
+		if err := s.countStock(ctx, product, &product.Variants[i], opening[i], "Opening stock"); err != nil {
+			return nil, err
+		}
+	}
+	return product, nil
+}
+
+// UpdateVariant changes a variant's SKU, price, weight or image
+func (s *ShopService) UpdateVariant(ctx context.Context, productID, variantID string, input models.VariantUpdateInput) (*models.ProductVariant, error) {
+	product, err := s.productsRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := resolveVariant(product, variantID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if variant == nil {
+		return nil, fmt.Errorf("%w: no variant given", ErrInvalidVariant)
+	}
+
+	// A variant generated from options is named after them
+	if len(product.Options) == 0 && strings.TrimSpace(input.Name) != "" {
+		variant.Name = strings.TrimSpace(input.Name)
+	}
+	variant.SKU = strings.TrimSpace(input.SKU)
+	variant.Price = input.Price
+	variant.Weight = input.Weight
+	variant.Image = input.Image
+
+	if err := s.productsRepo.UpdateVariant(ctx, variant); err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+// planVariants works out a product's option axes and the variants they generate, or
+// the free-form variants given when it has no options
+func planVariants(product *models.Product, optionInputs []models.ProductOptionInput, inputs []models.VariantInput) ([]models.ProductOption, []plannedVariant, error) {
+	options, err := buildOptions(product.ID, optionInputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(options) == 0 {
+		planned := make([]plannedVariant, 0, len(inputs))
+		for _, input := range inputs {
+			name := strings.TrimSpace(input.Name)
+			if name == "" {
+				return nil, nil, fmt.Errorf("%w: a variant needs a name when the product has no options", ErrInvalidVariant)
+			}
+			planned = append(planned, plannedVariant{
+				Variant: newVariant(product, name, nil, input),
+				Stock:   input.StockQuantity,
+				Given:   true,
+			})
+		}
+		return options, planned, nil
+	}
+
+	combinations := optionCombinations(options)
+	if len(combinations) > maxVariantCombinations {
+		return nil, nil, fmt.Errorf("%w: the options make %d variants, more than %d", ErrInvalidVariant, len(combinations), maxVariantCombinations)
+	}
+
+	// Every input has to name exactly one combination
+	given := make(map[int]models.VariantInput, len(inputs))
+	for _, input := range inputs {
+		index := -1
+		for i, combination := range combinations {
+			if len(input.Options) == len(combination) && combination.Matches(input.Options) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, nil, fmt.Errorf("%w: no combination of the options matches %v", ErrInvalidVariant, map[string]string(input.Options))
+		}
+		if _, ok := given[index]; ok {
+			return nil, nil, fmt.Errorf("%w: %v is given twice", ErrInvalidVariant, map[string]string(input.Options))
+		}
+		given[index] = input
+	}
+
+	planned := make([]plannedVariant, len(combinations))
+	for i, combination := range combinations {
+		values := make([]string, len(options))
+		for j, option := range options {
+			values[j], _ = combination.Get(option.Name)
+		}
+
+		input, ok := given[i]
+		planned[i] = plannedVariant{
+			Variant: newVariant(product, strings.Join(values, " / "), combination, input),
+			Stock:   input.StockQuantity,
+			Given:   ok,
+		}
+	}
+	return options, planned, nil
+}
+
+// buildOptions validates option inputs and turns them into a product's option axes
+func buildOptions(productID uuid.UUID, inputs []models.ProductOptionInput) ([]models.ProductOption, error) {
+	options := make([]models.ProductOption, 0, len(inputs))
+	names := make(map[string]bool, len(inputs))
+	for i, input := range inputs {
+		name := strings.TrimSpace(input.Name)
+		if name == "" || names[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%w: option names must be set and different", ErrInvalidVariant)
+		}
+		names[strings.ToLower(name)] = true
+
+		optionType := input.Type
+		if optionType == "" {
+			optionType = models.OptionText
+		}
+
+		var values []string
+		seen := make(map[string]bool, len(input.Values))
+		for _, value := range input.Values {
+			value = strings.TrimSpace(value)
+			if value == "" || seen[strings.ToLower(value)] {
+				return nil, fmt.Errorf("%w: %s's values must be set and different", ErrInvalidVariant, name)
+			}
+			if optionType == models.OptionNumber {
+				if _, err := strconv.ParseFloat(value, 64); err != nil {
+					return nil, fmt.Errorf("%w: %s's values must be numbers", ErrInvalidVariant, name)
+				}
+			}
+			seen[strings.ToLower(value)] = true
+			values = append(values, value)
+		}
+
+		options = append(options, models.ProductOption{
+			ProductID: productID,
+			Name:      name,
+			Type:      optionType,
+			Values:    values,
+			Position:  i,
+		})
+	}
+	return options, nil
+}
+
+// optionCombinations returns every combination of the options' values, varying the
+// last option fastest
+func optionCombinations(options []models.ProductOption) []models.VariantOptions {
+	combinations := []models.VariantOptions{{}}
+	for _, option := range options {
+		next := make([]models.VariantOptions, 0, len(combinations)*len(option.Values))
+		for _, combination := range combinations {
+			for _, value := range option.Values {
+				extended := make(models.VariantOptions, len(combination)+1)
+				for axis, v := range combination {
+					extended[axis] = v
+				}
+				extended[option.Name] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// newVariant makes a variant of a product from its input, defaulting what the input
+// leaves out to the product's details
+func newVariant(product *models.Product, name string, options models.VariantOptions, input models.VariantInput) models.ProductVariant {
+	variant := models.ProductVariant{
+		ProductID: product.ID,
+		Name:      name,
+		Options:   options,
+		Price:     input.Price,
+		SKU:       strings.TrimSpace(input.SKU),
+		Weight:    input.Weight,
+		Image:     input.Image,
+	}
+	if variant.Price == 0 {
+		variant.Price = product.Price
+	}
+	if variant.SKU == "" {
+		base := product.SKU
+		if base == "" {
+			base = product.Slug
+		}
+		variant.SKU = strings.ToUpper(base + "-" + util.GenerateSlug(name))
+	}
+	return variant
+}
+
+// matchVariant finds the existing variant with a combination of option values, or
+// with a name for free-form variants
+func matchVariant(variants []models.ProductVariant, options models.VariantOptions, freeForm bool, name string) *models.ProductVariant {
+	for i := range variants {
+		variant := &variants[i]
+		if freeForm {
+			if len(variant.Options) == 0 && strings.EqualFold(variant.Name, name) {
+				return variant
+			}
+			continue
+		}
+		if len(variant.Options) == len(options) && variant.Options.Matches(options) {
+			return variant
+		}
+	}
+	return nil
+}
+
+// resolveVariant finds the variant of a product picked by ID or by option values. It
+// returns nil for a product without variants, and ErrVariantRequired if one has them
+// and neither is given.
+func resolveVariant(product *models.Product, variantID string, options models.VariantOptions) (*models.ProductVariant, error) {
+	if variantID != "" {
+		for i := range product.Variants {
+			if product.Variants[i].ID.String() == variantID {
+				return &product.Variants[i], nil
+			}
+		}
+		return nil, errors.New("variant not found")
+	}
+
+	if len(options) > 0 {
+		for i := range product.Variants {
+			if product.Variants[i].Options.Matches(options) && len(product.Variants[i].Options) == len(options) {
+				return &product.Variants[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%w: no variant has options %v", ErrInvalidVariant, map[string]string(options))
+	}
+
+	if len(product.Variants) > 0 {
+		return nil, ErrVariantRequired
+	}
+	return nil, nil
+}
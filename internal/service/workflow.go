@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+)
+
+// ErrInvalidTransition is returned when a post cannot move to the requested status
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// PostNotifier sends email notifications about post workflow changes
+type PostNotifier interface {
+	SendPostStatusEmail(to, name, postTitle, status, comment string) error
+}
+
+// SetNotifier sets the notifier used for workflow emails; without one no emails are sent
+func (s *BlogService) SetNotifier(notifier PostNotifier) {
+	s.notifier = notifier
+}
+
+// TransitionPost moves a post to a new workflow status, optionally attaching a reviewer comment
+func (s *BlogService) TransitionPost(ctx context.Context, id, status, comment, userID string) (*models.Post, error) {
+	post, err := s.postRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrNotAuthorized
+	}
+
+	transition, ok := models.FindPostTransition(postStatus(post), status)
+	if !ok {
+		return nil, ErrInvalidTransition
+	}
+
+	// Authors can submit and withdraw their own posts; everything else is for editors
+	isEditor := models.IsEditorRole(user.Role)
+	if transition.EditorOnly && !isEditor {
+		return nil, ErrNotAuthorized
+	}
+	if !transition.EditorOnly && post.AuthorID != userID && !isEditor {
+		return nil, ErrNotAuthorized
+	}
+
+	setPostStatus(post, status)
+	post.UpdatedAt = time.Now()
+	if err := s.postRepo.Update(ctx, post); err != nil {
+		return nil, err
+	}
+
+	comment = strings.TrimSpace(comment)
+	if comment != "" {
+		if _, err := s.createReviewComment(ctx, post, user, comment); err != nil {
+			return nil, err
+		}
+	}
+
+	s.notifyStatusChange(ctx, post, user, comment)
+
+	return post, nil
+}
+
+// AddReviewComment attaches a comment to a post submission
+func (s *BlogService) AddReviewComment(ctx context.Context, postID, body, userID string) (*models.ReviewComment, error) {
+	post, user, err := s.getReviewablePost(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.createReviewComment(ctx, post, user, strings.TrimSpace(body))
+}
+
+// ListReviewComments retrieves the review comments on a post, oldest first
+func (s *BlogService) ListReviewComments(ctx context.Context, postID, userID string) ([]models.ReviewComment, error) {
+	if _, _, err := s.getReviewablePost(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+	return s.commentRepo.ListByPost(ctx, postID)
+}
+
+// ListSubmissions retrieves posts in a workflow status for the review queue
+func (s *BlogService) ListSubmissions(ctx context.Context, status string, page, limit int) ([]*models.Post, int64, error) {
+	if status == "" {
+		status = models.PostStatusInReview
+	}
+	return s.postRepo.GetByStatus(ctx, status, page, limit)
+}
+
+// getReviewablePost loads a post and the user, checking the user is its author or an editor
+func (s *BlogService) getReviewablePost(ctx context.Context, postID, userID string) (*models.Post, *models.User, error) {
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, nil, ErrPostNotFound
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, ErrNotAuthorized
+	}
+	if post.AuthorID != userID && !models.IsEditorRole(user.Role) {
+		return nil, nil, ErrNotAuthorized
+	}
+
+	return post, user, nil
+}
+
+// createReviewComment saves a comment left by user on post
+func (s *BlogService) createReviewComment(ctx context.Context, post *models.Post, user *models.User, body string) (*models.ReviewComment, error) {
+	comment := &models.ReviewComment{
+		PostID:     post.ID.String(),
+		AuthorID:   user.ID.String(),
+		AuthorName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Body:       body,
+		Status:     postStatus(post),
+	}
+	if err := s.commentRepo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// notifyStatusChange emails the post's author when someone else changes its status,
+// and the editorial inbox (EDITORIAL_EMAIL) when the author does
+func (s *BlogService) notifyStatusChange(ctx context.Context, post *models.Post, actor *models.User, comment string) {
+	if s.notifier == nil {
+		return
+	}
+
+	type recipient struct{ email, name string }
+	var recipients []recipient
+
+	if post.AuthorID != actor.ID.String() {
+		if author, err := s.userRepo.GetByID(ctx, post.AuthorID); err == nil {
+			recipients = append(recipients, recipient{author.Email, author.FirstName})
+		}
+	}
+	if editorial := os.Getenv("EDITORIAL_EMAIL"); editorial != "" && !models.IsEditorRole(actor.Role) {
+		recipients = append(recipients, recipient{editorial, "Editors"})
+	}
+
+	title, status := post.Title, post.Status
+	for _, r := range recipients {
+		// Send in the background so a slow mail server doesn't hold up the request
+		go func(r recipient) {
+			if err := s.notifier.SendPostStatusEmail(r.email, r.name, title, status, comment); err != nil {
+				log.Printf("Failed to send post status email to %s: %v", r.email, err)
+			}
+		}(r)
+	}
+}
+
+// postStatus returns the post's workflow status, deriving it from Published for
+// posts created before the workflow existed
+func postStatus(post *models.Post) string {
+	if post.Status != "" {
+		return post.Status
+	}
+	if post.Published {
+		return models.PostStatusPublished
+	}
+	return models.PostStatusDraft
+}
+
+// setPostStatus sets the post's workflow status and keeps Published in step with it
+func setPostStatus(post *models.Post, status string) {
+	post.Status = status
+	post.Published = status == models.PostStatusPublished
+	if post.Published && post.PublishedAt == nil {
+		now := time.Now()
+		post.PublishedAt = &now
+	}
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"github.com/adrianmcmains/blog-ecommerce/internal/models"
+	"github.com/adrianmcmains/blog-ecommerce/pkg/util"
+	"gorm.io/gorm"
+)
+
+// BackfillOrderCurrency gives orders placed before currencies were tracked the base
+// currency, so the migration can make the column required. Run it before AutoMigrate.
+func BackfillOrderCurrency(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Order{}) {
+		return nil
+	}
+
+	for _, statement := range []string{
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency VARCHAR(3)`,
+		`ALTER TABLE orders ALTER COLUMN currency DROP DEFAULT`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec(`UPDATE orders SET currency = ? WHERE currency IS NULL`, util.BaseCurrency()).Error
+}
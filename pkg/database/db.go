@@ -14,10 +14,15 @@ func InitDB(databaseURL string) (*gorm.DB, error) {
         return nil, err
     }
 
+    if err := BackfillOrderCurrency(db); err != nil {
+        return nil, err
+    }
+
     // Auto migrate the schemas
     err = db.AutoMigrate(
         &models.User{},
         &models.Post{},
+        &models.ReviewComment{},
         &models.Category{},
         &models.Tag{},
         &models.Product{},
@@ -25,6 +30,29 @@ func InitDB(databaseURL string) (*gorm.DB, error) {
         &models.CartItem{},
         &models.Order{},
         &models.OrderItem{},
+        &models.PostProductClick{},
+        &models.PostConversion{},
+        &models.SlugRedirect{},
+        &models.Subscriber{},
+        &models.Promotion{},
+        &models.PromotionRedemption{},
+        &models.OrderItemDiscount{},
+        &models.TaxZone{},
+        &models.TaxRate{},
+        &models.OrderTaxLine{},
+        &models.ShippingZone{},
+        &models.ShippingMethod{},
+        &models.Currency{},
+        &models.ProductPrice{},
+        &models.StockReservation{},
+        &models.StockMovement{},
+        &models.ProductOption{},
+        &models.BundleComponent{},
+        &models.OrderItemComponent{},
+        &models.Subscription{},
+        &models.GiftCard{},
+        &models.StoreCredit{},
+        &models.CreditTransaction{},
         &models.Payment{},
         &models.PaymentMethod{},
     )
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"math"
+	"os"
+	"strings"
+)
+
+// zeroDecimalCurrencies have no minor units, so amounts in them are whole numbers
+var zeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "JPY": true,
+	"KMF": true, "KRW": true, "PYG": true, "RWF": true, "UGX": true, "VND": true,
+	"VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// threeDecimalCurrencies have a minor unit of a thousandth
+var threeDecimalCurrencies = map[string]bool{
+	"BHD": true, "IQD": true, "JOD": true, "KWD": true, "LYD": true, "OMR": true, "TND": true,
+}
+
+// BaseCurrency returns the currency product prices are entered in (BASE_CURRENCY, or "UGX")
+func BaseCurrency() string {
+	if currency := NormalizeCurrency(os.Getenv("BASE_CURRENCY")); currency != "" {
+		return currency
+	}
+	return "UGX"
+}
+
+// NormalizeCurrency upper-cases and trims an ISO 4217 currency code
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CurrencyDecimals returns how many decimal places amounts in a currency have
+func CurrencyDecimals(code string) int {
+	code = NormalizeCurrency(code)
+	switch {
+	case zeroDecimalCurrencies[code]:
+		return 0
+	case threeDecimalCurrencies[code]:
+		return 3
+	}
+	return 2
+}
+
+// RoundCurrency rounds an amount to the smallest unit of a currency
+func RoundCurrency(amount float64, code string) float64 {
+	scale := math.Pow10(CurrencyDecimals(code))
+	return math.Round(amount*scale) / scale
+}
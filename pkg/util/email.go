@@ -0,0 +1,8 @@
+package util
+
+// EmailTemplate represents an email template
+type EmailTemplate struct {
+	Subject string
+	HTML    string
+	Text    string
+}
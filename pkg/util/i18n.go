@@ -0,0 +1,99 @@
+package util
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage returns the site's default content language (DEFAULT_LANGUAGE, or "en")
+func DefaultLanguage() string {
+	if lang := normalizeLanguage(os.Getenv("DEFAULT_LANGUAGE")); lang != "" {
+		return lang
+	}
+	return "en"
+}
+
+// SupportedLanguages returns the languages posts can be written in (SITE_LANGUAGES, or the
+// languages shipped in i18n/), always including the default language
+func SupportedLanguages() []string {
+	configured := os.Getenv("SITE_LANGUAGES")
+	if configured == "" {
+		configured = "en,es"
+	}
+
+	defaultLang := DefaultLanguage()
+	languages := []string{defaultLang}
+	for _, lang := range strings.Split(configured, ",") {
+		lang = normalizeLanguage(lang)
+		if lang != "" && lang != defaultLang && !containsString(languages, lang) {
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// IsSupportedLanguage reports whether lang is one of the supported languages
+func IsSupportedLanguage(lang string) bool {
+	return containsString(SupportedLanguages(), normalizeLanguage(lang))
+}
+
+// ResolveLanguage picks the content language for a request: an explicit lang parameter wins,
+// then the best supported match from an Accept-Language header, then the default language
+func ResolveLanguage(lang, acceptLanguage string) string {
+	if lang = normalizeLanguage(lang); lang != "" && IsSupportedLanguage(lang) {
+		return lang
+	}
+
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var candidates []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if tag := normalizeLanguage(fields[0]); tag != "" && q > 0 {
+			candidates = append(candidates, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, candidate := range candidates {
+		if IsSupportedLanguage(candidate.lang) {
+			return candidate.lang
+		}
+	}
+	return DefaultLanguage()
+}
+
+// normalizeLanguage reduces a language tag such as "es-MX" to its lowercase primary subtag
+func normalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// wordsPerMinute is the reading speed used to estimate reading time
+const wordsPerMinute = 200
+
+// TOCEntry is a heading in the table of contents of a rendered document
+type TOCEntry struct {
+	Level    int         `json:"level"`
+	ID       string      `json:"id"`
+	Text     string      `json:"text"`
+	Children []*TOCEntry `json:"children,omitempty"`
+}
+
+// RenderedMarkdown is sanitized HTML rendered from Markdown, with document metadata
+type RenderedMarkdown struct {
+	HTML        string      `json:"html"`
+	TOC         []*TOCEntry `json:"toc"`
+	WordCount   int         `json:"word_count"`
+	ReadingTime int         `json:"reading_time"` // Minutes
+}
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	// Raw HTML is allowed through here and cleaned up by the sanitizer afterwards
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+// sanitizer strips scripts, iframes, event handler attributes and other unsafe markup
+var sanitizer = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowElements("section")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)).
+		OnElements("section", "div", "p", "h2", "img", "pre", "code", "span")
+	policy.AllowAttrs("data-product").Matching(productSlugPattern).OnElements("div")
+	return policy
+}()
+
+// Tina rich-text templates (see tina/schema.ts), either as JSX-style tags or Hugo shortcodes
+var (
+	templateTagPattern  = regexp.MustCompile(`(?s)<(hero|codeBlock|product)\b((?:[^>"` + "`" + `]|"(?:[^"\\]|\\.)*"|` + "`[^`]*`" + `)*?)/>`)
+	templateShortcode   = regexp.MustCompile(`(?s)\{\{<\s*(hero|codeBlock|product)\b(.*?)>\}\}`)
+	templateAttrPattern = regexp.MustCompile(`(\w+)=(?:"((?:[^"\\]|\\.)*)"|\{"((?:[^"\\]|\\.)*)"\}|\{` + "`([^`]*)`" + `\})`)
+	backtickRunPattern  = regexp.MustCompile("`{3,}")
+	productSlugPattern  = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+)
+
+// RenderMarkdown renders Markdown to sanitized HTML with heading IDs, a table of
+// contents, word count and reading time
+func RenderMarkdown(source string) (*RenderedMarkdown, error) {
+	src := []byte(expandTemplates(source))
+	doc := markdown.Parser().Parse(text.NewReader(src))
+
+	var headings []*TOCEntry
+	words := 0
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch node := n.(type) {
+		case *ast.Heading:
+			id := ""
+			if value, ok := node.AttributeString("id"); ok {
+				if b, ok := value.([]byte); ok {
+					id = string(b)
+				}
+			}
+			headings = append(headings, &TOCEntry{Level: node.Level, ID: id, Text: nodeText(node, src)})
+		case *ast.Text:
+			words += len(strings.Fields(string(node.Segment.Value(src))))
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, err
+	}
+
+	readingTime := 0
+	if words > 0 {
+		readingTime = (words + wordsPerMinute - 1) / wordsPerMinute
+	}
+
+	return &RenderedMarkdown{
+		HTML:        string(sanitizer.SanitizeBytes(buf.Bytes())),
+		TOC:         buildTOC(headings),
+		WordCount:   words,
+		ReadingTime: readingTime,
+	}, nil
+}
+
+// buildTOC nests a flat list of headings by level
+func buildTOC(headings []*TOCEntry) []*TOCEntry {
+	toc := []*TOCEntry{}
+	var stack []*TOCEntry
+
+	for _, heading := range headings {
+		for len(stack) > 0 && stack[len(stack)-1].Level >= heading.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			toc = append(toc, heading)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, heading)
+		}
+		stack = append(stack, heading)
+	}
+
+	return toc
+}
+
+// nodeText returns the plain text content of a node
+func nodeText(n ast.Node, src []byte) string {
+	var sb strings.Builder
+	ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := child.(type) {
+		case *ast.Text:
+			sb.Write(node.Segment.Value(src))
+			if node.SoftLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(node.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
+
+// expandTemplates replaces Tina hero and codeBlock templates with Markdown or HTML
+func expandTemplates(source string) string {
+	expand := func(match []string) string {
+		attrs := parseTemplateAttrs(match[2])
+		switch match[1] {
+		case "hero":
+			return renderHero(attrs)
+		case "codeBlock":
+			return renderCodeBlock(attrs)
+		case "product":
+			return renderProduct(attrs)
+		}
+		return match[0]
+	}
+
+	source = templateTagPattern.ReplaceAllStringFunc(source, func(s string) string {
+		return expand(templateTagPattern.FindStringSubmatch(s))
+	})
+	return templateShortcode.ReplaceAllStringFunc(source, func(s string) string {
+		return expand(templateShortcode.FindStringSubmatch(s))
+	})
+}
+
+// ExtractProductEmbeds returns the slugs of the products embedded in Markdown content with
+// {{< product slug="..." >}} or <product slug="..."/>, in order of first appearance
+func ExtractProductEmbeds(source string) []string {
+	type embed struct {
+		pos  int
+		slug string
+	}
+	var embeds []embed
+	for _, pattern := range []*regexp.Regexp{templateTagPattern, templateShortcode} {
+		for _, match := range pattern.FindAllStringSubmatchIndex(source, -1) {
+			if source[match[2]:match[3]] == "product" {
+				attrs := parseTemplateAttrs(source[match[4]:match[5]])
+				embeds = append(embeds, embed{match[0], strings.TrimSpace(attrs["slug"])})
+			}
+		}
+	}
+	sort.Slice(embeds, func(i, j int) bool { return embeds[i].pos < embeds[j].pos })
+
+	var slugs []string
+	seen := make(map[string]bool)
+	for _, e := range embeds {
+		if !seen[e.slug] {
+			seen[e.slug] = true
+			slugs = append(slugs, e.slug)
+		}
+	}
+	return slugs
+}
+
+// parseTemplateAttrs parses name="value", name={"value"} and name={`value`} attributes
+func parseTemplateAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range templateAttrPattern.FindAllStringSubmatch(s, -1) {
+		switch {
+		case m[4] != "":
+			attrs[m[1]] = m[4]
+		case m[3] != "":
+			attrs[m[1]] = unescapeAttr(m[3])
+		default:
+			attrs[m[1]] = unescapeAttr(m[2])
+		}
+	}
+	return attrs
+}
+
+// unescapeAttr resolves backslash escapes in a quoted attribute value
+func unescapeAttr(s string) string {
+	if unquoted, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// renderHero renders the hero template as an HTML block
+func renderHero(attrs map[string]string) string {
+	var sb strings.Builder
+	sb.WriteString("\n\n<section class=\"hero\">")
+	if attrs["image"] != "" {
+		fmt.Fprintf(&sb, "<img class=\"hero-image\" src=\"%s\" alt=\"%s\">",
+			html.EscapeString(attrs["image"]), html.EscapeString(attrs["heading"]))
+	}
+	if attrs["heading"] != "" {
+		fmt.Fprintf(&sb, "<h2 class=\"hero-heading\">%s</h2>", html.EscapeString(attrs["heading"]))
+	}
+	if attrs["subtext"] != "" {
+		fmt.Fprintf(&sb, "<p class=\"hero-subtext\">%s</p>", html.EscapeString(attrs["subtext"]))
+	}
+	sb.WriteString("</section>\n\n")
+	return sb.String()
+}
+
+// renderProduct renders a placeholder for an embedded product card, which clients fill in
+// from the products returned alongside the post
+func renderProduct(attrs map[string]string) string {
+	slug := strings.TrimSpace(attrs["slug"])
+	if !productSlugPattern.MatchString(slug) {
+		return ""
+	}
+	return "\n\n<div class=\"product-embed\" data-product=\"" + slug + "\"></div>\n\n"
+}
+
+// renderCodeBlock renders the codeBlock template as a fenced code block
+func renderCodeBlock(attrs map[string]string) string {
+	// Use a fence longer than any run of backticks inside the code
+	fence := "```"
+	for _, run := range backtickRunPattern.FindAllString(attrs["code"], -1) {
+		if len(run) >= len(fence) {
+			fence = strings.Repeat("`", len(run)+1)
+		}
+	}
+
+	language := strings.Map(func(r rune) rune {
+		if r == '`' || r == ' ' || r == '\n' {
+			return -1
+		}
+		return r
+	}, attrs["language"])
+
+	return "\n\n" + fence + language + "\n" + strings.TrimRight(attrs["code"], "\n") + "\n" + fence + "\n\n"
+}
@@ -0,0 +1,101 @@
+package util
+
+// NewsletterConfirmTemplate asks a new subscriber to confirm their address.
+// Data: Email, ConfirmURL, Year.
+var NewsletterConfirmTemplate = EmailTemplate{
+	Subject: "Confirm your BlogCommerce newsletter subscription",
+	HTML: `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="utf-8">
+		<title>Confirm your subscription</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.header { text-align: center; padding: 20px 0; }
+			.content { padding: 20px; background-color: #f9f9f9; border-radius: 5px; }
+			.button { display: inline-block; padding: 10px 20px; background-color: #0066cc; color: #fff; text-decoration: none; border-radius: 5px; }
+			.footer { text-align: center; margin-top: 20px; font-size: 12px; color: #999; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>Confirm your subscription</h1>
+			</div>
+			<div class="content">
+				<p>Thanks for signing up for the BlogCommerce newsletter. Please confirm your email address to start receiving our weekly digest of new posts.</p>
+				<p><a class="button" href="{{.ConfirmURL}}">Confirm subscription</a></p>
+				<p>If you didn't sign up, you can ignore this email and you won't hear from us again.</p>
+			</div>
+			<div class="footer">
+				<p>&copy; {{.Year}} BlogCommerce. All rights reserved.</p>
+				<p>This email was sent to {{.Email}}</p>
+			</div>
+		</div>
+	</body>
+	</html>
+	`,
+	Text: `Confirm your subscription
+
+Thanks for signing up for the BlogCommerce newsletter. Please confirm your email address to start receiving our weekly digest of new posts:
+
+{{.ConfirmURL}}
+
+If you didn't sign up, you can ignore this email and you won't hear from us again.
+
+© {{.Year}} BlogCommerce. All rights reserved.
+This email was sent to {{.Email}}`,
+}
+
+// NewsletterDigestTemplate lists the posts published since a subscriber's last digest.
+// Data: Email, Posts (Title, Excerpt, URL), UnsubscribeURL, Year.
+var NewsletterDigestTemplate = EmailTemplate{
+	Subject: "This week on BlogCommerce",
+	HTML: `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="utf-8">
+		<title>This week on BlogCommerce</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.header { text-align: center; padding: 20px 0; }
+			.content { padding: 20px; background-color: #f9f9f9; border-radius: 5px; }
+			.post { margin-bottom: 20px; }
+			.post h2 { font-size: 18px; margin: 0 0 5px; }
+			.post a { color: #0066cc; text-decoration: none; }
+			.footer { text-align: center; margin-top: 20px; font-size: 12px; color: #999; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>This week on BlogCommerce</h1>
+			</div>
+			<div class="content">
+				{{range .Posts}}
+				<div class="post">
+					<h2><a href="{{.URL}}">{{.Title}}</a></h2>
+					{{if .Excerpt}}<p>{{.Excerpt}}</p>{{end}}
+				</div>
+				{{end}}
+			</div>
+			<div class="footer">
+				<p>&copy; {{.Year}} BlogCommerce. All rights reserved.</p>
+				<p>This email was sent to {{.Email}}. <a href="{{.UnsubscribeURL}}">Unsubscribe</a></p>
+			</div>
+		</div>
+	</body>
+	</html>
+	`,
+	Text: `This week on BlogCommerce
+{{range .Posts}}
+{{.Title}}
+{{.URL}}
+{{end}}
+© {{.Year}} BlogCommerce. All rights reserved.
+This email was sent to {{.Email}}. Unsubscribe: {{.UnsubscribeURL}}`,
+}
@@ -0,0 +1,164 @@
+package util
+
+// SubscriptionReminderTemplate tells a customer their next subscription order is coming up.
+// Data: Name, Email, Product, Quantity, RenewalDate, ManageURL, Year.
+var SubscriptionReminderTemplate = EmailTemplate{
+	Subject: "Your {{.Product}} subscription renews on {{.RenewalDate}}",
+	HTML: `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="utf-8">
+		<title>Your subscription renews soon</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.header { text-align: center; padding: 20px 0; }
+			.content { padding: 20px; background-color: #f9f9f9; border-radius: 5px; }
+			.button { display: inline-block; padding: 10px 20px; background-color: #0066cc; color: #fff; text-decoration: none; border-radius: 5px; }
+			.footer { text-align: center; margin-top: 20px; font-size: 12px; color: #999; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>Your subscription renews soon</h1>
+			</div>
+			<div class="content">
+				<p>Hi {{.Name}},</p>
+				<p>We'll place your next order of {{.Quantity}} &times; {{.Product}} on {{.RenewalDate}}.</p>
+				<p>Need it later, or not at all this time? You can skip this delivery, pause or cancel your subscription before then.</p>
+				<p><a class="button" href="{{.ManageURL}}">Manage subscription</a></p>
+			</div>
+			<div class="footer">
+				<p>&copy; {{.Year}} BlogCommerce. All rights reserved.</p>
+				<p>This email was sent to {{.Email}}</p>
+			</div>
+		</div>
+	</body>
+	</html>
+	`,
+	Text: `Your subscription renews soon
+
+Hi {{.Name}},
+
+We'll place your next order of {{.Quantity}} x {{.Product}} on {{.RenewalDate}}.
+
+Need it later, or not at all this time? You can skip this delivery, pause or cancel your subscription before then:
+
+{{.ManageURL}}
+
+© {{.Year}} BlogCommerce. All rights reserved.
+This email was sent to {{.Email}}`,
+}
+
+// SubscriptionPaymentTemplate asks a customer to pay for a subscription's renewal order.
+// Data: Name, Email, Product, Quantity, Total, PayBy, PayURL, ManageURL, Year.
+var SubscriptionPaymentTemplate = EmailTemplate{
+	Subject: "Your {{.Product}} order is ready to pay for",
+	HTML: `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="utf-8">
+		<title>Your subscription order is ready</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.header { text-align: center; padding: 20px 0; }
+			.content { padding: 20px; background-color: #f9f9f9; border-radius: 5px; }
+			.button { display: inline-block; padding: 10px 20px; background-color: #0066cc; color: #fff; text-decoration: none; border-radius: 5px; }
+			.footer { text-align: center; margin-top: 20px; font-size: 12px; color: #999; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>Your subscription order is ready</h1>
+			</div>
+			<div class="content">
+				<p>Hi {{.Name}},</p>
+				<p>We've placed your order of {{.Quantity}} &times; {{.Product}} for {{.Total}} and are holding the stock for you until {{.PayBy}}.</p>
+				<p><a class="button" href="{{.PayURL}}">Pay for your order</a></p>
+				<p>You can also <a href="{{.ManageURL}}">manage your subscription</a>.</p>
+			</div>
+			<div class="footer">
+				<p>&copy; {{.Year}} BlogCommerce. All rights reserved.</p>
+				<p>This email was sent to {{.Email}}</p>
+			</div>
+		</div>
+	</body>
+	</html>
+	`,
+	Text: `Your subscription order is ready
+
+Hi {{.Name}},
+
+We've placed your order of {{.Quantity}} x {{.Product}} for {{.Total}} and are holding the stock for you until {{.PayBy}}. Pay for your order here:
+
+{{.PayURL}}
+
+Manage your subscription: {{.ManageURL}}
+
+© {{.Year}} BlogCommerce. All rights reserved.
+This email was sent to {{.Email}}`,
+}
+
+// SubscriptionFailedTemplate tells a customer a subscription renewal didn't go through,
+// and when it will be tried again or that the subscription is paused.
+// Data: Name, Email, Product, Reason, RetryDate, Paused, ManageURL, Year.
+var SubscriptionFailedTemplate = EmailTemplate{
+	Subject: "We couldn't renew your {{.Product}} subscription",
+	HTML: `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta charset="utf-8">
+		<title>We couldn't renew your subscription</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.header { text-align: center; padding: 20px 0; }
+			.content { padding: 20px; background-color: #f9f9f9; border-radius: 5px; }
+			.button { display: inline-block; padding: 10px 20px; background-color: #0066cc; color: #fff; text-decoration: none; border-radius: 5px; }
+			.footer { text-align: center; margin-top: 20px; font-size: 12px; color: #999; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			<div class="header">
+				<h1>We couldn't renew your subscription</h1>
+			</div>
+			<div class="content">
+				<p>Hi {{.Name}},</p>
+				<p>Your order of {{.Product}} didn't go through: {{.Reason}}.</p>
+				{{if .Paused}}
+				<p>We've tried several times, so we've paused your subscription. Resume it once everything is sorted and we'll place your order again.</p>
+				{{else}}
+				<p>We'll try again on {{.RetryDate}}. If anything needs updating, please do so before then.</p>
+				{{end}}
+				<p><a class="button" href="{{.ManageURL}}">Manage subscription</a></p>
+			</div>
+			<div class="footer">
+				<p>&copy; {{.Year}} BlogCommerce. All rights reserved.</p>
+				<p>This email was sent to {{.Email}}</p>
+			</div>
+		</div>
+	</body>
+	</html>
+	`,
+	Text: `We couldn't renew your subscription
+
+Hi {{.Name}},
+
+Your order of {{.Product}} didn't go through: {{.Reason}}.
+{{if .Paused}}
+We've tried several times, so we've paused your subscription. Resume it once everything is sorted and we'll place your order again.
+{{else}}
+We'll try again on {{.RetryDate}}. If anything needs updating, please do so before then.
+{{end}}
+Manage your subscription: {{.ManageURL}}
+
+© {{.Year}} BlogCommerce. All rights reserved.
+This email was sent to {{.Email}}`,
+}
@@ -31,6 +31,13 @@ const contentBlocks: TinaTemplate[] = [
       },
     ],
   },
+  {
+    name: "product",
+    label: "Product",
+    fields: [
+      { name: "slug", label: "Product Slug", type: "string", required: true },
+    ],
+  },
 ];
 
 export const schema = defineSchema({